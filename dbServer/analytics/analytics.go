@@ -0,0 +1,204 @@
+package analytics
+
+import (
+	"context"
+	"dbserver/currency"
+	"dbserver/db"
+	"dbserver/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	GroupByDay      = "day"
+	GroupByWeek     = "week"
+	GroupByMonth    = "month"
+	GroupByMart     = "mart"
+	GroupByCategory = "category"
+
+	TopBySpend     = "spend"
+	TopByFrequency = "frequency"
+
+	// Uncategorized is the category key of products without a category
+	Uncategorized = "uncategorized"
+)
+
+// ValidGroupBy reports whether groupBy is a supported grouping
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByDay, GroupByWeek, GroupByMonth, GroupByMart, GroupByCategory:
+		return true
+	}
+	return false
+}
+
+// IsTimeGrouping reports whether groupBy buckets by date
+func IsTimeGrouping(groupBy string) bool {
+	return groupBy == GroupByDay || groupBy == GroupByWeek || groupBy == GroupByMonth
+}
+
+// lineTotal is price*amount less the line discount of an unwound product line
+var lineTotal = bson.M{"$subtract": bson.A{
+	bson.M{"$multiply": bson.A{"$product.price", "$product.amount"}},
+	bson.M{"$ifNull": bson.A{"$product.discount", 0}},
+}}
+
+// converted starts a pipeline over the records matched by match with their amounts
+// convertible to home at the rates of their purchase dates in loc
+func converted(ctx context.Context, match bson.M, home string, loc *time.Location) (mongo.Pipeline, error) {
+	stages, err := currency.Stages(ctx, match, home, loc)
+	if err != nil {
+		return nil, err
+	}
+	return append(mongo.Pipeline{{{Key: "$match", Value: match}}}, stages...), nil
+}
+
+// Spending returns the totals of the records matched by match grouped by groupBy,
+// in the home currency. Date buckets are computed in loc and keyed by their first
+// day (YYYY-MM-DD).
+func Spending(ctx context.Context, match bson.M, groupBy string, home string, loc *time.Location) ([]models.SpendingBucket, error) {
+	pipeline, err := converted(ctx, match, home, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch groupBy {
+	case GroupByDay, GroupByWeek, GroupByMonth:
+		trunc := bson.M{
+			"date":     "$record.timeStamp.at",
+			"unit":     groupBy,
+			"timezone": loc.String(),
+		}
+		if groupBy == GroupByWeek {
+			trunc["startOfWeek"] = "monday"
+		}
+		pipeline = append(pipeline,
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id": bson.M{"$dateToString": bson.M{
+					"date":     bson.M{"$dateTrunc": trunc},
+					"format":   "%Y-%m-%d",
+					"timezone": loc.String(),
+				}},
+				"total": bson.M{"$sum": currency.Converted("$totalPrice")},
+				"count": bson.M{"$sum": 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		)
+	case GroupByMart:
+		pipeline = append(pipeline,
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":   "$mart.martName",
+				"total": bson.M{"$sum": currency.Converted("$totalPrice")},
+				"count": bson.M{"$sum": 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.M{"total": -1}}},
+		)
+	case GroupByCategory:
+		// 카테고리는 품목 단위로 집계
+		pipeline = append(pipeline,
+			bson.D{{Key: "$unwind", Value: "$product"}},
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":   bson.M{"$ifNull": bson.A{"$product.category", Uncategorized}},
+				"total": bson.M{"$sum": currency.Converted(lineTotal)},
+				"count": bson.M{"$sum": 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.M{"total": -1}}},
+		)
+	default:
+		return nil, fmt.Errorf("unsupported groupBy %q", groupBy)
+	}
+
+	buckets := []models.SpendingBucket{}
+	if err := aggregate(ctx, pipeline, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// Totals returns the total spend, receipt and item counts and the average basket size
+// in the home currency
+func Totals(ctx context.Context, match bson.M, home string, loc *time.Location) (models.SpendingTotals, error) {
+	pipeline, err := converted(ctx, match, home, loc)
+	if err != nil {
+		return models.SpendingTotals{}, err
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"total":       bson.M{"$sum": currency.Converted("$totalPrice")},
+			"receipts":    bson.M{"$sum": 1},
+			"items":       bson.M{"$sum": bson.M{"$size": bson.M{"$ifNull": bson.A{"$product", bson.A{}}}}},
+			"avgBasket":   bson.M{"$avg": currency.Converted("$totalPrice")},
+			"maxReceipt":  bson.M{"$max": currency.Converted("$totalPrice")},
+			"unconverted": currency.Unconverted,
+		}}},
+	)
+
+	var results []models.SpendingTotals
+	if err := aggregate(ctx, pipeline, &results); err != nil {
+		return models.SpendingTotals{}, err
+	}
+	if len(results) == 0 {
+		return models.SpendingTotals{}, nil
+	}
+	return results[0], nil
+}
+
+// TopProducts returns the products with the highest spend, in the home currency, or
+// bought on the most receipts
+func TopProducts(ctx context.Context, match bson.M, by string, limit int, home string, loc *time.Location) ([]models.ProductStat, error) {
+	sort := bson.D{{Key: "spend", Value: -1}, {Key: "purchases", Value: -1}}
+	if by == TopByFrequency {
+		sort = bson.D{{Key: "purchases", Value: -1}, {Key: "spend", Value: -1}}
+	}
+
+	pipeline, err := converted(ctx, match, home, loc)
+	if err != nil {
+		return nil, err
+	}
+	pipeline = append(pipeline, mongo.Pipeline{
+		{{Key: "$unwind", Value: "$product"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$product.pname",
+			"spend":    bson.M{"$sum": currency.Converted(lineTotal)},
+			"quantity": bson.M{"$sum": "$product.amount"},
+			"receipts": bson.M{"$addToSet": "$record.rid"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"spend":     1,
+			"quantity":  1,
+			"purchases": bson.M{"$size": "$receipts"},
+		}}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$limit", Value: limit}},
+	}...)
+
+	products := []models.ProductStat{}
+	if err := aggregate(ctx, pipeline, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Change returns the relative change from previous to current, or nil when there is
+// nothing to compare with
+func Change(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := (current - previous) / previous
+	return &change
+}
+
+func aggregate(ctx context.Context, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := db.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
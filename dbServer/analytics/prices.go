@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"context"
+	"dbserver/currency"
+	"dbserver/db"
+	"dbserver/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PriceHistory returns every purchase of the product normName in the records matched
+// by match, oldest first, with min/max/average unit prices overall and per mart.
+// Unit prices are converted to home; purchases without an exchange rate are left out.
+func PriceHistory(ctx context.Context, match bson.M, normName string, home string, loc *time.Location) (*models.PriceHistory, error) {
+	histories, err := PriceHistories(ctx, match, []string{normName}, home, loc)
+	if err != nil {
+		return nil, err
+	}
+	return histories[normName], nil
+}
+
+// PriceHistories is PriceHistory for several products in one aggregation. Every
+// product of normNames is in the result, with no purchases if it was never bought.
+func PriceHistories(ctx context.Context, match bson.M, normNames []string, home string, loc *time.Location) (map[string]*models.PriceHistory, error) {
+	match = bson.M{"$and": bson.A{match, bson.M{"product.normName": bson.M{"$in": normNames}}}}
+	pipeline, err := converted(ctx, match, home, loc)
+	if err != nil {
+		return nil, err
+	}
+	pipeline = append(pipeline, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{currency.FactorField: bson.M{"$ne": nil}}}},
+		{{Key: "$unwind", Value: "$product"}},
+		{{Key: "$match", Value: bson.M{"product.normName": bson.M{"$in": normNames}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"product":   "$product.normName",
+			"rid":       "$record.rid",
+			"timeStamp": "$record.timeStamp",
+			"martName":  "$mart.martName",
+			"pname":     "$product.pname",
+			"unitPrice": currency.Converted("$product.price"),
+			"amount":    "$product.amount",
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "timeStamp.at", Value: 1}}}},
+	}...)
+
+	cursor, err := db.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var purchases []models.PricePoint
+	if err := cursor.All(ctx, &purchases); err != nil {
+		return nil, err
+	}
+
+	histories := make(map[string]*models.PriceHistory, len(normNames))
+	for _, normName := range normNames {
+		histories[normName] = &models.PriceHistory{
+			Product:   normName,
+			Currency:  currency.Normalize(home),
+			Names:     []string{},
+			Purchases: []models.PricePoint{},
+			ByMart:    []models.MartPriceStats{},
+		}
+	}
+	for _, p := range purchases {
+		if history, ok := histories[p.Product]; ok {
+			history.Purchases = append(history.Purchases, p)
+		}
+	}
+	for _, history := range histories {
+		summarize(history)
+	}
+	return histories, nil
+}
+
+// summarize computes the stats of the purchases of history
+func summarize(history *models.PriceHistory) {
+	if len(history.Purchases) == 0 {
+		return
+	}
+
+	seenNames := map[string]bool{}
+	martIndex := map[string]int{}
+	for _, p := range history.Purchases {
+		if !seenNames[p.Pname] {
+			seenNames[p.Pname] = true
+			history.Names = append(history.Names, p.Pname)
+		}
+
+		history.Stats.Add(p.UnitPrice)
+
+		i, ok := martIndex[p.MartName]
+		if !ok {
+			i = len(history.ByMart)
+			martIndex[p.MartName] = i
+			history.ByMart = append(history.ByMart, models.MartPriceStats{MartName: p.MartName})
+		}
+		history.ByMart[i].Add(p.UnitPrice)
+		history.ByMart[i].Last = p.UnitPrice
+	}
+
+	cheapest := 0
+	for i, m := range history.ByMart {
+		if m.Avg < history.ByMart[cheapest].Avg {
+			cheapest = i
+		}
+	}
+	history.CheapestMart = history.ByMart[cheapest].MartName
+}
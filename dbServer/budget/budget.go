@@ -0,0 +1,222 @@
+package budget
+
+import (
+	"context"
+	"dbserver/currency"
+	"dbserver/db"
+	"dbserver/models"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PeriodBounds returns the week (starting on Monday) or month containing t in loc
+func PeriodBounds(period string, t time.Time, loc *time.Location) (time.Time, time.Time) {
+	t = t.In(loc)
+	if period == models.BudgetPeriodWeekly {
+		offset := (int(t.Weekday()) + 6) % 7
+		start := time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7)
+	}
+
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Spent returns how much was spent against b between start and end, converted to
+// home at the rates of each purchase date in loc
+func Spent(ctx context.Context, b models.Budget, start, end time.Time, home string, loc *time.Location) (int, error) {
+	match := bson.M{
+		"uid":                 b.Uid,
+		"record.rid":          bson.M{"$exists": true},
+		"record.timeStamp.at": bson.M{"$gte": start, "$lt": end},
+	}
+	if b.Scope == models.BudgetScopeMart {
+		match["mart.martName"] = b.Target
+	}
+
+	stages, err := currency.Stages(ctx, match, home, loc)
+	if err != nil {
+		return 0, err
+	}
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: match}}}, stages...)
+
+	if b.Scope == models.BudgetScopeCategory {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$unwind", Value: "$product"}},
+			bson.D{{Key: "$match", Value: bson.M{"product.category": b.Target}}},
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id": nil,
+				"spent": bson.M{"$sum": currency.Converted(bson.M{"$subtract": bson.A{
+					bson.M{"$multiply": bson.A{"$product.price", "$product.amount"}},
+					bson.M{"$ifNull": bson.A{"$product.discount", 0}},
+				}})},
+			}}},
+		)
+	} else {
+		pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"spent": bson.M{"$sum": currency.Converted("$totalPrice")},
+		}}})
+	}
+
+	cursor, err := db.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Spent int `bson:"spent"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Spent, nil
+}
+
+// Progress returns the state of b in the period containing at. Budgets are in the
+// home currency of their owner.
+func Progress(ctx context.Context, b models.Budget, at time.Time, home string, loc *time.Location) (models.BudgetProgress, error) {
+	start, end := PeriodBounds(b.Period, at, loc)
+	spent, err := Spent(ctx, b, start, end, home, loc)
+	if err != nil {
+		return models.BudgetProgress{}, err
+	}
+
+	progress := models.BudgetProgress{
+		Budget:      b,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Spent:       spent,
+		Remaining:   b.Amount - spent,
+		Reached:     []int{},
+	}
+	if b.Amount > 0 {
+		progress.Percent = float64(spent) * 100 / float64(b.Amount)
+	}
+	for _, threshold := range b.Thresholds {
+		if progress.Percent >= float64(threshold) {
+			progress.Reached = append(progress.Reached, threshold)
+		}
+	}
+	return progress, nil
+}
+
+// List returns the budgets of a user
+func List(ctx context.Context, uid string) ([]models.Budget, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := db.BudgetCollection.Find(ctx, bson.M{"uid": uid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	budgets := []models.Budget{}
+	if err := cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// Evaluate re-computes every budget of uid for the period containing at and raises an
+// alert for each threshold crossed for the first time in that period.
+func Evaluate(ctx context.Context, uid string, at time.Time, home string, loc *time.Location) ([]models.BudgetAlert, error) {
+	budgets, err := List(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []models.BudgetAlert{}
+	for _, b := range budgets {
+		progress, err := Progress(ctx, b, at, home, loc)
+		if err != nil {
+			return alerts, err
+		}
+
+		for _, threshold := range progress.Reached {
+			alert, created, err := raise(ctx, progress, threshold, home)
+			if err != nil {
+				return alerts, err
+			}
+			if !created {
+				continue
+			}
+
+			alerts = append(alerts, *alert)
+			if err := notifier.Notify(ctx, *alert); err != nil {
+				// 알림 전송 실패는 인박스 저장에 영향을 주지 않음
+				logNotifyError(alert, err)
+			}
+		}
+	}
+	return alerts, nil
+}
+
+// raise stores an alert unless the same budget, period and threshold already has one
+func raise(ctx context.Context, progress models.BudgetProgress, threshold int, home string) (*models.BudgetAlert, bool, error) {
+	b := progress.Budget
+	alert := models.BudgetAlert{
+		Aid:         uuid.NewString(),
+		Uid:         b.Uid,
+		Bid:         b.Bid,
+		BudgetName:  b.Name,
+		Threshold:   threshold,
+		PeriodStart: progress.PeriodStart,
+		Spent:       progress.Spent,
+		Amount:      b.Amount,
+		Currency:    home,
+		Message:     message(b, threshold, progress.Spent, home),
+		CreatedAt:   time.Now(),
+	}
+
+	filter := bson.M{"bid": b.Bid, "periodStart": progress.PeriodStart, "threshold": threshold}
+	result, err := db.AlertCollection.UpdateOne(ctx, filter,
+		bson.M{"$setOnInsert": alert},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return &alert, result.UpsertedCount > 0, nil
+}
+
+func message(b models.Budget, threshold int, spent int, home string) string {
+	amounts := fmt.Sprintf("%d / %d원", spent, b.Amount)
+	if home != currency.Default {
+		amounts = fmt.Sprintf("%s / %s %s", currency.Format(spent, home), currency.Format(b.Amount, home), home)
+	}
+	if threshold >= 100 {
+		return fmt.Sprintf("'%s' 예산을 초과했습니다 (%s)", b.Name, amounts)
+	}
+	return fmt.Sprintf("'%s' 예산의 %d%%를 사용했습니다 (%s)", b.Name, threshold, amounts)
+}
+
+// NormalizeThresholds sorts and de-duplicates thresholds, using the defaults when empty
+func NormalizeThresholds(thresholds []int) []int {
+	if len(thresholds) == 0 {
+		return append([]int{}, models.DefaultBudgetThresholds...)
+	}
+
+	seen := map[int]bool{}
+	normalized := []int{}
+	for _, t := range thresholds {
+		if !seen[t] {
+			seen[t] = true
+			normalized = append(normalized, t)
+		}
+	}
+	sort.Ints(normalized)
+	return normalized
+}
@@ -0,0 +1,34 @@
+package budget
+
+import (
+	"context"
+	"dbserver/models"
+	"log"
+)
+
+// Notifier delivers budget alerts outside of the in-app inbox
+type Notifier interface {
+	Notify(ctx context.Context, alert models.BudgetAlert) error
+}
+
+// LogNotifier only writes the alert to the server log
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, alert models.BudgetAlert) error {
+	log.Printf("Budget alert for %s: %s\n", alert.Uid, alert.Message)
+	return nil
+}
+
+var notifier Notifier = LogNotifier{}
+
+// SetNotifier replaces the notifier used by Evaluate
+func SetNotifier(n Notifier) {
+	if n == nil {
+		n = LogNotifier{}
+	}
+	notifier = n
+}
+
+func logNotifyError(alert *models.BudgetAlert, err error) {
+	log.Printf("Notify error for alert %s: %v\n", alert.Aid, err)
+}
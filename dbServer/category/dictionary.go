@@ -0,0 +1,145 @@
+package category
+
+// 기본 카테고리
+const (
+	Produce      = "produce"
+	Meat         = "meat"
+	Seafood      = "seafood"
+	Dairy        = "dairy"
+	Bakery       = "bakery"
+	Grains       = "grains"
+	Snacks       = "snacks"
+	Beverages    = "beverages"
+	Alcohol      = "alcohol"
+	Frozen       = "frozen"
+	Instant      = "instant"
+	Condiments   = "condiments"
+	Household    = "household"
+	PersonalCare = "personal-care"
+	Baby         = "baby"
+	Pet          = "pet"
+	Health       = "health"
+	Stationery   = "stationery"
+	Dining       = "dining"
+	Fashion      = "fashion"
+	Electronics  = "electronics"
+	Other        = "other"
+)
+
+// Labels are the Korean names of the built-in categories
+var Labels = map[string]string{
+	Produce:      "채소/과일",
+	Meat:         "정육/계란",
+	Seafood:      "수산/건어물",
+	Dairy:        "유제품",
+	Bakery:       "베이커리",
+	Grains:       "쌀/잡곡",
+	Snacks:       "과자/간식",
+	Beverages:    "음료/커피",
+	Alcohol:      "주류",
+	Frozen:       "냉동식품",
+	Instant:      "라면/간편식",
+	Condiments:   "양념/소스",
+	Household:    "생활용품",
+	PersonalCare: "뷰티/위생",
+	Baby:         "유아",
+	Pet:          "반려동물",
+	Health:       "건강/의약",
+	Stationery:   "문구/사무",
+	Dining:       "외식",
+	Fashion:      "의류/잡화",
+	Electronics:  "가전/디지털",
+	Other:        "기타",
+}
+
+// dictionary maps built-in categories to keywords found in Korean receipt item names.
+// Keywords are matched against the normalized product name, so they are written
+// without spaces and in lower case. One-letter keywords such as "배" only match
+// names that consist of that word alone, e.g. "배" or "배3입".
+var dictionary = map[string][]string{
+	Produce: {
+		"사과", "배", "바나나", "딸기", "포도", "귤", "감귤", "오렌지", "레몬", "키위", "수박", "참외", "복숭아", "토마토",
+		"블루베리", "망고", "아보카도", "파인애플", "양파", "대파", "쪽파", "마늘", "감자", "고구마", "당근", "오이",
+		"호박", "애호박", "양배추", "배추", "무", "상추", "깻잎", "시금치", "브로콜리", "파프리카", "피망", "버섯",
+		"표고", "팽이", "콩나물", "숙주", "고추", "청양", "생강", "샐러드", "채소", "야채", "과일",
+	},
+	Meat: {
+		"삼겹", "목살", "돼지", "한돈", "소고기", "한우", "우삼겹", "등심", "안심", "갈비", "불고기", "차돌",
+		"닭", "닭가슴살", "오리", "계란", "달걀", "란", "정육", "베이컨", "햄", "소시지", "스팸",
+	},
+	Seafood: {
+		"고등어", "갈치", "연어", "참치회", "오징어", "낙지", "문어", "새우", "조개", "바지락", "홍합", "굴", "전복",
+		"게", "꽃게", "멸치", "김", "미역", "다시마", "어묵", "맛살", "명태", "황태", "수산", "생선",
+	},
+	Dairy: {
+		"우유", "milk", "요거트", "요구르트", "야쿠르트", "치즈", "cheese", "버터", "생크림", "두유", "분유",
+	},
+	Bakery: {
+		"빵", "식빵", "베이글", "크로와상", "케이크", "케익", "도넛", "머핀", "모닝빵", "바게트", "카스테라", "파리바게뜨", "뚜레쥬르",
+	},
+	Grains: {
+		"쌀", "현미", "잡곡", "찹쌀", "귀리", "오트밀", "보리", "밀가루", "부침가루", "튀김가루", "국수", "소면", "파스타", "스파게티",
+	},
+	Snacks: {
+		"과자", "스낵", "칩", "새우깡", "초코", "초콜릿", "쿠키", "비스킷", "크래커", "젤리", "사탕", "껌", "아이스크림",
+		"빼빼로", "포카칩", "홈런볼", "견과", "아몬드", "땅콩",
+	},
+	Beverages: {
+		"커피", "coffee", "아메리카노", "라떼", "latte", "콜라", "cola", "사이다", "주스", "쥬스", "juice", "탄산수",
+		"생수", "삼다수", "아이시스", "에비앙", "녹차", "홍차", "티", "음료", "이온", "포카리", "게토레이", "비타500", "박카스",
+	},
+	Alcohol: {
+		"소주", "참이슬", "처음처럼", "진로", "맥주", "카스", "테라", "하이트", "클라우드", "필라이트", "막걸리", "와인",
+		"wine", "위스키", "하이볼", "사케", "beer",
+	},
+	Frozen: {
+		"냉동", "만두", "피자", "핫도그", "너겟", "동그랑땡", "떡갈비",
+	},
+	Instant: {
+		"라면", "신라면", "진라면", "짜파게티", "너구리", "불닭", "컵라면", "햇반", "즉석", "3분", "카레", "짜장",
+		"도시락", "삼각김밥", "김밥", "샌드위치", "죽", "밀키트", "떡볶이",
+	},
+	Condiments: {
+		"간장", "된장", "고추장", "쌈장", "소금", "설탕", "식초", "참기름", "들기름", "식용유", "올리브유", "케찹", "케첩",
+		"마요네즈", "소스", "드레싱", "후추", "다시다", "미원", "액젓", "양념", "물엿", "올리고당",
+	},
+	Household: {
+		"휴지", "화장지", "키친타올", "물티슈", "세제", "섬유유연제", "락스", "주방세제", "퐁퐁", "수세미", "고무장갑",
+		"랩", "호일", "지퍼백", "위생백", "쓰레기봉투", "종량제", "봉투", "건전지", "전구", "방향제", "탈취제", "살충제",
+	},
+	PersonalCare: {
+		"샴푸", "린스", "컨디셔너", "바디워시", "비누", "치약", "칫솔", "가글", "면도", "로션", "스킨", "크림", "선크림",
+		"마스크팩", "화장품", "생리대", "면봉", "클렌징",
+	},
+	Baby: {
+		"기저귀", "하기스", "팸퍼스", "이유식", "젖병", "아기", "유아",
+	},
+	Pet: {
+		"사료", "간식개", "강아지", "고양이", "캣", "펫", "배변패드", "모래",
+	},
+	Health: {
+		"약", "영양제", "비타민", "유산균", "홍삼", "밴드", "파스", "마스크", "소독", "감기",
+	},
+	Stationery: {
+		"볼펜", "연필", "노트", "공책", "테이프", "풀", "가위", "파일", "a4", "복사지", "포스트잇",
+	},
+	Dining: {
+		"식사", "정식", "세트", "국밥", "찌개", "탕", "냉면", "돈까스", "짜장면", "짬뽕", "탕수육", "치킨", "버거",
+		"햄버거", "메뉴", "공기밥", "주문",
+	},
+	Fashion: {
+		"티셔츠", "셔츠", "바지", "양말", "속옷", "신발", "운동화", "모자", "가방", "우산",
+	},
+	Electronics: {
+		"충전기", "케이블", "이어폰", "usb", "배터리", "마우스", "키보드",
+	},
+}
+
+// Builtin lists the keys of the built-in categories
+func Builtin() []string {
+	keys := make([]string, 0, len(Labels))
+	for key := range Labels {
+		keys = append(keys, key)
+	}
+	return keys
+}
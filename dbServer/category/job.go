@@ -0,0 +1,134 @@
+package category
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/history"
+	"dbserver/models"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RecategorizeActor is recorded in the history of records changed by the background job
+const RecategorizeActor = "system:recategorize"
+
+// 한 번의 재분류 작업에 허용하는 최대 시간
+const jobTimeout = 30 * time.Minute
+
+// StartRecategorize re-applies the current rules of uid to all of their records in the
+// background. Products categorized by hand are left alone.
+func StartRecategorize(uid string) (*models.CategoryJob, error) {
+	job := models.CategoryJob{
+		JobId:     uuid.NewString(),
+		Uid:       uid,
+		Status:    models.CategoryJobRunning,
+		StartedAt: time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := db.CategoryJobCollection.InsertOne(ctx, job); err != nil {
+		return nil, err
+	}
+
+	go runRecategorize(job)
+	return &job, nil
+}
+
+// GetJob returns a re-categorization job of uid
+func GetJob(ctx context.Context, uid string, jobId string) (*models.CategoryJob, error) {
+	var job models.CategoryJob
+	err := db.CategoryJobCollection.FindOne(ctx, bson.M{"jobId": jobId, "uid": uid}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func runRecategorize(job models.CategoryJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	scanned, updated, err := Recategorize(ctx, job.Uid, func(scanned, updated int) {
+		db.CategoryJobCollection.UpdateOne(ctx, bson.M{"jobId": job.JobId}, bson.M{
+			"$set": bson.M{"scanned": scanned, "updated": updated},
+		})
+	})
+
+	set := bson.M{
+		"status":     models.CategoryJobDone,
+		"scanned":    scanned,
+		"updated":    updated,
+		"finishedAt": time.Now(),
+	}
+	if err != nil {
+		log.Printf("Recategorize error for %s: %v\n", job.Uid, err)
+		set["status"] = models.CategoryJobFailed
+		set["error"] = err.Error()
+	}
+
+	if _, err := db.CategoryJobCollection.UpdateOne(context.Background(), bson.M{"jobId": job.JobId}, bson.M{"$set": set}); err != nil {
+		log.Printf("Update error: %v\n", err)
+	}
+}
+
+// Recategorize applies the rules of uid to every record and stores the ones that changed.
+// A record whose products were edited since it was read is skipped. progress, when set,
+// is called every 100 records.
+func Recategorize(ctx context.Context, uid string, progress func(scanned, updated int)) (int, int, error) {
+	categorizer, err := Load(ctx, uid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cursor, err := db.Collection.Find(ctx, bson.M{"uid": uid, "record.rid": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	scanned, updated := 0, 0
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			return scanned, updated, err
+		}
+		scanned++
+
+		before := record
+		before.Product = append([]models.DBProduct{}, record.Product...)
+		if categorizer.Apply(record.Product) {
+			// 읽은 뒤 사용자가 품목을 고쳤으면 그 수정을 덮어쓰지 않도록 건너뜀
+			filter := bson.M{"record.rid": record.Record.Rid, "product": cursor.Current.Lookup("product")}
+			saved := false
+			err := db.Transaction(ctx, func(sc mongo.SessionContext) error {
+				saved = false
+				result, err := db.Collection.UpdateOne(sc, filter, bson.M{"$set": bson.M{"product": record.Product}})
+				if err != nil || result.MatchedCount == 0 {
+					return err
+				}
+				if _, err := history.Record(sc, RecategorizeActor, models.HistoryActionUpdate, &before, record); err != nil {
+					return err
+				}
+				saved = true
+				return nil
+			})
+			if err != nil {
+				return scanned, updated, err
+			}
+			if saved {
+				updated++
+			}
+		}
+
+		if progress != nil && scanned%100 == 0 {
+			progress(scanned, updated)
+		}
+	}
+
+	return scanned, updated, cursor.Err()
+}
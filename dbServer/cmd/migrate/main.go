@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"dbserver/config"
+	"dbserver/db"
+	"dbserver/migrations"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// 사용법: go run ./cmd/migrate -name timestamps [-dry-run]
+func main() {
+	name := flag.String("name", "", "migration to run ("+strings.Join(migrations.Names(), ", ")+")")
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	migration, ok := migrations.Get(*name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown migration %q, available: %s\n", *name, strings.Join(migrations.Names(), ", "))
+		os.Exit(2)
+	}
+
+	config.Init()
+	db.DBInit()
+	defer db.DisconnectDB(db.Client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	report, err := migration(ctx, *dryRun)
+	if err != nil {
+		log.Fatalf("Migration %s failed: %v", *name, err)
+	}
+
+	report.Print(os.Stdout)
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"dbserver/config"
+	"dbserver/currency"
+	"dbserver/db"
+	"dbserver/models"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// 사용법: go run ./cmd/rates -file rates.csv [-dry-run]
+// CSV는 "date,currency,rate" 형식, JSON은 {"currency", "date", "rate"} 배열
+func main() {
+	path := flag.String("file", "", "exchange-rate table to load (.csv or .json)")
+	dryRun := flag.Bool("dry-run", false, "validate the file without writing")
+	flag.Parse()
+
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		os.Exit(2)
+	}
+
+	rates, err := currency.ReadFile(*path)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *path, err)
+	}
+	for i := range rates {
+		if err := currency.Check(&rates[i]); err != nil {
+			log.Fatalf("Invalid rate #%d: %v", i+1, err)
+		}
+	}
+	if *dryRun {
+		fmt.Printf("%d rates are valid\n", len(rates))
+		return
+	}
+
+	config.Init()
+	db.DBInit()
+	defer db.DisconnectDB(db.Client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	saved, err := currency.Save(ctx, rates, models.RateSourceFile)
+	if err != nil {
+		log.Fatalf("Failed to save rates: %v", err)
+	}
+	fmt.Printf("%d rates loaded, %d added or changed\n", len(rates), saved)
+}
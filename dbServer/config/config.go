@@ -1,8 +1,64 @@
 package config
 
+import (
+	"os"
+	"strings"
+)
+
 type Config struct {
 	MongoDB MongoConfig
 	OCR     OCRConfig
+	Admin   AdminConfig
+	Storage StorageConfig
+	Report  ReportConfig
+	Notify  NotifyConfig
+}
+
+const (
+	StorageGridFS = "gridfs"
+	StorageLocal  = "local"
+)
+
+// StorageConfig selects where receipt images are kept: RECEIPT_STORAGE is "gridfs"
+// (default) or "local", and RECEIPT_IMAGE_DIR is the directory of the local store.
+type StorageConfig struct {
+	Backend string
+	Dir     string
+}
+
+// ReportConfig names the TrueType fonts PDF reports are written in. They must cover
+// Hangul: REPORT_FONT and REPORT_FONT_BOLD default to NanumGothic as installed by the
+// fonts-nanum package. The regular font is used for bold text if the bold one is missing.
+type ReportConfig struct {
+	Font     string
+	BoldFont string
+}
+
+// NotifyConfig configures the notification transports. A transport whose settings
+// are missing is disabled and its deliveries are skipped.
+//
+//	SMTP_HOST, SMTP_PORT (587), SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM   email
+//	VAPID_PUBLIC_KEY, VAPID_PRIVATE_KEY, VAPID_SUBJECT                     web push
+//	PUSH_GATEWAY_URL, PUSH_GATEWAY_SECRET                                  mobile push
+type NotifyConfig struct {
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	VAPIDSubject    string
+
+	GatewayURL    string
+	GatewaySecret string
+}
+
+// AdminConfig lists the users allowed to call the admin API, e.g. to maintain the
+// exchange-rate table. It is read from ADMIN_UIDS, separated by commas.
+type AdminConfig struct {
+	Uids []string
 }
 
 type MongoConfig struct {
@@ -28,11 +84,85 @@ func InitDB() MongoConfig {
 	return mongoConfig
 }
 
+func InitAdmin() AdminConfig {
+	var uids []string
+	for _, uid := range strings.Split(os.Getenv("ADMIN_UIDS"), ",") {
+		if uid = strings.TrimSpace(uid); uid != "" {
+			uids = append(uids, uid)
+		}
+	}
+	return AdminConfig{Uids: uids}
+}
+
+// IsAdmin reports whether uid may call the admin API
+func IsAdmin(uid string) bool {
+	for _, admin := range AppConfig.Admin.Uids {
+		if admin == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func InitStorage() StorageConfig {
+	storage := StorageConfig{
+		Backend: strings.ToLower(strings.TrimSpace(os.Getenv("RECEIPT_STORAGE"))),
+		Dir:     strings.TrimSpace(os.Getenv("RECEIPT_IMAGE_DIR")),
+	}
+	if storage.Backend == "" {
+		storage.Backend = StorageGridFS
+	}
+	if storage.Dir == "" {
+		storage.Dir = "receipts"
+	}
+	return storage
+}
+
+func InitReport() ReportConfig {
+	report := ReportConfig{
+		Font:     strings.TrimSpace(os.Getenv("REPORT_FONT")),
+		BoldFont: strings.TrimSpace(os.Getenv("REPORT_FONT_BOLD")),
+	}
+	if report.Font == "" {
+		report.Font = "/usr/share/fonts/truetype/nanum/NanumGothic.ttf"
+	}
+	if report.BoldFont == "" {
+		report.BoldFont = "/usr/share/fonts/truetype/nanum/NanumGothicBold.ttf"
+	}
+	return report
+}
+
+func InitNotify() NotifyConfig {
+	notify := NotifyConfig{
+		SMTPHost:        strings.TrimSpace(os.Getenv("SMTP_HOST")),
+		SMTPPort:        strings.TrimSpace(os.Getenv("SMTP_PORT")),
+		SMTPUsername:    os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:    os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:        strings.TrimSpace(os.Getenv("SMTP_FROM")),
+		VAPIDPublicKey:  strings.TrimSpace(os.Getenv("VAPID_PUBLIC_KEY")),
+		VAPIDPrivateKey: strings.TrimSpace(os.Getenv("VAPID_PRIVATE_KEY")),
+		VAPIDSubject:    strings.TrimSpace(os.Getenv("VAPID_SUBJECT")),
+		GatewayURL:      strings.TrimSpace(os.Getenv("PUSH_GATEWAY_URL")),
+		GatewaySecret:   os.Getenv("PUSH_GATEWAY_SECRET"),
+	}
+	if notify.SMTPPort == "" {
+		notify.SMTPPort = "587"
+	}
+	if notify.SMTPFrom == "" {
+		notify.SMTPFrom = notify.SMTPUsername
+	}
+	return notify
+}
+
 func Init() {
 	MongoConfig := InitDB()
 
 	AppConfig = &Config{
 		MongoDB: MongoConfig,
+		Admin:   InitAdmin(),
+		Storage: InitStorage(),
+		Report:  InitReport(),
+		Notify:  InitNotify(),
 	}
 
 }
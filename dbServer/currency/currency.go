@@ -0,0 +1,91 @@
+package currency
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the currency of records and users that do not name one
+const Default = "KRW"
+
+// exponents is the number of minor-unit digits of each supported ISO 4217 currency.
+// Amounts are stored as integers in minor units, e.g. 12.50 USD is 1250.
+var exponents = map[string]int{
+	"KRW": 0,
+	"JPY": 0,
+	"VND": 0,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"CNY": 2,
+	"HKD": 2,
+	"TWD": 2,
+	"SGD": 2,
+	"THB": 2,
+	"PHP": 2,
+	"MYR": 2,
+	"IDR": 2,
+	"AUD": 2,
+	"NZD": 2,
+	"CAD": 2,
+	"CHF": 2,
+}
+
+// Normalize upper-cases a currency code. An empty code is the default currency.
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return Default
+	}
+	return code
+}
+
+// Valid reports whether code is a supported currency
+func Valid(code string) bool {
+	_, ok := exponents[Normalize(code)]
+	return ok
+}
+
+// Exponent returns the number of minor-unit digits of code
+func Exponent(code string) int {
+	exponent, ok := exponents[Normalize(code)]
+	if !ok {
+		return 2
+	}
+	return exponent
+}
+
+// Codes returns the supported currencies in alphabetical order
+func Codes() []string {
+	codes := make([]string, 0, len(exponents))
+	for code := range exponents {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Format writes an amount in minor units as a decimal, e.g. 1250 USD is "12.50"
+func Format(amount int, code string) string {
+	exponent := Exponent(code)
+	if exponent == 0 {
+		return strconv.Itoa(amount)
+	}
+	return strconv.FormatFloat(float64(amount)/math.Pow10(exponent), 'f', exponent, 64)
+}
+
+// ParseAmount reads a decimal amount such as "12.50" or "1,200" into minor units of code
+func ParseAmount(value string, code string) (int, error) {
+	s := strings.NewReplacer(",", "", " ", "").Replace(strings.TrimSpace(value))
+	if s == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %s", value)
+	}
+	return int(math.Round(f * math.Pow10(Exponent(code)))), nil
+}
@@ -0,0 +1,126 @@
+package currency
+
+import (
+	"context"
+	"dbserver/db"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FactorField holds the factor that converts the minor units of a record into
+// minor units of the home currency. It is null when the table has no rate for the
+// purchase date, so converted amounts of that record drop out of $sum and $avg.
+const FactorField = "_fx"
+
+// Stages returns the aggregation stages that set FactorField on the records matched
+// by match, using the rates on each purchase date in loc. When every record is
+// already in home no rate is looked up.
+func Stages(ctx context.Context, match bson.M, home string, loc *time.Location) (mongo.Pipeline, error) {
+	home = Normalize(home)
+
+	foreign := bson.M{"currency": bson.M{"$ne": home}}
+	if home == Default {
+		foreign = bson.M{"currency": bson.M{"$exists": true, "$nin": bson.A{"", Default}}}
+	}
+	count, err := db.Collection.CountDocuments(ctx, bson.M{"$and": bson.A{match, foreign}}, options.Count().SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return mongo.Pipeline{{{Key: "$set", Value: bson.M{FactorField: 1}}}}, nil
+	}
+
+	recordCurrency := bson.M{"$cond": bson.A{
+		bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{"$currency", ""}}, bson.A{"", Default}}},
+		Default,
+		"$currency",
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"_fxCurrency": recordCurrency,
+			"_fxDate": bson.M{"$dateToString": bson.M{
+				"date":     "$record.timeStamp.at",
+				"format":   "%Y-%m-%d",
+				"timezone": loc.String(),
+			}},
+		}}},
+		rateLookup("$_fxCurrency", "_fxFrom"),
+	}
+
+	homeRate := interface{}(1)
+	if home != Default {
+		pipeline = append(pipeline, rateLookup(home, "_fxHome"))
+		homeRate = bson.M{"$arrayElemAt": bson.A{"$_fxHome.rate", 0}}
+	}
+
+	// 기준 통화(원)를 거쳐 환산하고 두 통화의 소수 자릿수 차이를 보정
+	fromRate := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{"$_fxCurrency", Default}},
+		1,
+		bson.M{"$arrayElemAt": bson.A{"$_fxFrom.rate", 0}},
+	}}
+	digits := bson.M{"$subtract": bson.A{Exponent(home), exponentOf("$_fxCurrency")}}
+	factor := bson.M{"$multiply": bson.A{
+		bson.M{"$divide": bson.A{fromRate, homeRate}},
+		bson.M{"$pow": bson.A{10, digits}},
+	}}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$set", Value: bson.M{
+			FactorField: bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$_fxCurrency", home}}, 1, factor}},
+		}}},
+		bson.D{{Key: "$unset", Value: bson.A{"_fxCurrency", "_fxDate", "_fxFrom", "_fxHome"}}},
+	)
+	return pipeline, nil
+}
+
+// Converted is the expression of amount (in the record's minor units) in the
+// home currency. It must follow the stages of Stages.
+func Converted(amount interface{}) bson.M {
+	return bson.M{"$toLong": bson.M{"$round": bson.A{
+		bson.M{"$multiply": bson.A{amount, "$" + FactorField}},
+		0,
+	}}}
+}
+
+// Unconverted counts the records whose amounts could not be converted
+var Unconverted = bson.M{"$sum": bson.M{"$cond": bson.A{
+	bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + FactorField, nil}}, nil}},
+	1,
+	0,
+}}}
+
+// rateLookup joins the latest rate of currency on or before _fxDate as field
+func rateLookup(currency interface{}, field string) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.M{
+		"from": db.RateCollection.Name(),
+		"let":  bson.M{"currency": currency, "date": "$_fxDate"},
+		"pipeline": bson.A{
+			bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$currency", "$$currency"}},
+				bson.M{"$lte": bson.A{"$date", "$$date"}},
+			}}}},
+			bson.M{"$sort": bson.M{"date": -1}},
+			bson.M{"$limit": 1},
+			bson.M{"$project": bson.M{"_id": 0, "rate": 1}},
+		},
+		"as": field,
+	}}}
+}
+
+// exponentOf is the $switch expression of the minor-unit digits of a currency field
+func exponentOf(field string) bson.M {
+	branches := bson.A{}
+	for _, code := range Codes() {
+		if exponents[code] != 2 {
+			branches = append(branches, bson.M{
+				"case": bson.M{"$eq": bson.A{field, code}},
+				"then": exponents[code],
+			})
+		}
+	}
+	return bson.M{"$switch": bson.M{"branches": branches, "default": 2}}
+}
@@ -0,0 +1,169 @@
+package currency
+
+import (
+	"context"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNoRate is returned when the table has no rate on or before a date
+var ErrNoRate = errors.New("no exchange rate")
+
+// Check normalizes the currency of rate and validates its fields
+func Check(rate *models.ExchangeRate) error {
+	rate.Currency = Normalize(rate.Currency)
+	if !Valid(rate.Currency) {
+		return fmt.Errorf("unsupported currency %s", rate.Currency)
+	}
+	if rate.Currency == Default {
+		return fmt.Errorf("%s is the base currency and has no rate", Default)
+	}
+	if _, err := time.Parse(dates.DateLayout, rate.Date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", rate.Date)
+	}
+	if !(rate.Rate > 0) || math.IsInf(rate.Rate, 0) {
+		return fmt.Errorf("rate of %s on %s must be positive", rate.Currency, rate.Date)
+	}
+	return nil
+}
+
+// Save validates rates and inserts or replaces them by currency and date
+func Save(ctx context.Context, rates []models.ExchangeRate, source string) (int, error) {
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(rates))
+	for i := range rates {
+		if err := Check(&rates[i]); err != nil {
+			return 0, err
+		}
+		rates[i].Source = source
+		rates[i].UpdatedAt = now
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"currency": rates[i].Currency, "date": rates[i].Date}).
+			SetReplacement(rates[i]).
+			SetUpsert(true))
+	}
+
+	result, err := db.RateCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return int(result.UpsertedCount + result.ModifiedCount), nil
+}
+
+// RateOn returns the rate of code on date, taking the latest rate on or before it
+// so weekends and holidays use the last published rate
+func RateOn(ctx context.Context, code string, date string) (float64, error) {
+	code = Normalize(code)
+	if code == Default {
+		return 1, nil
+	}
+
+	var rate models.ExchangeRate
+	err := db.RateCollection.FindOne(ctx,
+		bson.M{"currency": code, "date": bson.M{"$lte": date}},
+		options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}}),
+	).Decode(&rate)
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("%w for %s on %s", ErrNoRate, code, date)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rate.Rate, nil
+}
+
+// Convert converts amount in minor units of from into minor units of to at the
+// rates on date
+func Convert(ctx context.Context, amount int, from string, to string, date string) (int, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, err := RateOn(ctx, from, date)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := RateOn(ctx, to, date)
+	if err != nil {
+		return 0, err
+	}
+
+	major := float64(amount) / math.Pow10(Exponent(from))
+	return int(math.Round(major * fromRate / toRate * math.Pow10(Exponent(to)))), nil
+}
+
+// ReadFile reads a rate table from a .csv or .json file
+func ReadFile(path string) ([]models.ExchangeRate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ReadJSON(file)
+	}
+	return ReadCSV(file)
+}
+
+// ReadJSON reads a JSON array of {"currency", "date", "rate"} objects
+func ReadJSON(r io.Reader) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	if err := json.NewDecoder(r).Decode(&rates); err != nil {
+		return nil, fmt.Errorf("invalid rate file: %v", err)
+	}
+	return rates, nil
+}
+
+// ReadCSV reads "date,currency,rate" lines. A header line is skipped.
+func ReadCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []models.ExchangeRate
+	for line := 1; ; line++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		value, err := strconv.ParseFloat(strings.ReplaceAll(fields[2], ",", ""), 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, fields[2])
+		}
+		rates = append(rates, models.ExchangeRate{
+			Date:     strings.TrimPrefix(strings.TrimSpace(fields[0]), "\ufeff"),
+			Currency: fields[1],
+			Rate:     value,
+		})
+	}
+	return rates, nil
+}
@@ -0,0 +1,108 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreviousPeriod(t *testing.T) {
+	loc := LoadLocation("Asia/Seoul")
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{"march against february", day(2026, 3, 1), day(2026, 4, 1), day(2026, 2, 1), day(2026, 3, 1)},
+		{"february against january", day(2026, 2, 1), day(2026, 3, 1), day(2026, 1, 1), day(2026, 2, 1)},
+		{"january against december", day(2026, 1, 1), day(2026, 2, 1), day(2025, 12, 1), day(2026, 1, 1)},
+		{"quarter against quarter", day(2026, 4, 1), day(2026, 7, 1), day(2026, 1, 1), day(2026, 4, 1)},
+		{"week against week", day(2026, 3, 2), day(2026, 3, 9), day(2026, 2, 23), day(2026, 3, 2)},
+		{"days across month end", day(2026, 3, 1), day(2026, 3, 31), day(2026, 1, 30), day(2026, 3, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := PreviousPeriod(tt.from, tt.to, loc)
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("PreviousPeriod(%s, %s) = %s, %s; want %s, %s",
+					tt.from.Format("2006-01-02"), tt.to.Format("2006-01-02"),
+					from.Format("2006-01-02"), to.Format("2006-01-02"),
+					tt.wantFrom.Format("2006-01-02"), tt.wantTo.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestPreviousPeriodAcrossDaylightSaving(t *testing.T) {
+	loc := LoadLocation("America/New_York")
+	// 2026-03-08에 서머타임이 시작되어 이 주는 167시간
+	from := time.Date(2026, 3, 8, 0, 0, 0, 0, loc)
+	to := time.Date(2026, 3, 15, 0, 0, 0, 0, loc)
+
+	prevFrom, prevTo := PreviousPeriod(from, to, loc)
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, loc); !prevFrom.Equal(want) {
+		t.Errorf("from = %s, want %s", prevFrom, want)
+	}
+	if !prevTo.Equal(from) {
+		t.Errorf("to = %s, want %s", prevTo, from)
+	}
+}
+
+func TestParse(t *testing.T) {
+	loc := LoadLocation("Asia/Seoul")
+	at := func(y int, m time.Month, d, h, min, s int) time.Time {
+		return time.Date(y, m, d, h, min, s, 0, loc)
+	}
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		hasTime bool
+	}{
+		{"2024-11-30", at(2024, 11, 30, 0, 0, 0), false},
+		{"  2024-11-30  ", at(2024, 11, 30, 0, 0, 0), false},
+		{"24.11.30 14:03", at(2024, 11, 30, 14, 3, 0), true},
+		{"2024/11/30 오후 2:03:11", at(2024, 11, 30, 14, 3, 11), true},
+		{"2024/11/30 오전 12:10", at(2024, 11, 30, 0, 10, 0), true},
+		{"2024/11/30 2:03 PM", at(2024, 11, 30, 14, 3, 0), true},
+		{"2024년 11월 30일", at(2024, 11, 30, 0, 0, 0), false},
+		{"2024년 11월 30일 14시 3분", at(2024, 11, 30, 14, 3, 0), true},
+		{"2024년 11월 30일 (토) 오후 2:03", at(2024, 11, 30, 14, 3, 0), true},
+		{"2024년 11월 30일(토) 14:03", at(2024, 11, 30, 14, 3, 0), true},
+		{"2024-11-30 (토)", at(2024, 11, 30, 0, 0, 0), false},
+		{"20241130", at(2024, 11, 30, 0, 0, 0), false},
+		{"2024-11-30T14:03:11", at(2024, 11, 30, 14, 3, 11), true},
+		{"2024-11-30T05:03:11Z", at(2024, 11, 30, 14, 3, 11), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, hasTime, err := Parse(tt.value, loc)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.Equal(tt.want) || hasTime != tt.hasTime {
+				t.Errorf("Parse() = %s, %v; want %s, %v", got, hasTime, tt.want, tt.hasTime)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	loc := LoadLocation("Asia/Seoul")
+	for _, value := range []string{
+		"",
+		"어제",
+		"2024-13-01",
+		"2023-02-29",
+		"2024-11-30 25:00",
+		"2024-11-30 (토) 점심",
+	} {
+		if got, _, err := Parse(value, loc); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", value, got)
+		}
+	}
+}
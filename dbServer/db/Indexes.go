@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes는 서버 시작 시 필요한 인덱스를 생성합니다
+func EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 레코드별 버전은 중복될 수 없습니다
+	_, err := HistoryCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "rid", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 예산 알림은 예산, 기간, 임계값마다 한 번만 생성
+	_, err = AlertCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "bid", Value: 1}, {Key: "periodStart", Value: 1}, {Key: "threshold", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 태그 카탈로그는 사용자별로 이름이 중복될 수 없음
+	_, err = TagCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uid", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 상품별 가격 이력 조회
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "uid", Value: 1}, {Key: "product.normName", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 사용자별 구매일 정렬 및 기간 조회
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "uid", Value: 1}, {Key: "record.timeStamp.at", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 가져오기 배치 조회 및 되돌리기
+	_, err = ImportCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "batchId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uid", Value: 1}, {Key: "record.importBatchId", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 영수증 중복 검사
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "uid", Value: 1}, {Key: "record.fingerprint", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 가구 구성원 조회와 초대 토큰
+	_, err = HouseholdCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "members.uid", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	_, err = InvitationCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "token", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 가구 레코드 조회
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "groupId", Value: 1}, {Key: "record.timeStamp.at", Value: -1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 통화별 환율은 날짜마다 하나
+	_, err = RateCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "currency", Value: 1}, {Key: "date", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 장보기 목록의 미완료 항목 조회
+	_, err = ShoppingCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "uid", Value: 1}, {Key: "checked", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 매장 디렉터리 조회와 중복 판별
+	_, err = MartCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "martId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "uid", Value: 1}, {Key: "normBizNum", Value: 1}}},
+		{Keys: bson.D{{Key: "uid", Value: 1}, {Key: "normTel", Value: 1}}},
+		{Keys: bson.D{{Key: "uid", Value: 1}, {Key: "normAddress", Value: 1}}},
+		{Keys: bson.D{{Key: "uid", Value: 1}, {Key: "normNames", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uid", Value: 1}, {Key: "mart.martId", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	_, err = Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uid", Value: 1}, {Key: "issues.resolved", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 레코드 이벤트는 다시 연결한 클라이언트가 놓친 것을 받을 수 있게 하루 보관
+	_, err = EventCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 보고서 PDF는 사용자, 범위, 월마다 하나만 보관
+	_, err = ReportCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uid", Value: 1}, {Key: "group", Value: 1}, {Key: "month", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 작업 실행 기록은 90일 보관
+	_, err = JobRunCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "runId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "job", Value: 1}, {Key: "startedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "startedAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(90 * 24 * 60 * 60)},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	// 알림은 보낼 것을 찾는 인덱스와 받은 편지함 인덱스, 180일 보관
+	_, err = NotificationCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "nid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "deliverAfter", Value: 1}}},
+		{Keys: bson.D{{Key: "uid", Value: 1}, {Key: "inbox", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(180 * 24 * 60 * 60)},
+	})
+	if err != nil {
+		log.Printf("Index error: %v\n", err)
+	}
+
+	for _, c := range []struct {
+		collection *mongo.Collection
+		key        string
+	}{
+		{NotificationPrefCollection, "uid"},
+		{PushSubscriptionCollection, "endpoint"},
+		{PushDeviceCollection, "token"},
+	} {
+		_, err = c.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: c.key, Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			log.Printf("Index error: %v\n", err)
+		}
+	}
+}
@@ -13,3 +13,10 @@ func SelectTable(client *mongo.Client) *mongo.Collection {
 
 	return collection
 }
+
+// SelectCollection은 같은 데이터베이스의 다른 컬렉션을 선택합니다
+func SelectCollection(client *mongo.Client, name string) *mongo.Collection {
+	database := client.Database(config.AppConfig.MongoDB.Database)
+
+	return database.Collection(name)
+}
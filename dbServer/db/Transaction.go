@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// transactions is whether the server can run transactions. A standalone mongod can
+// not; a replica set, even with one member, or a sharded cluster can.
+var transactions bool
+
+// detectTransactions asks the server what it is, see SupportsTransactions
+func detectTransactions(client *mongo.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		log.Printf("Failed to detect transaction support: %v\n", err)
+		return
+	}
+	transactions = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !transactions {
+		// 단독 서버에서는 레코드 변경과 기록이 함께 되돌려지지 않음
+		log.Println("MongoDB is not a replica set: transactions are off, atomic bulk operations are rejected")
+	}
+}
+
+// SupportsTransactions reports whether Transaction really runs in a transaction
+func SupportsTransactions() bool {
+	return transactions
+}
+
+// Transaction runs fn in a transaction. Every query in fn must use the context it
+// receives. fn is run again when the server reports a transient error, so it must
+// not keep state from an earlier attempt.
+//
+// A standalone server has no transactions, so there fn runs once in a plain session
+// and a failure leaves the writes made before it. Callers that must be all or nothing
+// check SupportsTransactions first.
+func Transaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	if !transactions {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
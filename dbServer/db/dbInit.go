@@ -5,11 +5,66 @@ import (
 )
 
 var (
-	Client     *mongo.Client
-	Collection *mongo.Collection
+	Client            *mongo.Client
+	Collection        *mongo.Collection
+	HistoryCollection *mongo.Collection
+	BudgetCollection  *mongo.Collection
+	AlertCollection   *mongo.Collection
+
+	CategoryRuleCollection *mongo.Collection
+	CategoryJobCollection  *mongo.Collection
+	TagCollection          *mongo.Collection
+	ImportCollection       *mongo.Collection
+
+	HouseholdCollection  *mongo.Collection
+	InvitationCollection *mongo.Collection
+
+	PersonCollection     *mongo.Collection
+	SettlementCollection *mongo.Collection
+
+	RateCollection     *mongo.Collection
+	ShoppingCollection *mongo.Collection
+	MartCollection     *mongo.Collection
+
+	EventCollection   *mongo.Collection
+	CounterCollection *mongo.Collection
+	ReportCollection  *mongo.Collection
+	JobCollection     *mongo.Collection
+	JobRunCollection  *mongo.Collection
+
+	NotificationCollection     *mongo.Collection
+	NotificationPrefCollection *mongo.Collection
+	PushSubscriptionCollection *mongo.Collection
+	PushDeviceCollection       *mongo.Collection
 )
 
 func DBInit() {
 	Client, _, _ = ConnectDB()
+	detectTransactions(Client)
 	Collection = SelectTable(Client)
+	HistoryCollection = SelectCollection(Client, "RecordHistory")
+	BudgetCollection = SelectCollection(Client, "Budget")
+	AlertCollection = SelectCollection(Client, "BudgetAlert")
+	CategoryRuleCollection = SelectCollection(Client, "CategoryRule")
+	CategoryJobCollection = SelectCollection(Client, "CategoryJob")
+	TagCollection = SelectCollection(Client, "Tag")
+	ImportCollection = SelectCollection(Client, "ImportBatch")
+	HouseholdCollection = SelectCollection(Client, "Household")
+	InvitationCollection = SelectCollection(Client, "HouseholdInvitation")
+	PersonCollection = SelectCollection(Client, "SplitPerson")
+	SettlementCollection = SelectCollection(Client, "Settlement")
+	RateCollection = SelectCollection(Client, "ExchangeRate")
+	ShoppingCollection = SelectCollection(Client, "ShoppingList")
+	MartCollection = SelectCollection(Client, "Mart")
+	EventCollection = SelectCollection(Client, "RecordEvent")
+	CounterCollection = SelectCollection(Client, "Counter")
+	ReportCollection = SelectCollection(Client, "Report")
+	JobCollection = SelectCollection(Client, "Job")
+	JobRunCollection = SelectCollection(Client, "JobRun")
+	NotificationCollection = SelectCollection(Client, "Notification")
+	NotificationPrefCollection = SelectCollection(Client, "NotificationPreference")
+	PushSubscriptionCollection = SelectCollection(Client, "PushSubscription")
+	PushDeviceCollection = SelectCollection(Client, "PushDevice")
+
+	EnsureIndexes()
 }
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// pollInterval is how often the event collection is read for events of other
+	// processes and of transactions that committed after Publish
+	pollInterval = time.Second
+	// lateWindow is how long after it was numbered an event is still looked for
+	lateWindow = time.Minute
+	// bufferSize is how many events a slow stream may fall behind before it is closed
+	bufferSize = 64
+)
+
+var (
+	mu          sync.Mutex
+	subscribers = map[chan models.RecordEvent]bool{}
+	wake        = make(chan struct{}, 1)
+	startOnce   sync.Once
+)
+
+// Start runs the dispatcher delivering stored events to the subscribers
+func Start() {
+	startOnce.Do(func() {
+		go dispatch()
+	})
+}
+
+// Subscribe returns a channel receiving every new event. The channel is closed when
+// the subscriber falls too far behind; it should reconnect and resume.
+func Subscribe() chan models.RecordEvent {
+	ch := make(chan models.RecordEvent, bufferSize)
+	mu.Lock()
+	subscribers[ch] = true
+	mu.Unlock()
+	return ch
+}
+
+// Unsubscribe stops delivering to ch
+func Unsubscribe(ch chan models.RecordEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+	if subscribers[ch] {
+		delete(subscribers, ch)
+		close(ch)
+	}
+}
+
+// Wake makes the dispatcher look for new events now
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+func dispatch() {
+	started := time.Now()
+	delivered := map[int64]time.Time{}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wake:
+		case <-ticker.C:
+		}
+
+		// 번호 순서와 커밋 순서가 다를 수 있어 최근 이벤트를 모두 보고 안 보낸 것만 보냄
+		since := time.Now().Add(-lateWindow)
+		if since.Before(started) {
+			since = started
+		}
+		events, err := recent(since)
+		if err != nil {
+			log.Printf("Event error: %v\n", err)
+			continue
+		}
+
+		// 구독자가 없어도 보낸 것으로 기록해야 나중에 구독한 스트림에 지난 이벤트가 가지 않음
+		for _, event := range events {
+			if _, ok := delivered[event.Seq]; ok {
+				continue
+			}
+			delivered[event.Seq] = event.At
+			broadcast(event)
+		}
+		for seq, at := range delivered {
+			if at.Before(since) {
+				delete(delivered, seq)
+			}
+		}
+	}
+}
+
+func recent(since time.Time) ([]models.RecordEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := db.EventCollection.Find(ctx,
+		bson.M{"at": bson.M{"$gte": since}},
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.RecordEvent
+	err = cursor.All(ctx, &events)
+	return events, err
+}
+
+func broadcast(event models.RecordEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+	for ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 밀린 구독자는 끊고 다시 연결해서 이어 받게 함
+			delete(subscribers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package events stores record change events and delivers them to the open event
+// streams of this process. Events are kept in Mongo, so a client can resume after a
+// reconnect and events written by ocrServer reach the streams too, without change
+// streams or a replica set.
+package events
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// counterId is the Counter document numbering the events
+const counterId = "recordEvent"
+
+// New builds an event about record
+func New(eventType string, actor string, record models.RecordInput) models.RecordEvent {
+	return models.RecordEvent{
+		Type:    eventType,
+		Rid:     record.Record.Rid,
+		Uid:     record.Uid,
+		GroupId: record.GroupId,
+		Actor:   actor,
+	}
+}
+
+// Publish numbers and stores events. They reach the streams once written, so events
+// published inside a transaction are only delivered if it commits.
+func Publish(ctx context.Context, events ...models.RecordEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// 번호는 트랜잭션 밖에서 받아 동시에 도는 트랜잭션끼리 충돌하지 않게 함
+	seqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	last, err := nextSeq(seqCtx, len(events))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		events[i].Seq = last - int64(len(events)-1-i)
+		if events[i].At.IsZero() {
+			events[i].At = now
+		}
+		docs[i] = events[i]
+	}
+	if _, err := db.EventCollection.InsertMany(ctx, docs); err != nil {
+		return err
+	}
+
+	Wake()
+	return nil
+}
+
+// nextSeq reserves n sequence numbers and returns the last one
+func nextSeq(ctx context.Context, n int) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := db.CounterCollection.FindOneAndUpdate(ctx, bson.M{"_id": counterId}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(&counter)
+	// 처음 두 요청이 동시에 카운터를 만들면 한쪽은 키 중복으로 실패하므로 다시 시도
+	if mongo.IsDuplicateKeyError(err) {
+		err = db.CounterCollection.FindOneAndUpdate(ctx, bson.M{"_id": counterId}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(&counter)
+	}
+	return counter.Seq, err
+}
+
+// Since returns up to limit stored events after seq, oldest first. complete is false
+// when events after seq may already have expired.
+//
+// Numbers are reserved before the events are written, so an event numbered below seq
+// can be stored after the client saw seq. Events numbered below seq that were stored
+// within lateWindow of it are returned again; a client may see them twice.
+func Since(ctx context.Context, seq int64, limit int64) (events []models.RecordEvent, complete bool, err error) {
+	var oldest models.RecordEvent
+	err = db.EventCollection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "seq", Value: 1}})).Decode(&oldest)
+	if err == mongo.ErrNoDocuments {
+		return []models.RecordEvent{}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	complete = oldest.Seq <= seq+1
+
+	filter := bson.M{"seq": bson.M{"$gt": seq}}
+	var last models.RecordEvent
+	err = db.EventCollection.FindOne(ctx, bson.M{"seq": seq}).Decode(&last)
+	switch {
+	case err == nil:
+		filter = bson.M{"$or": bson.A{
+			filter,
+			bson.M{"seq": bson.M{"$lt": seq}, "at": bson.M{"$gte": last.At.Add(-lateWindow)}},
+		}}
+	case err != mongo.ErrNoDocuments:
+		return nil, false, err
+	}
+
+	cursor, err := db.EventCollection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	events = []models.RecordEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, false, err
+	}
+	return events, complete, nil
+}
+
+// Latest returns the seq of the newest stored event, 0 if there is none
+func Latest(ctx context.Context) (int64, error) {
+	var newest models.RecordEvent
+	err := db.EventCollection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})).Decode(&newest)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return newest.Seq, err
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// Excel은 BOM이 없으면 UTF-8 CSV의 한글을 깨뜨림
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := w.Write(utf8BOM); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) WriteRow(cells []interface{}) error {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		value := cellString(cell)
+		if _, isString := cell.(string); isString {
+			value = escapeFormula(value)
+		}
+		row[i] = value
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// escapeFormula keeps spreadsheet programs from running text cells as formulas
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	LayoutRecord = "record"
+	LayoutItem   = "item"
+)
+
+// Writer writes rows of a spreadsheet one at a time. Cells may be string, int,
+// int64 or float64; anything else is written with fmt.
+type Writer interface {
+	WriteRow(cells []interface{}) error
+	// Flush sends buffered rows to the underlying writer
+	Flush() error
+	// Close finishes the file; the writer must not be used afterwards
+	Close() error
+}
+
+// NewWriter returns a writer for format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w, "records")
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// ContentType returns the MIME type of format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func cellString(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(cell)
+}
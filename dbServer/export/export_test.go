@@ -0,0 +1,113 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	rows := [][]interface{}{
+		{"=HYPERLINK(\"http://x\")", "+82", "-1", "@SUM(A1)", "\tcmd", "마트"},
+		{-1500, 2.5, int64(3), nil, "a,b", "줄\n바꿈"},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, utf8BOM) {
+		t.Fatalf("output does not start with a BOM: %q", out[:min(len(out), 8)])
+	}
+	records, err := csv.NewReader(bytes.NewReader(out[len(utf8BOM):])).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		{"'=HYPERLINK(\"http://x\")", "'+82", "'-1", "'@SUM(A1)", "'\tcmd", "마트"},
+		// 숫자 셀은 수식 방지 대상이 아님
+		{"-1500", "2.5", "3", "", "a,b", "줄\n바꿈"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+	if !bytes.Contains(out, []byte("마트\r\n")) {
+		t.Errorf("rows do not end with CRLF")
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.WriteRow([]interface{}{"<b>&", "=1+1", "벨\x07소리", 1200, -2.5, nil}); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("package is missing %s", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<t xml:space="preserve">&lt;b&gt;&amp;</t>`,
+		// 인라인 문자열은 수식으로 실행되지 않으므로 그대로 둠
+		`<t xml:space="preserve">=1+1</t>`,
+		`<t xml:space="preserve">벨소리</t>`,
+		`<c><v>1200</v></c>`,
+		`<c><v>-2.5</v></c>`,
+		`<t xml:space="preserve"></t>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s:\n%s", want, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, "</sheetData></worksheet>") {
+		t.Errorf("sheet is not closed: %s", sheet)
+	}
+}
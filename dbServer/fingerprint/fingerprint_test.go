@@ -0,0 +1,110 @@
+package fingerprint
+
+import (
+	"dbserver/dates"
+	"dbserver/models"
+	"testing"
+	"time"
+)
+
+func receipt() models.RecordInput {
+	at := time.Date(2026, 3, 14, 18, 30, 12, 0, dates.LoadLocation("Asia/Seoul"))
+	return models.RecordInput{
+		Record: models.DBRecord{TimeStamp: models.NewPurchaseTime(at, true)},
+		Mart:   models.DBMart{MartName: "이마트 성수점", BizNum: "123-45-67890"},
+		Product: []models.DBProduct{
+			{Pname: "우유", Price: 2500, Amount: 2},
+			{Pname: "식빵", Price: 3200, Amount: 1},
+		},
+		TotalPrice: 8200,
+	}
+}
+
+func TestOf(t *testing.T) {
+	base := Of(receipt())
+	if base != Of(receipt()) {
+		t.Fatalf("Of() is not stable")
+	}
+
+	tests := []struct {
+		name   string
+		change func(r *models.RecordInput)
+		same   bool
+	}{
+		{"items reordered", func(r *models.RecordInput) {
+			r.Product[0], r.Product[1] = r.Product[1], r.Product[0]
+		}, true},
+		{"item names spaced differently", func(r *models.RecordInput) {
+			r.Product[0].Pname = " 우 유 "
+		}, true},
+		// 초 단위는 사진마다 다르게 읽힐 수 있으므로 분까지만 비교
+		{"seconds differ", func(r *models.RecordInput) {
+			r.Record.TimeStamp.At = r.Record.TimeStamp.At.Add(30 * time.Second)
+		}, true},
+		{"mart name differs with the same business number", func(r *models.RecordInput) {
+			r.Mart.MartName = "(주)이마트"
+			r.Mart.BizNum = "1234567890"
+		}, true},
+		{"date only", func(r *models.RecordInput) {
+			r.Record.TimeStamp.HasTime = false
+		}, false},
+		{"minute differs", func(r *models.RecordInput) {
+			r.Record.TimeStamp.At = r.Record.TimeStamp.At.Add(time.Minute)
+		}, false},
+		{"business number differs", func(r *models.RecordInput) {
+			r.Mart.BizNum = "123-45-00000"
+		}, false},
+		{"total differs", func(r *models.RecordInput) {
+			r.TotalPrice = 8300
+		}, false},
+		{"amount differs", func(r *models.RecordInput) {
+			r.Product[1].Amount = 2
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := receipt()
+			tt.change(&r)
+			if got := Of(r) == base; got != tt.same {
+				t.Errorf("same fingerprint = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestSameMerchant(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b models.DBMart
+		want bool
+	}{
+		{"same business number, different names", models.DBMart{MartName: "이마트", BizNum: "123-45-67890"}, models.DBMart{MartName: "(주)이마트 성수점", BizNum: "1234567890"}, true},
+		{"business number wins over the name", models.DBMart{MartName: "이마트", BizNum: "123-45-67890"}, models.DBMart{MartName: "이마트", BizNum: "123-45-00000"}, false},
+		{"one business number missing", models.DBMart{MartName: "이마트 성수점", BizNum: "123-45-67890"}, models.DBMart{MartName: "이마트성수점"}, true},
+		{"names only", models.DBMart{MartName: "Starbucks"}, models.DBMart{MartName: "STAR BUCKS"}, true},
+		{"different names", models.DBMart{MartName: "이마트"}, models.DBMart{MartName: "홈플러스"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameMerchant(tt.a, tt.b); got != tt.want {
+				t.Errorf("SameMerchant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurchaseKey(t *testing.T) {
+	kst := dates.LoadLocation("Asia/Seoul")
+	// 자정 직후 KST는 UTC로 전날이므로 날짜는 구매한 곳의 시간대로 정함
+	at := time.Date(2026, 3, 14, 0, 30, 0, 0, kst)
+
+	if got := purchaseKey(models.NewPurchaseTime(at, true)); got != "2026-03-14T00:30" {
+		t.Errorf("purchaseKey() = %q, want 2026-03-14T00:30", got)
+	}
+	if got := purchaseKey(models.NewPurchaseTime(at, false)); got != "2026-03-14" {
+		t.Errorf("purchaseKey() = %q for a date-only receipt, want 2026-03-14", got)
+	}
+	if got := purchaseKey(models.PurchaseTime{}); got != "" {
+		t.Errorf("purchaseKey() = %q without a time, want empty", got)
+	}
+}
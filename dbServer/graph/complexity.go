@@ -0,0 +1,509 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// Operation is an operation of a query document with its estimated cost
+type Operation struct {
+	// Type is query, mutation or subscription
+	Type string
+	Cost int
+}
+
+// Cost estimates what the operation of query costs before it runs. Every field costs
+// 1, and the fields below a list are counted once per element: the first argument
+// where the field has one (or its default), otherwise ListSize. Lists directly in a
+// connection are not counted again. The query should have been validated already.
+func Cost(schema *types.Schema, query string, operationName string, variables map[string]interface{}) (*Operation, error) {
+	doc, err := parseDocument(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var op *operation
+	for _, o := range doc.operations {
+		if o.name == operationName || (operationName == "" && len(doc.operations) == 1) {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		if operationName == "" {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return nil, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	root, ok := schema.EntryPoints[op.kind]
+	if !ok {
+		return nil, fmt.Errorf("%s is not supported", op.kind)
+	}
+
+	values := map[string]interface{}{}
+	for name, def := range op.variables {
+		values[name] = def
+	}
+	for name, v := range variables {
+		values[name] = v
+	}
+
+	a := &analysis{schema: schema, doc: doc, variables: values}
+	cost := a.cost(op.selections, root.TypeName(), 0)
+	return &Operation{Type: op.kind, Cost: int(math.Min(cost, math.MaxInt32))}, nil
+}
+
+type analysis struct {
+	schema    *types.Schema
+	doc       *document
+	variables map[string]interface{}
+}
+
+func (a *analysis) cost(selections []*selection, typeName string, depth int) float64 {
+	// 조각이 서로를 펼치는 경우 검증에서 걸러지지만 여기서도 멈춤
+	if depth > 2*MaxDepth {
+		return 0
+	}
+
+	var object *types.ObjectTypeDefinition
+	if t, ok := a.schema.Types[typeName].(*types.ObjectTypeDefinition); ok {
+		object = t
+	}
+
+	total := 0.0
+	for _, sel := range selections {
+		switch {
+		case sel.spread != "":
+			if fragment, ok := a.doc.fragments[sel.spread]; ok {
+				total += a.cost(fragment.selections, fragment.on, depth+1)
+			}
+		case sel.inline:
+			on := typeName
+			if sel.on != "" {
+				on = sel.on
+			}
+			total += a.cost(sel.selections, on, depth+1)
+		default:
+			total++
+			if len(sel.selections) == 0 {
+				continue
+			}
+			// 인트로스펙션처럼 스키마에 없는 필드는 하위 필드만 셈
+			var field *types.FieldDefinition
+			if object != nil {
+				field = object.Fields.Get(sel.name)
+			}
+			if field == nil {
+				total += a.cost(sel.selections, "", depth+1)
+				continue
+			}
+			total += a.multiplier(field, sel, typeName) * a.cost(sel.selections, namedType(field.Type), depth+1)
+		}
+	}
+	return total
+}
+
+// multiplier is how many times the selections below field are counted
+func (a *analysis) multiplier(field *types.FieldDefinition, sel *selection, typeName string) float64 {
+	if arg := field.Arguments.Get("first"); arg != nil {
+		var value interface{}
+		if arg.Default != nil {
+			value = arg.Default.Deserialize(nil)
+		}
+		if v, ok := sel.arguments["first"]; ok {
+			value = v.resolve(a.variables)
+		}
+		if n, ok := number(value); ok {
+			return math.Max(0, math.Min(n, math.MaxInt32))
+		}
+		return ListSize
+	}
+	if isList(field.Type) && !strings.HasSuffix(typeName, "Connection") {
+		return ListSize
+	}
+	return 1
+}
+
+func namedType(t types.Type) string {
+	switch t := t.(type) {
+	case *types.NonNull:
+		return namedType(t.OfType)
+	case *types.List:
+		return namedType(t.OfType)
+	case types.NamedType:
+		return t.TypeName()
+	}
+	return ""
+}
+
+func isList(t types.Type) bool {
+	if nonNull, ok := t.(*types.NonNull); ok {
+		t = nonNull.OfType
+	}
+	_, ok := t.(*types.List)
+	return ok
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// 비용 계산에 필요한 만큼만 읽는 쿼리 문서 파서
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	variables  map[string]interface{}
+	selections []*selection
+}
+
+type fragment struct {
+	on         string
+	selections []*selection
+}
+
+type selection struct {
+	name       string
+	arguments  map[string]argument
+	selections []*selection
+	// spread is the name of a fragment spread
+	spread string
+	// inline is an inline fragment, optionally on type on
+	inline bool
+	on     string
+}
+
+// argument is a literal number or a variable; other values are not needed
+type argument struct {
+	variable string
+	value    interface{}
+}
+
+func (a argument) resolve(variables map[string]interface{}) interface{} {
+	if a.variable != "" {
+		return variables[a.variable]
+	}
+	return a.value
+}
+
+const (
+	tokenEOF = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func parseDocument(src string) (doc *document, err error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			if perr, ok := r.(parseError); ok {
+				doc, err = nil, perr
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	p.next()
+	doc = &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.selectionSet()})
+		case p.peek(tokenName, "fragment"):
+			p.next()
+			name := p.expect(tokenName).text
+			p.expectText("on")
+			f := &fragment{on: p.expect(tokenName).text}
+			p.directives()
+			f.selections = p.selectionSet()
+			doc.fragments[name] = f
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			op := &operation{kind: p.tok.text, variables: map[string]interface{}{}}
+			p.next()
+			if p.tok.kind == tokenName {
+				op.name = p.tok.text
+				p.next()
+			}
+			if p.skip("(") {
+				for !p.skip(")") {
+					p.expectText("$")
+					name := p.expect(tokenName).text
+					p.expectText(":")
+					p.typeRef()
+					if p.skip("=") {
+						op.variables[name] = p.value().value
+					}
+					p.directives()
+				}
+			}
+			p.directives()
+			op.selections = p.selectionSet()
+			doc.operations = append(doc.operations, op)
+		default:
+			p.fail("unexpected %q", p.tok.text)
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) selectionSet() []*selection {
+	p.expectText("{")
+	var selections []*selection
+	for !p.skip("}") {
+		if p.skip("...") {
+			sel := &selection{}
+			switch {
+			case p.peek(tokenName, "on"):
+				p.next()
+				sel.inline = true
+				sel.on = p.expect(tokenName).text
+			case p.tok.kind == tokenName:
+				sel.spread = p.tok.text
+				p.next()
+			default:
+				sel.inline = true
+			}
+			p.directives()
+			if sel.inline {
+				sel.selections = p.selectionSet()
+			}
+			selections = append(selections, sel)
+			continue
+		}
+
+		sel := &selection{name: p.expect(tokenName).text}
+		if p.skip(":") {
+			sel.name = p.expect(tokenName).text
+		}
+		sel.arguments = p.arguments()
+		p.directives()
+		if p.peek(tokenPunct, "{") {
+			sel.selections = p.selectionSet()
+		}
+		selections = append(selections, sel)
+	}
+	return selections
+}
+
+func (p *parser) arguments() map[string]argument {
+	args := map[string]argument{}
+	if !p.skip("(") {
+		return args
+	}
+	for !p.skip(")") {
+		name := p.expect(tokenName).text
+		p.expectText(":")
+		args[name] = p.value()
+	}
+	return args
+}
+
+func (p *parser) directives() {
+	for p.skip("@") {
+		p.expect(tokenName)
+		p.arguments()
+	}
+}
+
+func (p *parser) typeRef() {
+	if p.skip("[") {
+		p.typeRef()
+		p.expectText("]")
+	} else {
+		p.expect(tokenName)
+	}
+	p.skip("!")
+}
+
+func (p *parser) value() argument {
+	tok := p.tok
+	switch {
+	case p.skip("$"):
+		return argument{variable: p.expect(tokenName).text}
+	case p.skip("["):
+		for !p.skip("]") {
+			p.value()
+		}
+	case p.skip("{"):
+		for !p.skip("}") {
+			p.expect(tokenName)
+			p.expectText(":")
+			p.value()
+		}
+	case tok.kind == tokenInt:
+		p.next()
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			p.fail("invalid number %s", tok.text)
+		}
+		return argument{value: n}
+	case tok.kind == tokenFloat, tok.kind == tokenString, tok.kind == tokenName:
+		p.next()
+	default:
+		p.fail("unexpected %q", tok.text)
+	}
+	return argument{}
+}
+
+func (p *parser) peek(kind int, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+// skip consumes the punctuator text if it is next
+func (p *parser) skip(text string) bool {
+	if p.peek(tokenPunct, text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind int) token {
+	tok := p.tok
+	if tok.kind != kind {
+		if tok.kind == tokenEOF {
+			p.fail("unexpected end of query")
+		}
+		p.fail("unexpected %q", tok.text)
+	}
+	p.next()
+	return tok
+}
+
+func (p *parser) expectText(text string) {
+	if p.tok.text != text || (p.tok.kind != tokenPunct && p.tok.kind != tokenName) {
+		p.fail("expected %q", text)
+	}
+	p.next()
+}
+
+type parseError struct {
+	message string
+}
+
+func (e parseError) Error() string {
+	return e.message
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	panic(parseError{fmt.Sprintf(format, args...)})
+}
+
+// next reads the following token, skipping white space, commas and comments
+func (p *parser) next() {
+	src := p.src
+	for p.pos < len(src) {
+		ch := src[p.pos]
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',' {
+			p.pos++
+		} else if ch == '#' {
+			for p.pos < len(src) && src[p.pos] != '\n' && src[p.pos] != '\r' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+	if p.pos >= len(src) {
+		p.tok = token{kind: tokenEOF}
+		return
+	}
+
+	start := p.pos
+	ch := src[p.pos]
+	switch {
+	case strings.HasPrefix(src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{tokenPunct, "..."}
+	case strings.IndexByte("!$()&:=@[]{}|", ch) >= 0:
+		p.pos++
+		p.tok = token{tokenPunct, string(ch)}
+	case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+		for p.pos < len(src) && isNameChar(src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{tokenName, src[start:p.pos]}
+	case ch == '-' || ch >= '0' && ch <= '9':
+		kind := tokenInt
+		p.pos++
+		for p.pos < len(src) {
+			c := src[p.pos]
+			if c == '.' || c == 'e' || c == 'E' || (c == '-' || c == '+') && (src[p.pos-1] == 'e' || src[p.pos-1] == 'E') {
+				kind = tokenFloat
+			} else if c < '0' || c > '9' {
+				break
+			}
+			p.pos++
+		}
+		p.tok = token{kind, src[start:p.pos]}
+	case strings.HasPrefix(src[p.pos:], `"""`):
+		p.pos += 3
+		for !strings.HasPrefix(src[p.pos:], `"""`) {
+			if p.pos >= len(src) {
+				p.fail("unterminated string")
+			}
+			if strings.HasPrefix(src[p.pos:], `\"""`) {
+				p.pos += 3
+			}
+			p.pos++
+		}
+		p.pos += 3
+		p.tok = token{tokenString, src[start:p.pos]}
+	case ch == '"':
+		p.pos++
+		for p.pos < len(src) && src[p.pos] != '"' {
+			if src[p.pos] == '\\' {
+				p.pos++
+			} else if src[p.pos] == '\n' {
+				p.fail("unterminated string")
+			}
+			p.pos++
+		}
+		if p.pos >= len(src) {
+			p.fail("unterminated string")
+		}
+		p.pos++
+		p.tok = token{tokenString, src[start:p.pos]}
+	default:
+		p.fail("unexpected character %q", ch)
+	}
+}
+
+func isNameChar(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+var testSchema = graphql.MustParseSchema(Schema, nil, graphql.MaxDepth(MaxDepth))
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]interface{}
+		wantType  string
+		want      int
+	}{
+		{
+			name:     "plain fields",
+			query:    `{ me { uid nickname } }`,
+			wantType: "query",
+			want:     3,
+		},
+		{
+			name:     "default first",
+			query:    `{ records { nodes { rid rname } totalCount } }`,
+			wantType: "query",
+			want:     1 + 20*(1+2+1),
+		},
+		{
+			name:      "first from a variable",
+			query:     `query Page($n: Int) { records(first: $n) { nodes { rid } } }`,
+			variables: map[string]interface{}{"n": float64(5)},
+			wantType:  "query",
+			want:      1 + 5*2,
+		},
+		{
+			name:     "first from a variable default",
+			query:    `query Page($n: Int = 3) { records(first: $n) { nodes { rid } } }`,
+			wantType: "query",
+			want:     1 + 3*2,
+		},
+		{
+			name:     "negative first",
+			query:    `{ records(first: -5) { nodes { rid } } }`,
+			wantType: "query",
+			want:     1,
+		},
+		{
+			name:     "aliases count separately",
+			query:    `{ a: records(first: 2) { totalCount } b: records(first: 3) { totalCount } }`,
+			wantType: "query",
+			want:     (1 + 2) + (1 + 3),
+		},
+		{
+			name: "fragment spread",
+			query: `query { records(first: 2) { ...page } }
+				fragment page on RecordConnection { nodes { rid products { pname } } }`,
+			wantType: "query",
+			want:     1 + 2*(1+(1+1+ListSize)),
+		},
+		{
+			name:     "inline fragments",
+			query:    `{ records(first: 2) { ... on RecordConnection { totalCount } ... { pageInfo { hasNextPage } } } }`,
+			wantType: "query",
+			want:     1 + 2*(1+2),
+		},
+		{
+			name:     "nested lists multiply",
+			query:    `{ marts(first: 10) { nodes { records { rid products { pname } } } } }`,
+			wantType: "query",
+			want:     1 + 10*(1+(1+5*(1+1+ListSize))),
+		},
+		{
+			name:     "introspection",
+			query:    `{ __schema { types { name } } }`,
+			wantType: "query",
+			want:     3,
+		},
+		{
+			name: "named operation",
+			query: `query A { me { uid } }
+				query B { tags { name count } }`,
+			operation: "B",
+			wantType:  "query",
+			want:      1 + ListSize*2,
+		},
+		{
+			name:     "mutation",
+			query:    `mutation { addRecordTags(rid: "r1", tags: ["a", "b"]) { record { rid } } }`,
+			wantType: "mutation",
+			want:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := testSchema.ValidateWithVariables(tt.query, tt.variables); len(errs) > 0 {
+				t.Fatalf("query is invalid: %v", errs)
+			}
+			op, err := Cost(testSchema.ASTSchema(), tt.query, tt.operation, tt.variables)
+			if err != nil {
+				t.Fatalf("Cost() error = %v", err)
+			}
+			if op.Type != tt.wantType || op.Cost != tt.want {
+				t.Errorf("Cost() = %s %d, want %s %d", op.Type, op.Cost, tt.wantType, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostOverBudget(t *testing.T) {
+	query := `{ records(first: 100) { nodes { products { priceHistory { purchases { rid } } } } } }`
+	op, err := Cost(testSchema.ASTSchema(), query, "", nil)
+	if err != nil {
+		t.Fatalf("Cost() error = %v", err)
+	}
+	if op.Cost <= MaxCost {
+		t.Errorf("Cost() = %d, want more than %d", op.Cost, MaxCost)
+	}
+
+	// 변수로 큰 페이지를 요청해도 비용에 반영
+	query = `query Big($n: Int) { records(first: $n) { nodes { rid } } }`
+	op, err = Cost(testSchema.ASTSchema(), query, "", map[string]interface{}{"n": float64(1e12)})
+	if err != nil {
+		t.Fatalf("Cost() error = %v", err)
+	}
+	if op.Cost <= MaxCost {
+		t.Errorf("Cost() = %d with a huge first, want more than %d", op.Cost, MaxCost)
+	}
+}
+
+func TestCostFragmentCycle(t *testing.T) {
+	query := `{ records { ...a } } fragment a on RecordConnection { ...b } fragment b on RecordConnection { ...a }`
+	if _, err := Cost(testSchema.ASTSchema(), query, "", nil); err != nil {
+		t.Fatalf("Cost() error = %v", err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	nested := func(levels int) string {
+		return "{ record(rid: \"r1\") { " + strings.Repeat("duplicateOf { ", levels) + "rid" + strings.Repeat(" }", levels) + " } }"
+	}
+	if errs := testSchema.Validate(nested(MaxDepth - 2)); len(errs) > 0 {
+		t.Errorf("query within the depth limit is invalid: %v", errs)
+	}
+	if errs := testSchema.Validate(nested(MaxDepth + 1)); len(errs) == 0 {
+		t.Errorf("query deeper than %d was accepted", MaxDepth)
+	}
+}
+
+func TestCostErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+	}{
+		{"several operations without a name", `query A { me { uid } } query B { me { uid } }`, ""},
+		{"unknown operation", `query A { me { uid } }`, "C"},
+		{"unterminated selection", `{ me { uid }`, ""},
+		{"unterminated string", `{ record(rid: "r1) { rid } }`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Cost(testSchema.ASTSchema(), tt.query, tt.operation, nil); err == nil {
+				t.Errorf("Cost() succeeded, want an error")
+			}
+		})
+	}
+}
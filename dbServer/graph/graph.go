@@ -0,0 +1,20 @@
+// Package graph holds the GraphQL schema of dbServer with the query cost limit and
+// the batching loaders its resolvers use. The resolvers are in the handlers package
+// so they share the checks of the REST routes.
+package graph
+
+import _ "embed"
+
+//go:embed schema.graphql
+var Schema string
+
+const (
+	// MaxDepth is how deeply selections may be nested
+	MaxDepth = 10
+	// MaxCost is the highest cost a query may have, see Cost
+	MaxCost = 10000
+	// ListSize is the length assumed for lists that have no first argument
+	ListSize = 10
+	// MaxFirst is the largest page a connection returns
+	MaxFirst = 100
+)
@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// Loader batches the lookups of one request. Keys announced with Want are fetched
+// together with the first key that is loaded, so resolving a page of records takes
+// one query per kind of related object instead of one per record. Results, missing
+// keys included, are kept for the rest of the request.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	wanted  []K
+	results map[K]result[V]
+}
+
+type result[V any] struct {
+	value V
+	found bool
+	err   error
+}
+
+// NewLoader returns a loader that looks keys up with fetch. fetch leaves out the
+// keys that do not exist.
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, results: map[K]result[V]{}}
+}
+
+// Want announces keys that are likely to be loaded soon
+func (l *Loader[K, V]) Want(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := l.results[key]; !ok {
+			l.wanted = append(l.wanted, key)
+		}
+	}
+}
+
+// Load returns the value of key and whether it exists
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	// 가져오는 동안 다른 Load는 기다렸다가 같은 결과를 씀
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r, ok := l.results[key]; ok {
+		return r.value, r.found, r.err
+	}
+
+	batch := []K{key}
+	seen := map[K]bool{key: true}
+	for _, k := range l.wanted {
+		if _, done := l.results[k]; !done && !seen[k] {
+			seen[k] = true
+			batch = append(batch, k)
+		}
+	}
+	l.wanted = nil
+
+	values, err := l.fetch(ctx, batch)
+	for _, k := range batch {
+		v, found := values[k]
+		l.results[k] = result[V]{value: v, found: found, err: err}
+	}
+
+	r := l.results[key]
+	return r.value, r.found, r.err
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/household"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// opError is a change that cannot be applied, with the status the REST route answers
+type opError struct {
+	code    int
+	message string
+}
+
+func (e *opError) Error() string {
+	return e.message
+}
+
+// Extensions adds the status to the errors of a GraphQL response
+func (e *opError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"status": e.code}
+}
+
+// writeError answers err, an opError or a failure that was already logged
+func writeError(c *gin.Context, err error) {
+	var opErr *opError
+	if errors.As(err, &opErr) {
+		c.JSON(opErr.code, gin.H{"error": opErr.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+}
+
+// loadAccess reads the user's household memberships, writing the error response if it fails
+func loadAccess(c *gin.Context, ctx context.Context, uid string) (*household.Access, bool) {
+	access, err := household.Load(ctx, uid)
+	if err != nil {
+		log.Printf("Household error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch households"})
+		return nil, false
+	}
+	return access, true
+}
+
+// recordNotWritable answers a write to a record the user could not change: 403 if they
+// can see it as a household viewer, otherwise 404 with message
+func recordNotWritable(c *gin.Context, ctx context.Context, access *household.Access, rid string, message string) {
+	count, err := db.Collection.CountDocuments(ctx, bson.M{"record.rid": rid, "$or": access.Readable()})
+	if err == nil && count > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Viewers cannot change household records"})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": message})
+}
+
+// notWritable is the error of recordNotWritable for routes that answer later
+func notWritable(ctx context.Context, access *household.Access, rid string, message string) error {
+	count, err := db.Collection.CountDocuments(ctx, bson.M{"record.rid": rid, "$or": access.Readable()})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &opError{http.StatusForbidden, "Viewers cannot change household records"}
+	}
+	return &opError{http.StatusNotFound, message}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"dbserver/analytics"
+	jwt "dbserver/auth"
+	"dbserver/dates"
+	"dbserver/household"
+	"dbserver/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// analyticsScope builds the match stages of the current and the previous period.
+// Without from/to the current month in the user's timezone is used.
+func analyticsScope(c *gin.Context, access *household.Access, loc *time.Location) (current bson.M, previous bson.M, period models.Period, previousPeriod models.Period, err error) {
+	base, err := recordFilter(c, access, loc)
+	if err != nil {
+		return nil, nil, period, previousPeriod, err
+	}
+
+	from, to, err := dateRange(c, loc)
+	if err != nil {
+		return nil, nil, period, previousPeriod, err
+	}
+
+	now := time.Now().In(loc)
+	if from.IsZero() {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		if !to.IsZero() && !from.Before(to) {
+			from = to.AddDate(0, -1, 0)
+		}
+	}
+	if to.IsZero() {
+		to = time.Date(from.Year(), from.Month()+1, 1, 0, 0, 0, 0, loc)
+	}
+
+	// 직전 기간은 달 단위면 달력으로, 아니면 같은 일수만큼 앞으로 이동
+	period = models.Period{From: from, To: to}
+	previousFrom, previousTo := dates.PreviousPeriod(from, to, loc)
+	previousPeriod = models.Period{From: previousFrom, To: previousTo}
+
+	current = bson.M{}
+	previous = bson.M{}
+	for k, v := range base {
+		current[k] = v
+		previous[k] = v
+	}
+	current["record.timeStamp.at"] = bson.M{"$gte": period.From, "$lt": period.To}
+	previous["record.timeStamp.at"] = bson.M{"$gte": previousPeriod.From, "$lt": previousPeriod.To}
+
+	return current, previous, period, previousPeriod, nil
+}
+
+func GetSpending(c *gin.Context) {
+	groupBy := c.DefaultQuery("groupBy", analytics.GroupByDay)
+	if !analytics.ValidGroupBy(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "groupBy must be one of day, week, month, mart, category"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	current, previous, period, previousPeriod, err := analyticsScope(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	home := homeCurrency(ctx, account.Uid)
+	buckets, err := analytics.Spending(ctx, current, groupBy, home, loc)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute spending"})
+		return
+	}
+
+	previousBuckets, err := analytics.Spending(ctx, previous, groupBy, home, loc)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute spending"})
+		return
+	}
+
+	total, previousTotal := 0, 0
+	for _, b := range buckets {
+		total += b.Total
+	}
+	for _, b := range previousBuckets {
+		previousTotal += b.Total
+	}
+
+	// 마트와 카테고리는 같은 키끼리 직전 기간과 비교
+	if !analytics.IsTimeGrouping(groupBy) {
+		previousByKey := make(map[string]int, len(previousBuckets))
+		for _, b := range previousBuckets {
+			previousByKey[b.Key] = b.Total
+		}
+		for i := range buckets {
+			prev := previousByKey[buckets[i].Key]
+			buckets[i].PreviousTotal = &prev
+			buckets[i].Change = analytics.Change(float64(buckets[i].Total), float64(prev))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupBy":         groupBy,
+		"currency":        home,
+		"period":          period,
+		"previousPeriod":  previousPeriod,
+		"buckets":         buckets,
+		"previousBuckets": previousBuckets,
+		"total":           total,
+		"previousTotal":   previousTotal,
+		"change":          analytics.Change(float64(total), float64(previousTotal)),
+	})
+}
+
+func GetSpendingSummary(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	current, previous, period, previousPeriod, err := analyticsScope(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	home := homeCurrency(ctx, account.Uid)
+	currentTotals, err := analytics.Totals(ctx, current, home, loc)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute summary"})
+		return
+	}
+
+	previousTotals, err := analytics.Totals(ctx, previous, home, loc)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute summary"})
+		return
+	}
+
+	change := map[string]float64{}
+	for name, values := range map[string][2]float64{
+		"total":     {float64(currentTotals.Total), float64(previousTotals.Total)},
+		"receipts":  {float64(currentTotals.Receipts), float64(previousTotals.Receipts)},
+		"avgBasket": {currentTotals.AvgBasket, previousTotals.AvgBasket},
+	} {
+		if diff := analytics.Change(values[0], values[1]); diff != nil {
+			change[name] = *diff
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"currency": home, "summary": models.SpendingSummary{
+		Period:         period,
+		PreviousPeriod: previousPeriod,
+		Current:        currentTotals,
+		Previous:       previousTotals,
+		Change:         change,
+	}})
+}
+
+func GetTopProducts(c *gin.Context) {
+	by := c.DefaultQuery("by", analytics.TopBySpend)
+	if by != analytics.TopBySpend && by != analytics.TopByFrequency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by must be spend or frequency"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	current, _, period, _, err := analyticsScope(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	home := homeCurrency(ctx, account.Uid)
+	products, err := analytics.TopProducts(ctx, current, by, limit, home, loc)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute top products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"by":       by,
+		"currency": home,
+		"period":   period,
+		"products": products,
+	})
+}
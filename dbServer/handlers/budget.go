@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/budget"
+	"dbserver/db"
+	"dbserver/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// evaluateBudgets re-checks the budgets of the owner of a newly saved record.
+// Failures are only logged so they never undo the save itself.
+func evaluateBudgets(ctx context.Context, record models.RecordInput) []models.BudgetAlert {
+	at := record.Record.TimeStamp.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	alerts, err := budget.Evaluate(ctx, record.Uid, at, homeCurrency(ctx, record.Uid), userLocation(ctx, record.Uid))
+	if err != nil {
+		log.Printf("Budget error: %v\n", err)
+	}
+	return alerts
+}
+
+func validateBudget(req models.BudgetRequest) string {
+	if req.Scope != models.BudgetScopeOverall && req.Target == "" {
+		return "target is required for category and mart budgets"
+	}
+	return ""
+}
+
+func ListBudgets(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	budgets, err := budget.List(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	home := homeCurrency(ctx, account.Uid)
+	now := time.Now()
+	progress := make([]models.BudgetProgress, 0, len(budgets))
+	for _, b := range budgets {
+		p, err := budget.Progress(ctx, b, now, home, loc)
+		if err != nil {
+			log.Printf("Aggregate error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute budget progress"})
+			return
+		}
+		progress = append(progress, p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"budgets": progress})
+}
+
+func CreateBudget(c *gin.Context) {
+	var req models.BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateBudget(req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	newBudget := models.Budget{
+		Bid:        uuid.NewString(),
+		Uid:        account.Uid,
+		Name:       req.Name,
+		Period:     req.Period,
+		Scope:      req.Scope,
+		Amount:     req.Amount,
+		Thresholds: budget.NormalizeThresholds(req.Thresholds),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if req.Scope != models.BudgetScopeOverall {
+		newBudget.Target = req.Target
+	}
+
+	if _, err := db.BudgetCollection.InsertOne(ctx, newBudget); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create budget"})
+		return
+	}
+
+	progress, err := budget.Progress(ctx, newBudget, now, homeCurrency(ctx, account.Uid), userLocation(ctx, account.Uid))
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute budget progress"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Budget created successfully",
+		"budget":  progress,
+	})
+}
+
+func UpdateBudget(c *gin.Context) {
+	bid := c.Param("bid")
+
+	var req models.BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateBudget(req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	target := req.Target
+	if req.Scope == models.BudgetScopeOverall {
+		target = ""
+	}
+
+	var updated models.Budget
+	err = db.BudgetCollection.FindOneAndUpdate(ctx,
+		bson.M{"bid": bid, "uid": account.Uid},
+		bson.M{"$set": bson.M{
+			"name":       req.Name,
+			"period":     req.Period,
+			"scope":      req.Scope,
+			"target":     target,
+			"amount":     req.Amount,
+			"thresholds": budget.NormalizeThresholds(req.Thresholds),
+			"updatedAt":  time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		} else {
+			log.Printf("Update error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update budget"})
+		}
+		return
+	}
+
+	progress, err := budget.Progress(ctx, updated, time.Now(), homeCurrency(ctx, account.Uid), userLocation(ctx, account.Uid))
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute budget progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Budget updated successfully",
+		"budget":  progress,
+	})
+}
+
+func DeleteBudget(c *gin.Context) {
+	bid := c.Param("bid")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.BudgetCollection.DeleteOne(ctx, bson.M{"bid": bid, "uid": account.Uid})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budget"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
+
+func ListAlerts(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"uid": account.Uid}
+	if c.Query("unread") == "true" {
+		filter["read"] = false
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(100)
+	cursor, err := db.AlertCollection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []models.BudgetAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+func MarkAlertRead(c *gin.Context) {
+	aid := c.Param("aid")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.AlertCollection.UpdateOne(ctx,
+		bson.M{"aid": aid, "uid": account.Uid},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert marked as read"})
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/events"
+	"dbserver/household"
+	"dbserver/models"
+	"dbserver/storage"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// bulkOp is an operation checked before any record is touched
+type bulkOp struct {
+	models.BulkOperation
+	tags      []string
+	timeStamp models.PurchaseTime
+}
+
+// BulkRecords applies a batch of operations to records. Each operation runs in a
+// transaction together with its history entry; with atomic set the whole batch
+// runs in one transaction and is rolled back on the first failure. A standalone
+// MongoDB has no transactions: atomic batches are refused with 501 and each operation
+// of a batch is applied on its own, without rolling back its history entry.
+func BulkRecords(c *gin.Context) {
+	var req models.BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+	loc := userLocation(ctx, account.Uid)
+
+	ops := make([]*bulkOp, len(req.Operations))
+	results := make([]models.BulkResult, len(req.Operations))
+	invalid := false
+	var tags []string
+	for i, operation := range req.Operations {
+		results[i] = models.BulkResult{Index: i, Op: operation.Op, Rid: operation.Rid}
+		op, err := prepareBulk(operation, loc)
+		if err != nil {
+			failBulk(&results[i], err)
+			invalid = true
+			continue
+		}
+		ops[i] = op
+		if op.Op == models.BulkOpTag || op.Op == models.BulkOpRetag {
+			tags = append(tags, op.tags...)
+		}
+	}
+
+	if req.Atomic && !db.SupportsTransactions() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Atomic bulk operations need MongoDB to run as a replica set"})
+		return
+	}
+
+	if invalid && req.Atomic {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = models.BulkStatusRolledBack
+				results[i].Code = http.StatusFailedDependency
+			}
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation", "results": results})
+		return
+	}
+
+	// 트랜잭션이 취소돼도 태그 목록에 남는 것은 문제 없으므로 먼저 추가
+	if len(tags) > 0 {
+		if err := addToCatalogue(ctx, account.Uid, uniqueStrings(tags)); err != nil {
+			log.Printf("Tag error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag catalogue"})
+			return
+		}
+	}
+
+	// 사진은 트랜잭션 밖에 있으므로 커밋된 뒤에 지움
+	var purged []*models.ReceiptImage
+	categorized := false
+
+	if req.Atomic {
+		failed := -1
+		err := db.Transaction(ctx, func(sc mongo.SessionContext) error {
+			purged, categorized, failed = nil, false, -1
+			for i, op := range ops {
+				image, err := applyBulk(sc, account.Uid, access, op)
+				if err != nil {
+					failed = i
+					return err
+				}
+				purged = append(purged, image)
+				categorized = categorized || op.Op == models.BulkOpCategory
+			}
+			return nil
+		})
+
+		var opErr *opError
+		if errors.As(err, &opErr) && failed >= 0 {
+			for i := range results {
+				if i == failed {
+					failBulk(&results[i], opErr)
+				} else {
+					results[i].Status = models.BulkStatusRolledBack
+					results[i].Code = http.StatusFailedDependency
+				}
+			}
+			c.JSON(opErr.code, gin.H{"error": "Bulk operation rolled back: " + opErr.message, "results": results})
+			return
+		}
+		if err != nil {
+			log.Printf("Transaction error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bulk operation"})
+			return
+		}
+
+		for i := range results {
+			results[i].Status = models.BulkStatusApplied
+			results[i].Code = http.StatusOK
+		}
+	} else {
+		for i, op := range ops {
+			if op == nil {
+				continue
+			}
+
+			var image *models.ReceiptImage
+			err := db.Transaction(ctx, func(sc mongo.SessionContext) error {
+				var err error
+				image, err = applyBulk(sc, account.Uid, access, op)
+				return err
+			})
+			if err != nil {
+				var opErr *opError
+				if !errors.As(err, &opErr) {
+					log.Printf("Transaction error: %s: %v\n", op.Rid, err)
+				}
+				failBulk(&results[i], err)
+				continue
+			}
+
+			results[i].Status = models.BulkStatusApplied
+			results[i].Code = http.StatusOK
+			purged = append(purged, image)
+			categorized = categorized || op.Op == models.BulkOpCategory
+		}
+	}
+
+	for _, image := range purged {
+		if err := storage.Remove(ctx, image); err != nil {
+			log.Printf("Image error: %v\n", err)
+		}
+	}
+
+	applied := 0
+	for _, result := range results {
+		if result.Status == models.BulkStatusApplied {
+			applied++
+		}
+	}
+
+	response := gin.H{
+		"message": "Bulk operation finished",
+		"applied": applied,
+		"failed":  len(results) - applied,
+		"results": results,
+	}
+	// 카테고리를 직접 고쳤으면 학습한 규칙을 다른 레코드에도 적용
+	if categorized {
+		response["job"] = startRecategorize(account.Uid)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// prepareBulk checks an operation without looking at the records
+func prepareBulk(operation models.BulkOperation, loc *time.Location) (*bulkOp, error) {
+	op := &bulkOp{BulkOperation: operation}
+
+	switch op.Op {
+	case models.BulkOpDelete, models.BulkOpMove:
+	case models.BulkOpTag, models.BulkOpRetag:
+		if op.Op == models.BulkOpTag && len(op.Tags) == 0 {
+			return nil, &opError{http.StatusBadRequest, "tags is required"}
+		}
+		tags, ok := normalizeTags(op.Tags)
+		if !ok {
+			return nil, &opError{http.StatusBadRequest, "Tags must be 1 to 30 characters long"}
+		}
+		op.tags = tags
+	case models.BulkOpCategory:
+		if op.Pname == "" || op.Category == "" {
+			return nil, &opError{http.StatusBadRequest, "pname and category are required"}
+		}
+	case models.BulkOpDate:
+		if op.TimeZone != "" {
+			if !dates.ValidTimeZone(op.TimeZone) {
+				return nil, &opError{http.StatusBadRequest, "Unknown time zone"}
+			}
+			loc = dates.LoadLocation(op.TimeZone)
+		}
+		timeStamp, err := models.ParsePurchaseTime(op.Time, loc)
+		if err != nil {
+			return nil, &opError{http.StatusBadRequest, "Invalid time: " + err.Error()}
+		}
+		op.timeStamp = timeStamp
+	default:
+		return nil, &opError{http.StatusBadRequest, "Unknown operation " + op.Op}
+	}
+	return op, nil
+}
+
+// applyBulk applies one operation inside the transaction of ctx. It returns the
+// photo of a deleted record so it can be removed after the commit.
+func applyBulk(ctx mongo.SessionContext, actor string, access *household.Access, op *bulkOp) (*models.ReceiptImage, error) {
+	var before models.RecordInput
+	err := db.Collection.FindOne(ctx, bson.M{"record.rid": op.Rid, "$or": access.Writable()}).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return nil, notWritable(ctx, access, op.Rid, "Record not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"record.rid": op.Rid}
+	var update bson.M
+	switch op.Op {
+	case models.BulkOpDelete:
+		if _, err := db.Collection.DeleteOne(ctx, filter); err != nil {
+			return nil, err
+		}
+		if _, err := db.HistoryCollection.DeleteMany(ctx, bson.M{"rid": op.Rid}); err != nil {
+			return nil, err
+		}
+		if err := events.Publish(ctx, events.New(models.RecordEventDeleted, actor, before)); err != nil {
+			return nil, err
+		}
+		// 지운 레코드를 가리키던 중복 표시 해제
+		_, err := updateRecords(ctx, actor,
+			bson.M{"$or": access.Writable(), "record.duplicateOf": op.Rid},
+			bson.M{"$unset": bson.M{"record.duplicateOf": ""}},
+		)
+		return before.Image, err
+	case models.BulkOpTag:
+		update = bson.M{"$addToSet": bson.M{"record.tags": bson.M{"$each": op.tags}}}
+	case models.BulkOpRetag:
+		if len(op.tags) == 0 {
+			update = bson.M{"$unset": bson.M{"record.tags": ""}}
+		} else {
+			update = bson.M{"$set": bson.M{"record.tags": op.tags}}
+		}
+	case models.BulkOpCategory:
+		found := false
+		for _, product := range before.Product {
+			found = found || product.Pname == op.Pname
+		}
+		if !found {
+			return nil, &opError{http.StatusNotFound, "Record or product not found"}
+		}
+		filter["product.pname"] = op.Pname
+		update = bson.M{"$set": bson.M{
+			"product.$.category":       op.Category,
+			"product.$.categorySource": models.CategorySourceManual,
+		}}
+		if err := learnCategory(ctx, actor, op.Pname, op.Category); err != nil {
+			return nil, err
+		}
+	case models.BulkOpMove:
+		if op.GroupId == "" {
+			if before.Uid != actor {
+				return nil, &opError{http.StatusForbidden, "Only the member who saved the record can make it personal"}
+			}
+			update = bson.M{"$unset": bson.M{"groupId": ""}}
+		} else {
+			if !household.AtLeast(access.Role(op.GroupId), models.HouseholdRoleEditor) {
+				return nil, &opError{http.StatusForbidden, "Only household editors can add records"}
+			}
+			update = bson.M{"$set": bson.M{"groupId": op.GroupId}}
+		}
+	case models.BulkOpDate:
+		update = bson.M{"$set": bson.M{"record.timeStamp": op.timeStamp}}
+	}
+
+	result, err := db.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, nil
+	}
+	return nil, saveHistory(ctx, actor, &before, op.Rid)
+}
+
+func failBulk(result *models.BulkResult, err error) {
+	result.Status = models.BulkStatusFailed
+	var opErr *opError
+	if errors.As(err, &opErr) {
+		result.Code = opErr.code
+		result.Error = opErr.message
+		return
+	}
+	result.Code = http.StatusInternalServerError
+	result.Error = "Failed to apply operation"
+}
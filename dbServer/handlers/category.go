@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/category"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/normalize"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// startRecategorize runs the background job after a rule change; a failure to start it
+// does not fail the request that changed the rule
+func startRecategorize(uid string) *models.CategoryJob {
+	job, err := category.StartRecategorize(uid)
+	if err != nil {
+		log.Printf("Recategorize error: %v\n", err)
+		return nil
+	}
+	return job
+}
+
+func ListCategories(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	used, err := db.Collection.Distinct(ctx, "product.category", bson.M{"$or": access.Readable(), "record.rid": bson.M{"$exists": true}})
+	if err != nil {
+		log.Printf("Distinct error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	custom := []string{}
+	for _, value := range used {
+		name, ok := value.(string)
+		if !ok || name == "" {
+			continue
+		}
+		if _, builtin := category.Labels[name]; !builtin {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+
+	c.JSON(http.StatusOK, gin.H{
+		"builtin": category.Labels,
+		"custom":  custom,
+	})
+}
+
+func ListCategoryRules(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rules, err := category.Rules(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func CreateCategoryRule(c *gin.Context) {
+	var req models.CategoryRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Type == models.CategoryRuleRegex {
+		if _, err := category.CompileRegex(req.Pattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if normalize.ProductName(req.Pattern) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Keyword must contain letters or digits"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rule := models.CategoryRule{
+		RuleId:    uuid.NewString(),
+		Uid:       account.Uid,
+		Type:      req.Type,
+		Pattern:   req.Pattern,
+		Category:  req.Category,
+		Priority:  req.Priority,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := db.CategoryRuleCollection.InsertOne(ctx, rule); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Rule created successfully",
+		"rule":    rule,
+		"job":     startRecategorize(account.Uid),
+	})
+}
+
+func DeleteCategoryRule(c *gin.Context) {
+	ruleId := c.Param("ruleId")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.CategoryRuleCollection.DeleteOne(ctx, bson.M{"ruleId": ruleId, "uid": account.Uid})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rule deleted successfully",
+		"job":     startRecategorize(account.Uid),
+	})
+}
+
+// learnCategory saves a category the user picked for a product as an exact rule, so
+// the product gets it again on the next receipts
+func learnCategory(ctx context.Context, uid string, pname string, category string) error {
+	_, err := db.CategoryRuleCollection.UpdateOne(ctx,
+		bson.M{"uid": uid, "type": models.CategoryRuleExact, "pattern": normalize.ProductName(pname)},
+		bson.M{
+			"$set": bson.M{"category": category},
+			"$setOnInsert": bson.M{
+				"ruleId":    uuid.NewString(),
+				"learned":   true,
+				"priority":  0,
+				"createdAt": time.Now(),
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func UpdateProductCategory(c *gin.Context) {
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{
+		"record.rid":    req.Rid,
+		"product.pname": req.Pname,
+		"$or":           access.Writable(),
+	}
+
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			recordNotWritable(c, ctx, access, req.Rid, "Record or product not found")
+		} else {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		}
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"product.$.category":       req.Category,
+			"product.$.categorySource": models.CategorySourceManual,
+		},
+	}
+
+	result, err := updateWithHistory(ctx, account.Uid, &existingRecord, filter, update)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record or product not found"})
+		return
+	}
+
+	// 사용자의 수정을 학습해서 같은 상품에 다시 적용
+	if err := learnCategory(ctx, account.Uid, req.Pname, req.Category); err != nil {
+		log.Printf("Learn error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to learn category"})
+		return
+	}
+
+	var updatedRecord models.DBRequest
+	err = db.Collection.FindOne(ctx, bson.M{"record.rid": req.Rid}).Decode(&updatedRecord)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product category updated successfully",
+		"record":  updatedRecord,
+		"job":     startRecategorize(account.Uid),
+	})
+}
+
+func StartRecategorize(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	job, err := category.StartRecategorize(account.Uid)
+	if err != nil {
+		log.Printf("Recategorize error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start recategorization"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func GetCategoryJob(c *gin.Context) {
+	jobId := c.Param("jobId")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := category.GetJob(ctx, account.Uid, jobId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		} else {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 환율표 파일 업로드 최대 크기
+const maxRateFileSize = 2 << 20
+
+func GetCurrencies(c *gin.Context) {
+	currencies := make([]models.Currency, 0)
+	for _, code := range currency.Codes() {
+		currencies = append(currencies, models.Currency{Code: code, Exponent: currency.Exponent(code)})
+	}
+	c.JSON(http.StatusOK, gin.H{"base": currency.Default, "currencies": currencies})
+}
+
+// GetExchangeRates lists the rate table, optionally of one currency and between
+// from and to (YYYY-MM-DD, inclusive)
+func GetExchangeRates(c *gin.Context) {
+	filter := bson.M{}
+	if code := c.Query("currency"); code != "" {
+		filter["currency"] = currency.Normalize(code)
+	}
+
+	dateFilter := bson.M{}
+	for param, op := range map[string]string{"from": "$gte", "to": "$lte"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dates.DateLayout, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be YYYY-MM-DD"})
+			return
+		}
+		dateFilter[op] = value
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := db.RateCollection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "currency", Value: 1}, {Key: "date", Value: -1}}).
+		SetLimit(5000))
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.ExchangeRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"base": currency.Default, "rates": rates})
+}
+
+// ConvertAmount converts ?amount= (minor units) from ?from= to ?to= at the rates of
+// ?date= (today when omitted)
+func ConvertAmount(c *gin.Context) {
+	amount, err := strconv.Atoi(c.Query("amount"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be an integer in minor units"})
+		return
+	}
+	from, to := c.Query("from"), c.Query("to")
+	if !currency.Valid(from) || !currency.Valid(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+	date := c.DefaultQuery("date", time.Now().In(dates.LoadLocation("")).Format(dates.DateLayout))
+	if _, err := time.Parse(dates.DateLayout, date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	converted, err := currency.Convert(ctx, amount, from, to, date)
+	if err != nil {
+		if errors.Is(err, currency.ErrNoRate) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			log.Printf("Convert error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert amount"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"amount":    amount,
+		"from":      currency.Normalize(from),
+		"to":        currency.Normalize(to),
+		"date":      date,
+		"converted": converted,
+		"formatted": currency.Format(converted, to),
+	})
+}
+
+// PutExchangeRates adds or replaces rates of the table (admin only)
+func PutExchangeRates(c *gin.Context) {
+	var req models.ExchangeRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saveRates(c, req.Rates)
+}
+
+// ImportExchangeRates loads a rate table file (multipart field "file"), either CSV
+// lines of "date,currency,rate" or a JSON array (admin only)
+func ImportExchangeRates(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if file.Size > maxRateFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	var rates []models.ExchangeRate
+	if strings.EqualFold(filepath.Ext(file.Filename), ".json") {
+		rates, err = currency.ReadJSON(f)
+	} else {
+		rates, err = currency.ReadCSV(f)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File has no rates"})
+		return
+	}
+
+	saveRates(c, rates)
+}
+
+func saveRates(c *gin.Context, rates []models.ExchangeRate) {
+	for i := range rates {
+		if err := currency.Check(&rates[i]); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	saved, err := currency.Save(ctx, rates, models.RateSourceAdmin)
+	if err != nil {
+		log.Printf("Rate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Exchange rates saved successfully",
+		"rates":   len(rates),
+		"changed": saved,
+	})
+}
+
+// DeleteExchangeRate removes the rate of a currency on a date (admin only)
+func DeleteExchangeRate(c *gin.Context) {
+	code := currency.Normalize(c.Param("currency"))
+	date := c.Param("date")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.RateCollection.DeleteOne(ctx, bson.M{"currency": code, "date": date})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
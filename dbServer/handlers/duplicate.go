@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/events"
+	"dbserver/household"
+	"dbserver/models"
+	"dbserver/storage"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListDuplicates returns the user's records flagged as likely duplicates
+func ListDuplicates(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"$or": access.Readable(), "record.duplicateOf": bson.M{"$exists": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "record.timeStamp.at", Value: -1}})
+	cursor, err := db.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var duplicates []models.RecordInput
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode records"})
+		return
+	}
+
+	rids := make([]string, 0, len(duplicates))
+	for _, d := range duplicates {
+		rids = append(rids, d.Record.DuplicateOf)
+	}
+
+	originals := map[string]*models.RecordInput{}
+	if len(rids) > 0 {
+		cursor, err := db.Collection.Find(ctx, bson.M{"$or": access.Readable(), "record.rid": bson.M{"$in": rids}})
+		if err != nil {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var original models.RecordInput
+			if err := cursor.Decode(&original); err != nil {
+				log.Printf("Decode error: %v\n", err)
+				continue
+			}
+			originals[original.Record.Rid] = &original
+		}
+	}
+
+	pairs := make([]models.DuplicatePair, 0, len(duplicates))
+	for _, d := range duplicates {
+		pairs = append(pairs, models.DuplicatePair{
+			Duplicate: d,
+			Original:  originals[d.Record.DuplicateOf],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": pairs})
+}
+
+// findDuplicate loads a flagged record the user may change, writing the error response
+// if it fails
+func findDuplicate(c *gin.Context, ctx context.Context, access *household.Access) (*models.RecordInput, bool) {
+	var record models.RecordInput
+	filter := bson.M{
+		"$or":                access.Writable(),
+		"record.rid":         c.Param("rid"),
+		"record.duplicateOf": bson.M{"$exists": true},
+	}
+	err := db.Collection.FindOne(ctx, filter).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		recordNotWritable(c, ctx, access, c.Param("rid"), "Record is not flagged as a duplicate")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		return nil, false
+	}
+	return &record, true
+}
+
+// DismissDuplicate clears the duplicate flag of a record the user wants to keep
+func DismissDuplicate(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	record, ok := findDuplicate(c, ctx, access)
+	if !ok {
+		return
+	}
+
+	_, err = updateRecords(ctx, account.Uid,
+		bson.M{"record.rid": record.Record.Rid},
+		bson.M{"$unset": bson.M{"record.duplicateOf": ""}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss duplicate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Duplicate dismissed"})
+}
+
+// MergeDuplicate folds a flagged record into the record it duplicates and deletes it.
+// Tags are combined and the note and store details fill in whatever the original is
+// missing; the original's products and totals are kept.
+func MergeDuplicate(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	duplicate, ok := findDuplicate(c, ctx, access)
+	if !ok {
+		return
+	}
+
+	originalFilter := bson.M{"$or": access.Writable(), "record.rid": duplicate.Record.DuplicateOf}
+	var original models.RecordInput
+	err = db.Collection.FindOne(ctx, originalFilter).Decode(&original)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Original record not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		return
+	}
+
+	set := bson.M{}
+	if original.Record.Note == "" && duplicate.Record.Note != "" {
+		set["record.note"] = duplicate.Record.Note
+	}
+	// 원본에 사진이 없으면 중복 레코드의 사진을 넘겨받음
+	orphan := duplicate.Image
+	if original.Image == nil && duplicate.Image != nil {
+		set["image"] = duplicate.Image
+		orphan = nil
+	}
+	fill := map[string][2]string{
+		"mart.martName":    {original.Mart.MartName, duplicate.Mart.MartName},
+		"mart.martAddress": {original.Mart.MartAddress, duplicate.Mart.MartAddress},
+		"mart.tel":         {original.Mart.Tel, duplicate.Mart.Tel},
+		"mart.bizNum":      {original.Mart.BizNum, duplicate.Mart.BizNum},
+	}
+	for field, values := range fill {
+		if values[0] == "" && values[1] != "" {
+			set[field] = values[1]
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(duplicate.Record.Tags) > 0 {
+		update["$addToSet"] = bson.M{"record.tags": bson.M{"$each": duplicate.Record.Tags}}
+	}
+	err = db.Transaction(ctx, func(sc mongo.SessionContext) error {
+		if len(update) > 0 {
+			if _, err := updateRecords(sc, account.Uid, originalFilter, update); err != nil {
+				return err
+			}
+		}
+
+		// 삭제될 레코드를 가리키던 다른 중복 표시는 원본을 가리키도록 변경
+		_, err := updateRecords(sc, account.Uid,
+			bson.M{"$or": access.Writable(), "record.duplicateOf": duplicate.Record.Rid},
+			bson.M{"$set": bson.M{"record.duplicateOf": original.Record.Rid}},
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := db.Collection.DeleteOne(sc, bson.M{"$or": access.Writable(), "record.rid": duplicate.Record.Rid}); err != nil {
+			return err
+		}
+		if _, err := db.HistoryCollection.DeleteMany(sc, bson.M{"rid": duplicate.Record.Rid}); err != nil {
+			return err
+		}
+		return events.Publish(sc, events.New(models.RecordEventDeleted, account.Uid, *duplicate))
+	})
+	if err != nil {
+		log.Printf("Transaction error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge records"})
+		return
+	}
+
+	// 사진은 커밋된 뒤에 정리
+	if err := storage.Remove(ctx, orphan); err != nil {
+		log.Printf("Image error: %v\n", err)
+	}
+
+	var merged models.RecordInput
+	if err := db.Collection.FindOne(ctx, originalFilter).Decode(&merged); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Records merged successfully",
+		"record":  merged,
+		"deleted": duplicate.Record.Rid,
+	})
+}
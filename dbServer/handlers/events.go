@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/events"
+	"dbserver/household"
+	"dbserver/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventBacklogLimit is how many missed events a reconnecting client is sent before
+	// it is told to reload instead
+	eventBacklogLimit = 500
+	// eventHeartbeat keeps proxies from closing an idle stream
+	eventHeartbeat = 25 * time.Second
+	// eventRetry is the reconnect delay suggested to EventSource, in milliseconds
+	eventRetry = 3000
+)
+
+// StreamRecordEvents streams the creations, changes and deletions of the records the
+// user can read as server-sent events. A client that reconnects with Last-Event-ID
+// (or ?lastEventId=) gets the events it missed first; if they are no longer all kept
+// it gets a reset event and should reload its records.
+func StreamRecordEvents(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("lastEventId")
+	}
+	var after int64
+	resume := lastEventId != ""
+	if resume {
+		after, err = strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || after < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	// 놓친 이벤트를 읽기 전에 구독해야 그 사이의 이벤트가 빠지지 않음
+	ch := events.Subscribe()
+	defer events.Unsubscribe(ch)
+
+	var backlog []models.RecordEvent
+	var resetId int64 = -1
+	if resume {
+		// 늦게 저장된 이벤트를 위해 마지막 id 직전의 이벤트도 다시 보낼 수 있음
+		missed, complete, err := events.Since(ctx, after, eventBacklogLimit+1)
+		if err != nil {
+			log.Printf("Event error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+			return
+		}
+		if complete && len(missed) <= eventBacklogLimit {
+			backlog = missed
+		} else {
+			resetId, err = events.Latest(ctx)
+			if err != nil {
+				log.Printf("Event error: %v\n", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+				return
+			}
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventRetry)
+	if resetId >= 0 {
+		c.Render(-1, sse.Event{Id: strconv.FormatInt(resetId, 10), Event: "reset", Data: gin.H{"reason": "Missed events are no longer available"}})
+	}
+
+	// 백로그와 구독으로 같은 이벤트가 두 번 올 수 있어 보낸 번호를 기억함
+	sent := map[int64]time.Time{}
+	send := func(event models.RecordEvent) {
+		if _, ok := sent[event.Seq]; ok {
+			return
+		}
+		sent[event.Seq] = time.Now()
+		if event.Seq <= resetId {
+			return
+		}
+		event, visible := visibleEvent(access, event)
+		if !visible {
+			return
+		}
+		c.Render(-1, sse.Event{Id: strconv.FormatInt(event.Seq, 10), Event: "record." + event.Type, Data: event})
+	}
+
+	for _, event := range backlog {
+		send(event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				// 너무 밀려서 끊긴 경우, 클라이언트가 마지막 id로 다시 연결함
+				return
+			}
+			send(event)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			// 가구 멤버십이 바뀌었을 수 있어 권한을 다시 읽음
+			if reloaded, err := reloadAccess(account.Uid); err == nil {
+				access = reloaded
+			} else {
+				log.Printf("Household error: %v\n", err)
+			}
+			for seq, at := range sent {
+				if time.Since(at) > 2*time.Minute {
+					delete(sent, seq)
+				}
+			}
+			c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// visibleEvent returns event as the user should see it: members of the household a
+// record was moved out of see it deleted
+func visibleEvent(access *household.Access, event models.RecordEvent) (models.RecordEvent, bool) {
+	if access.CanRead(event.Uid, event.GroupId) {
+		return event, true
+	}
+	if event.PrevGroupId != "" && access.CanRead(event.Uid, event.PrevGroupId) {
+		event.Type = models.RecordEventDeleted
+		event.GroupId = event.PrevGroupId
+		return event, true
+	}
+	return event, false
+}
+
+func reloadAccess(uid string) (*household.Access, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return household.Load(ctx, uid)
+}
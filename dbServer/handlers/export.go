@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	jwt "dbserver/auth"
+	"dbserver/currency"
+	"dbserver/db"
+	"dbserver/export"
+	"dbserver/models"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportFlushRows is how many rows are buffered before they are sent to the client
+const exportFlushRows = 500
+
+var recordExportHeader = []interface{}{
+	"영수증ID", "구매일", "구매시각", "영수증명", "매장명", "매장주소", "전화번호", "품목수", "통화", "합계", "할인", "부가세", "결제수단", "태그", "메모",
+}
+
+var itemExportHeader = []interface{}{
+	"영수증ID", "구매일", "구매시각", "영수증명", "매장명", "품목명", "분류", "단가", "수량", "할인", "금액", "영수증합계", "통화",
+}
+
+// ExportRecords streams the user's records as CSV or XLSX. It accepts the same
+// filters as the record listing plus:
+//
+//	format  csv (default) or xlsx
+//	layout  record (default): one row per record, item: one row per line item
+func ExportRecords(c *gin.Context) {
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+	layout := c.DefaultQuery("layout", export.LayoutRecord)
+	if layout != export.LayoutRecord && layout != export.LayoutItem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "layout must be record or item"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	// 대용량 내보내기는 오래 걸릴 수 있으므로 기한 없이 클라이언트 연결이 끊길 때까지 진행
+	ctx := c.Request.Context()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	filter, err := recordFilter(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "record.timeStamp.at", Value: -1}}).
+		SetBatchSize(exportFlushRows)
+	cursor, err := db.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	filename := fmt.Sprintf("records-%s.%s", time.Now().In(loc).Format("20060102"), format)
+	if layout == export.LayoutItem {
+		filename = strings.Replace(filename, "records-", "items-", 1)
+	}
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s", filename, url.PathEscape(filename)))
+	c.Status(http.StatusOK)
+
+	// 헤더를 보낸 뒤에는 상태 코드를 바꿀 수 없으므로 이후 오류는 로그만 남김
+	w, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		log.Printf("Export error: %v\n", err)
+		return
+	}
+
+	header := recordExportHeader
+	if layout == export.LayoutItem {
+		header = itemExportHeader
+	}
+	if err := w.WriteRow(header); err != nil {
+		log.Printf("Export error: %v\n", err)
+		return
+	}
+
+	rows := 0
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			log.Printf("Decode error: %v\n", err)
+			continue
+		}
+
+		var lines [][]interface{}
+		if layout == export.LayoutItem {
+			lines = itemRows(record, loc)
+		} else {
+			lines = [][]interface{}{recordRow(record, loc)}
+		}
+		for _, line := range lines {
+			if err := w.WriteRow(line); err != nil {
+				log.Printf("Export error: %v\n", err)
+				return
+			}
+			rows++
+			if rows%exportFlushRows == 0 {
+				if err := w.Flush(); err != nil {
+					log.Printf("Export error: %v\n", err)
+					return
+				}
+				c.Writer.Flush()
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		abortStream(c)
+		return
+	}
+
+	if err := w.Close(); err != nil {
+		log.Printf("Export error: %v\n", err)
+	}
+}
+
+// abortStream closes the connection without ending the response, so a client sees
+// a truncated body instead of a complete export that silently misses records
+func abortStream(c *gin.Context) {
+	if conn, _, err := c.Writer.Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+// purchaseDateTime formats a purchase time as date and time columns; time is empty
+// when the receipt only had a date
+func purchaseDateTime(t models.PurchaseTime, loc *time.Location) (string, string) {
+	if t.At.IsZero() {
+		return "", ""
+	}
+	local := t.At.In(loc)
+	if !t.HasTime {
+		return local.Format("2006-01-02"), ""
+	}
+	return local.Format("2006-01-02"), local.Format("15:04:05")
+}
+
+func recordRow(record models.RecordInput, loc *time.Location) []interface{} {
+	date, clock := purchaseDateTime(record.Record.TimeStamp, loc)
+	vat := 0
+	if record.Tax != nil {
+		vat = record.Tax.Vat
+	}
+	payment := paymentLabel(record.Payment)
+	return []interface{}{
+		record.Record.Rid,
+		date,
+		clock,
+		record.Record.Rname,
+		record.Mart.MartName,
+		record.Mart.MartAddress,
+		record.Mart.Tel,
+		len(record.Product),
+		currency.Normalize(record.Currency),
+		exportAmount(record.TotalPrice, record.Currency),
+		exportAmount(record.Discount, record.Currency),
+		exportAmount(vat, record.Currency),
+		payment,
+		strings.Join(record.Record.Tags, ", "),
+		record.Record.Note,
+	}
+}
+
+// exportAmount writes an amount in minor units in the major units of code, so
+// 1250 USD is exported as 12.5
+func exportAmount(amount int, code string) interface{} {
+	exponent := currency.Exponent(code)
+	if exponent == 0 {
+		return amount
+	}
+	return float64(amount) / math.Pow10(exponent)
+}
+
+// paymentLabel is the payment column of the record export, e.g. "카드 (신한 1234-****-****-5678)"
+func paymentLabel(payment *models.PaymentInfo) string {
+	if payment == nil {
+		return ""
+	}
+	switch payment.Method {
+	case models.PaymentMethodCash:
+		return "현금"
+	case models.PaymentMethodCard:
+		detail := strings.TrimSpace(payment.CardCompany + " " + payment.CardNumber)
+		if detail == "" {
+			return "카드"
+		}
+		return "카드 (" + detail + ")"
+	}
+	return payment.Method
+}
+
+func itemRows(record models.RecordInput, loc *time.Location) [][]interface{} {
+	date, clock := purchaseDateTime(record.Record.TimeStamp, loc)
+	rows := make([][]interface{}, 0, len(record.Product))
+	for _, product := range record.Product {
+		rows = append(rows, []interface{}{
+			record.Record.Rid,
+			date,
+			clock,
+			record.Record.Rname,
+			record.Mart.MartName,
+			product.Pname,
+			product.Category,
+			exportAmount(product.Price, record.Currency),
+			product.Amount,
+			exportAmount(product.Discount, record.Currency),
+			exportAmount(product.LineTotal(), record.Currency),
+			exportAmount(record.TotalPrice, record.Currency),
+			currency.Normalize(record.Currency),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"dbserver/dates"
+	"dbserver/household"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// recordFilter builds the record query shared by the listing endpoints from the
+// query string. Supported parameters:
+//
+//	from, to  purchase date range, inclusive, read in the user's timezone
+//	tag       only records carrying the tag; repeat to require several tags
+//	group     "personal" or a household id; personal and shared records by default
+func recordFilter(c *gin.Context, access *household.Access, loc *time.Location) (bson.M, error) {
+	return filterRecords(access, loc, recordQuery{
+		Group: c.Query("group"),
+		From:  c.Query("from"),
+		To:    c.Query("to"),
+		Tags:  c.QueryArray("tag"),
+	})
+}
+
+// recordQuery holds the parameters of recordFilter
+type recordQuery struct {
+	Group string
+	From  string
+	To    string
+	Tags  []string
+}
+
+// filterRecords builds the filter of recordFilter from parameters read elsewhere,
+// e.g. the arguments of a GraphQL query
+func filterRecords(access *household.Access, loc *time.Location, q recordQuery) (bson.M, error) {
+	scope, err := access.Scope(q.Group)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"$or": scope, "record.rid": bson.M{"$exists": true}}
+
+	from, to, err := parseDateRange(q.From, q.To, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	timeRange := bson.M{}
+	if !from.IsZero() {
+		timeRange["$gte"] = from
+	}
+	if !to.IsZero() {
+		timeRange["$lt"] = to
+	}
+	if len(timeRange) > 0 {
+		filter["record.timeStamp.at"] = timeRange
+	}
+
+	if len(q.Tags) > 0 {
+		tags, ok := normalizeTags(q.Tags)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag")
+		}
+		filter["record.tags"] = bson.M{"$all": tags}
+	}
+
+	return filter, nil
+}
+
+// dateRange reads the from/to query parameters. to is returned as the start of the
+// following day so it can be used as an exclusive upper bound.
+func dateRange(c *gin.Context, loc *time.Location) (from time.Time, to time.Time, err error) {
+	return parseDateRange(c.Query("from"), c.Query("to"), loc)
+}
+
+func parseDateRange(fromValue string, toValue string, loc *time.Location) (from time.Time, to time.Time, err error) {
+	if value := fromValue; value != "" {
+		t, _, err := dates.Parse(value, loc)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from: %v", err)
+		}
+		from = dates.StartOfDay(t, loc)
+	}
+
+	if value := toValue; value != "" {
+		t, _, err := dates.Parse(value, loc)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to: %v", err)
+		}
+		to = dates.StartOfDay(t, loc).AddDate(0, 0, 1)
+	}
+
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/graph"
+	"dbserver/household"
+	"dbserver/models"
+	"dbserver/normalize"
+	"dbserver/patch"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var graphSchema = graphql.MustParseSchema(graph.Schema, &graphResolver{},
+	graphql.MaxDepth(graph.MaxDepth),
+)
+
+// GraphQL runs a query against graph.Schema. Queries may also be sent with GET;
+// mutations only with POST. Queries costing more than graph.MaxCost are refused
+// before they run.
+func GraphQL(c *gin.Context) {
+	var req models.GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if value := c.Query("variables"); value != "" {
+			if err := json.Unmarshal([]byte(value), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variables: " + err.Error()})
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	if errs := graphSchema.ValidateWithVariables(req.Query, req.Variables); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": errs})
+		return
+	}
+
+	op, err := graph.Cost(graphSchema.ASTSchema(), req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gin.H{{"message": err.Error()}}})
+		return
+	}
+	if op.Type != "query" && c.Request.Method == http.MethodGet {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"errors": []gin.H{{"message": "Only queries can be sent with GET"}}})
+		return
+	}
+	if op.Cost > graph.MaxCost {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gin.H{{
+			"message":    fmt.Sprintf("Query is too complex: cost %d exceeds %d", op.Cost, graph.MaxCost),
+			"extensions": gin.H{"cost": op.Cost, "maxCost": graph.MaxCost},
+		}}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	state := newGraphRequest(ctx, account, access)
+	response := graphSchema.Exec(context.WithValue(ctx, graphKey{}, state), req.Query, req.OperationName, req.Variables)
+	response.Extensions = map[string]interface{}{"cost": op.Cost}
+	c.JSON(http.StatusOK, response)
+}
+
+type graphKey struct{}
+
+// graphRequest is what the resolvers of one request share
+type graphRequest struct {
+	account *models.User
+	access  *household.Access
+	loc     *time.Location
+	home    string
+
+	users   *graph.Loader[string, models.User]
+	records *graph.Loader[string, models.RecordInput]
+	marts   *graph.Loader[string, models.Mart]
+	stats   *graph.Loader[string, models.MartStats]
+	latest  *graph.Loader[martRecordsKey, []models.RecordInput]
+	prices  *graph.Loader[string, *models.PriceHistory]
+}
+
+func graphState(ctx context.Context) *graphRequest {
+	return ctx.Value(graphKey{}).(*graphRequest)
+}
+
+type graphResolver struct{}
+
+func (graphResolver) Me(ctx context.Context) (*viewerResolver, error) {
+	r := graphState(ctx)
+	var user models.User
+	if err := db.Collection.FindOne(ctx, userFilter(r.account.Uid)).Decode(&user); err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Find error: %v\n", err)
+			return nil, &opError{http.StatusInternalServerError, "Failed to fetch user"}
+		}
+		user = *r.account
+	}
+	return &viewerResolver{user: user, home: r.home}, nil
+}
+
+type recordFilterInput struct {
+	Group      *string
+	From       *string
+	To         *string
+	Tags       *[]string
+	MartId     *graphql.ID
+	OpenIssues *bool
+}
+
+func (graphResolver) Records(ctx context.Context, args struct {
+	First  int32
+	After  *string
+	Filter *recordFilterInput
+}) (*recordConnection, error) {
+	r := graphState(ctx)
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+
+	var q recordQuery
+	f := args.Filter
+	if f == nil {
+		f = &recordFilterInput{}
+	}
+	if f.Group != nil {
+		q.Group = *f.Group
+	}
+	if f.From != nil {
+		q.From = *f.From
+	}
+	if f.To != nil {
+		q.To = *f.To
+	}
+	if f.Tags != nil {
+		q.Tags = *f.Tags
+	}
+	filter, err := filterRecords(r.access, r.loc, q)
+	if err != nil {
+		return nil, &opError{http.StatusBadRequest, err.Error()}
+	}
+	if f.MartId != nil {
+		filter["mart.martId"] = string(*f.MartId)
+	}
+	if f.OpenIssues != nil {
+		open := bson.M{"$elemMatch": bson.M{"resolved": false}}
+		if *f.OpenIssues {
+			filter["issues"] = open
+		} else {
+			filter["issues"] = bson.M{"$not": open}
+		}
+	}
+
+	page := bson.M{"$and": bson.A{filter}}
+	if args.After != nil {
+		at, rid, err := decodeRecordCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		page["$and"] = bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"record.timeStamp.at": bson.M{"$lt": at}},
+			bson.M{"record.timeStamp.at": at, "record.rid": bson.M{"$lt": rid}},
+		}}}
+	}
+
+	cursor, err := db.Collection.Find(ctx, page, options.Find().
+		SetSort(bson.D{{Key: "record.timeStamp.at", Value: -1}, {Key: "record.rid", Value: -1}}).
+		SetLimit(int64(args.First)+1),
+	)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch records"}
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.RecordInput
+	if err := cursor.All(ctx, &records); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to decode records"}
+	}
+
+	connection := &recordConnection{filter: filter}
+	if len(records) > int(args.First) {
+		records = records[:args.First]
+		connection.pageInfo.hasNextPage = true
+	}
+	if len(records) > 0 {
+		end := encodeRecordCursor(records[len(records)-1])
+		connection.pageInfo.endCursor = &end
+	}
+	connection.nodes = r.recordNodes(records)
+	return connection, nil
+}
+
+func (graphResolver) Record(ctx context.Context, args struct{ Rid graphql.ID }) (*recordResolver, error) {
+	r := graphState(ctx)
+	var record models.RecordInput
+	err := db.Collection.FindOne(ctx, bson.M{"record.rid": string(args.Rid), "$or": r.access.Readable()}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch record"}
+	}
+	return r.recordNodes([]models.RecordInput{record})[0], nil
+}
+
+func (graphResolver) Marts(ctx context.Context, args struct {
+	Q     *string
+	First int32
+	After *string
+}) (*martConnection, error) {
+	r := graphState(ctx)
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"uid": r.account.Uid}
+	if args.Q != nil {
+		if q := normalize.ProductName(*args.Q); q != "" {
+			filter["normNames"] = bson.M{"$regex": regexp.QuoteMeta(q)}
+		}
+	}
+	if args.After != nil {
+		name, martId, err := decodeMartCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		filter["$or"] = bson.A{
+			bson.M{"name": bson.M{"$gt": name}},
+			bson.M{"name": name, "martId": bson.M{"$gt": martId}},
+		}
+	}
+
+	cursor, err := db.MartCollection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "martId", Value: 1}}).
+		SetLimit(int64(args.First)+1),
+	)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch marts"}
+	}
+	defer cursor.Close(ctx)
+
+	var directory []models.Mart
+	if err := cursor.All(ctx, &directory); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch marts"}
+	}
+
+	connection := &martConnection{}
+	if len(directory) > int(args.First) {
+		directory = directory[:args.First]
+		connection.pageInfo.hasNextPage = true
+	}
+	if len(directory) > 0 {
+		last := directory[len(directory)-1]
+		end := encodeCursor(last.Name, last.MartId)
+		connection.pageInfo.endCursor = &end
+	}
+	connection.nodes = r.martNodes(directory)
+	return connection, nil
+}
+
+func (graphResolver) Mart(ctx context.Context, args struct{ MartId graphql.ID }) (*martResolver, error) {
+	r := graphState(ctx)
+	mart, found, err := r.marts.Load(ctx, string(args.MartId))
+	if err != nil || !found {
+		return nil, err
+	}
+	return r.martNodes([]models.Mart{mart})[0], nil
+}
+
+func (graphResolver) Product(ctx context.Context, args struct{ Name string }) (*priceHistoryResolver, error) {
+	r := graphState(ctx)
+	normName := normalize.ProductName(args.Name)
+	if normName == "" {
+		return nil, &opError{http.StatusBadRequest, "Invalid product name"}
+	}
+	return r.priceHistory(ctx, normName)
+}
+
+func (graphResolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	r := graphState(ctx)
+	tags, err := tagCounts(ctx, r.account.Uid)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*tagResolver, 0, len(tags))
+	for _, tag := range tags {
+		resolvers = append(resolvers, &tagResolver{tag})
+	}
+	return resolvers, nil
+}
+
+type recordChangesInput struct {
+	Rname      *string
+	Time       *string
+	TimeZone   *string
+	Note       *string
+	Tags       *[]string
+	Currency   *string
+	Discount   *int32
+	TotalPrice *int32
+}
+
+// UpdateRecord turns the changes into a merge patch for patchRecord
+func (graphResolver) UpdateRecord(ctx context.Context, args struct {
+	Rid     graphql.ID
+	Changes recordChangesInput
+}) (*recordPayload, error) {
+	r := graphState(ctx)
+
+	changes := map[string]interface{}{}
+	set := func(key string, value interface{}, ok bool) {
+		if ok {
+			changes[key] = value
+		}
+	}
+	ch := args.Changes
+	set("rname", ch.Rname, ch.Rname != nil)
+	set("time", ch.Time, ch.Time != nil)
+	set("timeZone", ch.TimeZone, ch.TimeZone != nil)
+	set("note", ch.Note, ch.Note != nil)
+	set("tags", ch.Tags, ch.Tags != nil)
+	set("currency", ch.Currency, ch.Currency != nil)
+	set("discount", ch.Discount, ch.Discount != nil)
+	set("totalPrice", ch.TotalPrice, ch.TotalPrice != nil)
+
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	result, err := patchRecord(ctx, r.account.Uid, r.access, string(args.Rid), patch.Merge, body)
+	if err != nil {
+		return nil, err
+	}
+	return &recordPayload{record: r.recordNodes([]models.RecordInput{result.Record})[0], job: result.Job}, nil
+}
+
+func (graphResolver) AddRecordTags(ctx context.Context, args struct {
+	Rid  graphql.ID
+	Tags []string
+}) (*recordPayload, error) {
+	return graphState(ctx).runBulk(ctx, models.BulkOperation{Op: models.BulkOpTag, Rid: string(args.Rid), Tags: args.Tags})
+}
+
+func (graphResolver) SetProductCategory(ctx context.Context, args struct {
+	Rid      graphql.ID
+	Pname    string
+	Category string
+}) (*recordPayload, error) {
+	return graphState(ctx).runBulk(ctx, models.BulkOperation{
+		Op:       models.BulkOpCategory,
+		Rid:      string(args.Rid),
+		Pname:    args.Pname,
+		Category: args.Category,
+	})
+}
+
+func (graphResolver) MoveRecord(ctx context.Context, args struct {
+	Rid     graphql.ID
+	GroupId *graphql.ID
+}) (*recordPayload, error) {
+	operation := models.BulkOperation{Op: models.BulkOpMove, Rid: string(args.Rid)}
+	if args.GroupId != nil {
+		operation.GroupId = string(*args.GroupId)
+	}
+	return graphState(ctx).runBulk(ctx, operation)
+}
+
+type martChangesInput struct {
+	Name    *string
+	Address *string
+	Tel     *string
+	BizNum  *string
+}
+
+func (graphResolver) EditMart(ctx context.Context, args struct {
+	MartId  graphql.ID
+	Changes martChangesInput
+}) (*martPayload, error) {
+	r := graphState(ctx)
+
+	req := models.EditMartRequest{
+		Name:    args.Changes.Name,
+		Address: args.Changes.Address,
+		Tel:     args.Changes.Tel,
+		BizNum:  args.Changes.BizNum,
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, &opError{http.StatusBadRequest, err.Error()}
+	}
+
+	var mart models.Mart
+	err := db.MartCollection.FindOne(ctx, bson.M{"martId": string(args.MartId), "uid": r.account.Uid}).Decode(&mart)
+	if err == mongo.ErrNoDocuments {
+		return nil, &opError{http.StatusNotFound, "Mart not found"}
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch mart"}
+	}
+
+	updated, err := editMart(ctx, r.account.Uid, &mart, req)
+	if err != nil {
+		return nil, err
+	}
+	return &martPayload{mart: r.martNodes([]models.Mart{mart})[0], records: updated}, nil
+}
+
+// runBulk applies one operation of POST /records/bulk and returns the changed record
+func (r *graphRequest) runBulk(ctx context.Context, operation models.BulkOperation) (*recordPayload, error) {
+	op, err := prepareBulk(operation, r.loc)
+	if err != nil {
+		return nil, err
+	}
+	if len(op.tags) > 0 {
+		if err := addToCatalogue(ctx, r.account.Uid, op.tags); err != nil {
+			log.Printf("Tag error: %v\n", err)
+			return nil, &opError{http.StatusInternalServerError, "Failed to update tag catalogue"}
+		}
+	}
+
+	err = db.Transaction(ctx, func(sc mongo.SessionContext) error {
+		_, err := applyBulk(sc, r.account.Uid, r.access, op)
+		return err
+	})
+	if err != nil {
+		var opErr *opError
+		if errors.As(err, &opErr) {
+			return nil, opErr
+		}
+		log.Printf("Transaction error: %s: %v\n", op.Rid, err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to apply operation"}
+	}
+
+	var record models.RecordInput
+	if err := db.Collection.FindOne(ctx, bson.M{"record.rid": op.Rid}).Decode(&record); err != nil {
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch updated record"}
+	}
+	payload := &recordPayload{record: r.recordNodes([]models.RecordInput{record})[0]}
+	if op.Op == models.BulkOpCategory {
+		payload.job = startRecategorize(r.account.Uid)
+	}
+	return payload, nil
+}
+
+func checkFirst(first int32) error {
+	if first < 0 || first > graph.MaxFirst {
+		return &opError{http.StatusBadRequest, fmt.Sprintf("first must be between 0 and %d", graph.MaxFirst)}
+	}
+	return nil
+}
+
+// 커서는 마지막 항목의 정렬 키를 이어 붙여 인코딩한 값
+func encodeCursor(parts ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, "\x00")))
+}
+
+func decodeCursor(cursor string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, &opError{http.StatusBadRequest, "Invalid cursor"}
+	}
+	parts := strings.Split(string(raw), "\x00")
+	if len(parts) != n {
+		return nil, &opError{http.StatusBadRequest, "Invalid cursor"}
+	}
+	return parts, nil
+}
+
+func encodeRecordCursor(record models.RecordInput) string {
+	return encodeCursor(record.Record.TimeStamp.At.UTC().Format(time.RFC3339Nano), record.Record.Rid)
+}
+
+func decodeRecordCursor(cursor string) (time.Time, string, error) {
+	parts, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	at, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", &opError{http.StatusBadRequest, "Invalid cursor"}
+	}
+	return at, parts[1], nil
+}
+
+func decodeMartCursor(cursor string) (string, string, error) {
+	parts, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return "", "", err
+	}
+	return parts[0], parts[1], nil
+}
+
+// graphTime formats t for GraphQL in the zone of loc
+func graphTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.RFC3339)
+}
@@ -0,0 +1,563 @@
+package handlers
+
+import (
+	"context"
+	"dbserver/analytics"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/graph"
+	"dbserver/household"
+	"dbserver/marts"
+	"dbserver/models"
+	"dbserver/normalize"
+	"log"
+	"net/http"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// martRecordsKey asks for the latest records of a mart
+type martRecordsKey struct {
+	martId string
+	first  int
+}
+
+// newGraphRequest sets up the loaders of one GraphQL request. Each loader reads
+// everything its resolvers announced in one query.
+func newGraphRequest(ctx context.Context, account *models.User, access *household.Access) *graphRequest {
+	r := &graphRequest{
+		account: account,
+		access:  access,
+		loc:     userLocation(ctx, account.Uid),
+		home:    homeCurrency(ctx, account.Uid),
+	}
+
+	r.users = graph.NewLoader(func(ctx context.Context, uids []string) (map[string]models.User, error) {
+		var users []models.User
+		if err := findAll(ctx, db.Collection, bson.M{"uid": bson.M{"$in": uids}, "record": bson.M{"$exists": false}}, &users); err != nil {
+			return nil, err
+		}
+		byUid := make(map[string]models.User, len(users))
+		for _, user := range users {
+			byUid[user.Uid] = user
+		}
+		return byUid, nil
+	})
+
+	r.records = graph.NewLoader(func(ctx context.Context, rids []string) (map[string]models.RecordInput, error) {
+		var records []models.RecordInput
+		if err := findAll(ctx, db.Collection, bson.M{"record.rid": bson.M{"$in": rids}, "$or": access.Readable()}, &records); err != nil {
+			return nil, err
+		}
+		byRid := make(map[string]models.RecordInput, len(records))
+		for _, record := range records {
+			byRid[record.Record.Rid] = record
+		}
+		return byRid, nil
+	})
+
+	// 매장 목록은 사용자마다 따로라서 자기 매장만 보여 줌
+	r.marts = graph.NewLoader(func(ctx context.Context, ids []string) (map[string]models.Mart, error) {
+		var directory []models.Mart
+		if err := findAll(ctx, db.MartCollection, bson.M{"uid": account.Uid, "martId": bson.M{"$in": ids}}, &directory); err != nil {
+			return nil, err
+		}
+		byId := make(map[string]models.Mart, len(directory))
+		for _, mart := range directory {
+			byId[mart.MartId] = mart
+		}
+		return byId, nil
+	})
+
+	r.stats = graph.NewLoader(func(ctx context.Context, ids []string) (map[string]models.MartStats, error) {
+		return marts.Stats(ctx, account.Uid, ids, r.home, r.loc)
+	})
+
+	r.latest = graph.NewLoader(func(ctx context.Context, keys []martRecordsKey) (map[martRecordsKey][]models.RecordInput, error) {
+		ids := map[int][]string{}
+		for _, key := range keys {
+			ids[key.first] = append(ids[key.first], key.martId)
+		}
+
+		latest := map[martRecordsKey][]models.RecordInput{}
+		for first, martIds := range ids {
+			cursor, err := db.Collection.Aggregate(ctx, mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"uid": account.Uid, "mart.martId": bson.M{"$in": martIds}}}},
+				{{Key: "$sort", Value: bson.D{{Key: "record.timeStamp.at", Value: -1}, {Key: "record.rid", Value: -1}}}},
+				{{Key: "$group", Value: bson.M{"_id": "$mart.martId", "records": bson.M{"$push": "$$ROOT"}}}},
+				{{Key: "$project", Value: bson.M{"records": bson.M{"$slice": bson.A{"$records", first}}}}},
+			})
+			if err != nil {
+				return nil, err
+			}
+			var groups []struct {
+				MartId  string               `bson:"_id"`
+				Records []models.RecordInput `bson:"records"`
+			}
+			err = cursor.All(ctx, &groups)
+			cursor.Close(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range groups {
+				latest[martRecordsKey{group.MartId, first}] = group.Records
+			}
+		}
+		return latest, nil
+	})
+
+	r.prices = graph.NewLoader(func(ctx context.Context, normNames []string) (map[string]*models.PriceHistory, error) {
+		match := bson.M{"$or": access.Readable(), "record.rid": bson.M{"$exists": true}}
+		return analytics.PriceHistories(ctx, match, normNames, r.home, r.loc)
+	})
+
+	return r
+}
+
+func findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, results interface{}) error {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// loadError logs a failed loader query and hides it from the client
+func loadError(err error, what string) error {
+	log.Printf("Loader error: %v\n", err)
+	return &opError{http.StatusInternalServerError, "Failed to fetch " + what}
+}
+
+// recordNodes wraps records for the resolvers and announces what they refer to
+func (r *graphRequest) recordNodes(records []models.RecordInput) []*recordResolver {
+	var martIds []string
+	for _, record := range records {
+		if record.Mart.MartId != "" {
+			martIds = append(martIds, record.Mart.MartId)
+		}
+	}
+	r.marts.Want(martIds...)
+	r.stats.Want(martIds...)
+
+	nodes := make([]*recordResolver, 0, len(records))
+	for _, record := range records {
+		r.users.Want(record.Uid)
+		if record.Record.DuplicateOf != "" {
+			r.records.Want(record.Record.DuplicateOf)
+		}
+		for _, product := range record.Product {
+			r.prices.Want(productKey(product))
+		}
+		nodes = append(nodes, &recordResolver{r: r, record: record, martIds: martIds})
+	}
+	return nodes
+}
+
+func (r *graphRequest) martNodes(directory []models.Mart) []*martResolver {
+	ids := make([]string, 0, len(directory))
+	for _, mart := range directory {
+		ids = append(ids, mart.MartId)
+	}
+	r.stats.Want(ids...)
+
+	nodes := make([]*martResolver, 0, len(directory))
+	for _, mart := range directory {
+		nodes = append(nodes, &martResolver{r: r, mart: mart, siblings: ids})
+	}
+	return nodes
+}
+
+// priceHistory returns nil for products that were never bought, like
+// GET /products/:name/prices answers 404
+func (r *graphRequest) priceHistory(ctx context.Context, normName string) (*priceHistoryResolver, error) {
+	history, found, err := r.prices.Load(ctx, normName)
+	if err != nil {
+		return nil, loadError(err, "price history")
+	}
+	if !found || len(history.Purchases) == 0 {
+		return nil, nil
+	}
+	return &priceHistoryResolver{history}, nil
+}
+
+func productKey(product models.DBProduct) string {
+	if product.NormName != "" {
+		return product.NormName
+	}
+	return normalize.ProductName(product.Pname)
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+type viewerResolver struct {
+	user models.User
+	home string
+}
+
+func (v *viewerResolver) Uid() graphql.ID      { return graphql.ID(v.user.Uid) }
+func (v *viewerResolver) Nickname() string     { return v.user.Nickname }
+func (v *viewerResolver) Email() string        { return v.user.Email }
+func (v *viewerResolver) HomeCurrency() string { return v.home }
+
+func (v *viewerResolver) TimeZone() string {
+	if v.user.TimeZone == "" {
+		return dates.DefaultTimeZone
+	}
+	return v.user.TimeZone
+}
+
+type userResolver struct {
+	user models.User
+}
+
+func (u *userResolver) Uid() graphql.ID  { return graphql.ID(u.user.Uid) }
+func (u *userResolver) Nickname() string { return u.user.Nickname }
+
+type pageInfo struct {
+	endCursor   *string
+	hasNextPage bool
+}
+
+func (p pageInfo) EndCursor() *string { return p.endCursor }
+func (p pageInfo) HasNextPage() bool  { return p.hasNextPage }
+
+type recordConnection struct {
+	filter   bson.M
+	nodes    []*recordResolver
+	pageInfo pageInfo
+}
+
+func (c *recordConnection) Nodes() []*recordResolver { return c.nodes }
+func (c *recordConnection) PageInfo() pageInfo       { return c.pageInfo }
+
+func (c *recordConnection) TotalCount(ctx context.Context) (int32, error) {
+	count, err := db.Collection.CountDocuments(ctx, c.filter)
+	if err != nil {
+		return 0, loadError(err, "record count")
+	}
+	return int32(count), nil
+}
+
+type martConnection struct {
+	nodes    []*martResolver
+	pageInfo pageInfo
+}
+
+func (c *martConnection) Nodes() []*martResolver { return c.nodes }
+func (c *martConnection) PageInfo() pageInfo     { return c.pageInfo }
+
+type recordPayload struct {
+	record *recordResolver
+	job    *models.CategoryJob
+}
+
+func (p *recordPayload) Record() *recordResolver { return p.record }
+
+func (p *recordPayload) JobId() *graphql.ID {
+	if p.job == nil {
+		return nil
+	}
+	id := graphql.ID(p.job.JobId)
+	return &id
+}
+
+type martPayload struct {
+	mart    *martResolver
+	records int
+}
+
+func (p *martPayload) Mart() *martResolver { return p.mart }
+func (p *martPayload) Records() int32      { return int32(p.records) }
+
+type recordResolver struct {
+	r      *graphRequest
+	record models.RecordInput
+	// martIds are the marts of the records listed with this one
+	martIds []string
+}
+
+func (rr *recordResolver) Rid() graphql.ID   { return graphql.ID(rr.record.Record.Rid) }
+func (rr *recordResolver) Rname() string     { return rr.record.Record.Rname }
+func (rr *recordResolver) HasTime() bool     { return rr.record.Record.TimeStamp.HasTime }
+func (rr *recordResolver) Note() *string     { return optional(rr.record.Record.Note) }
+func (rr *recordResolver) Currency() string  { return currency.Normalize(rr.record.Currency) }
+func (rr *recordResolver) TotalPrice() int32 { return int32(rr.record.TotalPrice) }
+func (rr *recordResolver) Discount() int32   { return int32(rr.record.Discount) }
+func (rr *recordResolver) MartName() string  { return rr.record.Mart.MartName }
+func (rr *recordResolver) MartAddress() string {
+	return rr.record.Mart.MartAddress
+}
+func (rr *recordResolver) MartTel() string { return rr.record.Mart.Tel }
+func (rr *recordResolver) HasImage() bool  { return rr.record.Image != nil }
+
+func (rr *recordResolver) PurchasedAt() *string {
+	ts := rr.record.Record.TimeStamp
+	if ts.At.IsZero() {
+		return nil
+	}
+	return optional(graphTime(ts.At, dates.LoadLocation(ts.TimeZone)))
+}
+
+func (rr *recordResolver) Date() *string {
+	return optional(rr.record.Record.TimeStamp.Date())
+}
+
+func (rr *recordResolver) TimeZone() string {
+	if rr.record.Record.TimeStamp.TimeZone == "" {
+		return dates.DefaultTimeZone
+	}
+	return rr.record.Record.TimeStamp.TimeZone
+}
+
+func (rr *recordResolver) Tags() []string {
+	if rr.record.Record.Tags == nil {
+		return []string{}
+	}
+	return rr.record.Record.Tags
+}
+
+func (rr *recordResolver) GroupId() *graphql.ID {
+	if rr.record.GroupId == "" {
+		return nil
+	}
+	id := graphql.ID(rr.record.GroupId)
+	return &id
+}
+
+func (rr *recordResolver) Owner(ctx context.Context) (*userResolver, error) {
+	user, found, err := rr.r.users.Load(ctx, rr.record.Uid)
+	if err != nil {
+		return nil, loadError(err, "user")
+	}
+	if !found {
+		return nil, nil
+	}
+	return &userResolver{user}, nil
+}
+
+func (rr *recordResolver) Mart(ctx context.Context) (*martResolver, error) {
+	if rr.record.Mart.MartId == "" {
+		return nil, nil
+	}
+	mart, found, err := rr.r.marts.Load(ctx, rr.record.Mart.MartId)
+	if err != nil {
+		return nil, loadError(err, "mart")
+	}
+	if !found {
+		return nil, nil
+	}
+	return &martResolver{r: rr.r, mart: mart, siblings: rr.martIds}, nil
+}
+
+func (rr *recordResolver) Products() []*lineItemResolver {
+	items := make([]*lineItemResolver, 0, len(rr.record.Product))
+	for _, product := range rr.record.Product {
+		items = append(items, &lineItemResolver{r: rr.r, product: product})
+	}
+	return items
+}
+
+func (rr *recordResolver) Issues(args struct{ Resolved *bool }) []*issueResolver {
+	issues := []*issueResolver{}
+	for _, issue := range rr.record.Issues {
+		if args.Resolved == nil || *args.Resolved == issue.Resolved {
+			issues = append(issues, &issueResolver{r: rr.r, issue: issue})
+		}
+	}
+	return issues
+}
+
+func (rr *recordResolver) DuplicateOf(ctx context.Context) (*recordResolver, error) {
+	if rr.record.Record.DuplicateOf == "" {
+		return nil, nil
+	}
+	record, found, err := rr.r.records.Load(ctx, rr.record.Record.DuplicateOf)
+	if err != nil {
+		return nil, loadError(err, "record")
+	}
+	if !found {
+		return nil, nil
+	}
+	return rr.r.recordNodes([]models.RecordInput{record})[0], nil
+}
+
+type lineItemResolver struct {
+	r       *graphRequest
+	product models.DBProduct
+}
+
+func (l *lineItemResolver) Pname() string           { return l.product.Pname }
+func (l *lineItemResolver) Price() int32            { return int32(l.product.Price) }
+func (l *lineItemResolver) Amount() int32           { return int32(l.product.Amount) }
+func (l *lineItemResolver) Discount() int32         { return int32(l.product.Discount) }
+func (l *lineItemResolver) LineTotal() int32        { return int32(l.product.LineTotal()) }
+func (l *lineItemResolver) Category() *string       { return optional(l.product.Category) }
+func (l *lineItemResolver) CategorySource() *string { return optional(l.product.CategorySource) }
+
+func (l *lineItemResolver) PriceHistory(ctx context.Context) (*priceHistoryResolver, error) {
+	return l.r.priceHistory(ctx, productKey(l.product))
+}
+
+type issueResolver struct {
+	r     *graphRequest
+	issue models.RecordIssue
+}
+
+func (i *issueResolver) IssueId() graphql.ID { return graphql.ID(i.issue.IssueId) }
+func (i *issueResolver) Code() string        { return i.issue.Code }
+func (i *issueResolver) Pname() *string      { return optional(i.issue.Pname) }
+func (i *issueResolver) Expected() int32     { return int32(i.issue.Expected) }
+func (i *issueResolver) Actual() int32       { return int32(i.issue.Actual) }
+func (i *issueResolver) Resolved() bool      { return i.issue.Resolved }
+func (i *issueResolver) DetectedAt() string  { return graphTime(i.issue.DetectedAt, i.r.loc) }
+
+func (i *issueResolver) ResolvedAt() *string {
+	if i.issue.ResolvedAt == nil {
+		return nil
+	}
+	return optional(graphTime(*i.issue.ResolvedAt, i.r.loc))
+}
+
+type martResolver struct {
+	r    *graphRequest
+	mart models.Mart
+	// siblings are the marts listed with this one, whose records are read together
+	siblings []string
+}
+
+func (m *martResolver) MartId() graphql.ID { return graphql.ID(m.mart.MartId) }
+func (m *martResolver) Name() string       { return m.mart.Name }
+func (m *martResolver) Address() *string   { return optional(m.mart.Address) }
+func (m *martResolver) Tel() *string       { return optional(m.mart.Tel) }
+func (m *martResolver) BizNum() *string    { return optional(m.mart.BizNum) }
+
+func (m *martResolver) Aliases() []string {
+	if m.mart.Aliases == nil {
+		return []string{}
+	}
+	return m.mart.Aliases
+}
+
+func (m *martResolver) Stats(ctx context.Context) (*martStatsResolver, error) {
+	stats, _, err := m.r.stats.Load(ctx, m.mart.MartId)
+	if err != nil {
+		return nil, loadError(err, "mart stats")
+	}
+	return &martStatsResolver{r: m.r, stats: stats}, nil
+}
+
+func (m *martResolver) Records(ctx context.Context, args struct{ First int32 }) ([]*recordResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	for _, id := range m.siblings {
+		m.r.latest.Want(martRecordsKey{id, int(args.First)})
+	}
+	records, _, err := m.r.latest.Load(ctx, martRecordsKey{m.mart.MartId, int(args.First)})
+	if err != nil {
+		return nil, loadError(err, "records")
+	}
+	return m.r.recordNodes(records), nil
+}
+
+type martStatsResolver struct {
+	r     *graphRequest
+	stats models.MartStats
+}
+
+func (s *martStatsResolver) Currency() string   { return s.r.home }
+func (s *martStatsResolver) Visits() int32      { return int32(s.stats.Visits) }
+func (s *martStatsResolver) Spend() int32       { return int32(s.stats.Spend) }
+func (s *martStatsResolver) AvgBasket() float64 { return s.stats.AvgBasket }
+
+func (s *martStatsResolver) FirstVisit() *string {
+	if s.stats.FirstVisit == nil {
+		return nil
+	}
+	return optional(graphTime(*s.stats.FirstVisit, s.r.loc))
+}
+
+func (s *martStatsResolver) LastVisit() *string {
+	if s.stats.LastVisit == nil {
+		return nil
+	}
+	return optional(graphTime(*s.stats.LastVisit, s.r.loc))
+}
+
+type priceHistoryResolver struct {
+	history *models.PriceHistory
+}
+
+func (p *priceHistoryResolver) Product() string       { return p.history.Product }
+func (p *priceHistoryResolver) Currency() string      { return p.history.Currency }
+func (p *priceHistoryResolver) Names() []string       { return p.history.Names }
+func (p *priceHistoryResolver) CheapestMart() *string { return optional(p.history.CheapestMart) }
+
+func (p *priceHistoryResolver) Stats() *priceStatsResolver {
+	return &priceStatsResolver{p.history.Stats}
+}
+
+func (p *priceHistoryResolver) Purchases() []*pricePointResolver {
+	points := make([]*pricePointResolver, 0, len(p.history.Purchases))
+	for _, point := range p.history.Purchases {
+		points = append(points, &pricePointResolver{point})
+	}
+	return points
+}
+
+func (p *priceHistoryResolver) ByMart() []*martPriceResolver {
+	byMart := make([]*martPriceResolver, 0, len(p.history.ByMart))
+	for _, stats := range p.history.ByMart {
+		byMart = append(byMart, &martPriceResolver{stats})
+	}
+	return byMart
+}
+
+type pricePointResolver struct {
+	point models.PricePoint
+}
+
+func (p *pricePointResolver) Rid() graphql.ID  { return graphql.ID(p.point.Rid) }
+func (p *pricePointResolver) Date() string     { return p.point.TimeStamp.Date() }
+func (p *pricePointResolver) MartName() string { return p.point.MartName }
+func (p *pricePointResolver) Pname() string    { return p.point.Pname }
+func (p *pricePointResolver) UnitPrice() int32 { return int32(p.point.UnitPrice) }
+func (p *pricePointResolver) Amount() int32    { return int32(p.point.Amount) }
+
+type priceStatsResolver struct {
+	stats models.PriceStats
+}
+
+func (s *priceStatsResolver) Min() int32   { return int32(s.stats.Min) }
+func (s *priceStatsResolver) Max() int32   { return int32(s.stats.Max) }
+func (s *priceStatsResolver) Avg() float64 { return s.stats.Avg }
+func (s *priceStatsResolver) Count() int32 { return int32(s.stats.Count) }
+
+type martPriceResolver struct {
+	stats models.MartPriceStats
+}
+
+func (s *martPriceResolver) MartName() string { return s.stats.MartName }
+func (s *martPriceResolver) Min() int32       { return int32(s.stats.Min) }
+func (s *martPriceResolver) Max() int32       { return int32(s.stats.Max) }
+func (s *martPriceResolver) Avg() float64     { return s.stats.Avg }
+func (s *martPriceResolver) Count() int32     { return int32(s.stats.Count) }
+func (s *martPriceResolver) Last() int32      { return int32(s.stats.Last) }
+
+type tagResolver struct {
+	tag models.TagCount
+}
+
+func (t *tagResolver) Name() string      { return t.tag.Name }
+func (t *tagResolver) Count() int32      { return int32(t.tag.Count) }
+func (t *tagResolver) CreatedAt() string { return t.tag.CreatedAt.Format(time.RFC3339) }
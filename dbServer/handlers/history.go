@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/history"
+	"dbserver/models"
+	"dbserver/split"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetRecordHistory(c *gin.Context) {
+	rid := c.Param("rid")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	// Only users who can read the record can read its history
+	count, err := db.Collection.CountDocuments(ctx, bson.M{"record.rid": rid, "$or": access.Readable()})
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+
+	entries, err := history.List(ctx, rid)
+	if err != nil {
+		log.Printf("History error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": entries})
+}
+
+func RevertRecord(c *gin.Context) {
+	rid := c.Param("rid")
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{
+		"record.rid": rid,
+		"$or":        access.Writable(),
+	}
+
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			recordNotWritable(c, ctx, access, rid, "Record not found")
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		}
+		return
+	}
+
+	target, err := history.Get(ctx, rid, version)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		} else {
+			log.Printf("History error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record history"})
+		}
+		return
+	}
+
+	// Restore only what the user edits. Tags, duplicate marks, the import batch and the
+	// split are kept as they are now; the fingerprint is recomputed by refreshChecks.
+	snapshot := target.Snapshot
+	set := bson.M{
+		"record.rname":     snapshot.Record.Rname,
+		"record.timeStamp": snapshot.Record.TimeStamp,
+		"mart":             snapshot.Mart,
+		"product":          snapshot.Product,
+		"currency":         snapshot.Currency,
+		"totalPrice":       snapshot.TotalPrice,
+		"discount":         snapshot.Discount,
+		"tax":              snapshot.Tax,
+		"payment":          snapshot.Payment,
+	}
+	unset := bson.M{}
+	if snapshot.Record.Note == "" {
+		unset["record.note"] = ""
+	} else {
+		set["record.note"] = snapshot.Record.Note
+	}
+
+	// 나눈 품목은 위치로 가리키므로 되돌린 상품 목록에 맞춰 옮김
+	if existingRecord.Split != nil {
+		remapped, err := split.Remap(existingRecord.Split, existingRecord.Product, snapshot.Product)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error() + ", change the split first"})
+			return
+		}
+		set["split.items"] = remapped.Items
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var revertedRecord models.RecordInput
+	var entry *models.RecordHistory
+	err = db.Transaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := db.Collection.UpdateOne(sc, filter, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return &opError{http.StatusNotFound, "Record not found"}
+		}
+
+		if err := db.Collection.FindOne(sc, filter).Decode(&revertedRecord); err != nil {
+			return err
+		}
+		if err := refreshChecks(sc, &revertedRecord); err != nil {
+			return err
+		}
+
+		entry, err = history.RecordRevert(sc, account.Uid, version, &existingRecord, revertedRecord)
+		return err
+	})
+	if err != nil {
+		var opErr *opError
+		if errors.As(err, &opErr) {
+			c.JSON(opErr.code, gin.H{"error": opErr.message})
+			return
+		}
+		log.Printf("Revert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Record reverted successfully",
+		"version": entry.Version,
+		"record":  revertedRecord,
+	})
+}
@@ -0,0 +1,568 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/household"
+	"dbserver/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// findHousehold loads a household the user belongs to with at least the required role,
+// writing the error response if it fails
+func findHousehold(c *gin.Context, ctx context.Context, uid string, required string) (*models.Household, bool) {
+	var h models.Household
+	err := db.HouseholdCollection.FindOne(ctx, bson.M{"groupId": c.Param("groupId"), "members.uid": uid}).Decode(&h)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Household not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch household"})
+		return nil, false
+	}
+
+	if !household.AtLeast(household.RoleOf(h, uid), required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the household " + required + " can do this"})
+		return nil, false
+	}
+	return &h, true
+}
+
+func CreateHousehold(c *gin.Context) {
+	var req models.HouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	h := models.Household{
+		GroupId: uuid.NewString(),
+		Name:    req.Name,
+		Members: []models.HouseholdMember{
+			{Uid: account.Uid, Role: models.HouseholdRoleOwner, JoinedAt: now},
+		},
+		CreatedAt: now,
+	}
+
+	if _, err := db.HouseholdCollection.InsertOne(ctx, h); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create household"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Household created successfully",
+		"household": h,
+	})
+}
+
+func ListHouseholds(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := db.HouseholdCollection.Find(ctx, bson.M{"members.uid": account.Uid}, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch households"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	households := []models.Household{}
+	if err := cursor.All(ctx, &households); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode households"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"households": households})
+}
+
+func GetHousehold(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleViewer)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"household": h})
+}
+
+func UpdateHousehold(c *gin.Context) {
+	var req models.HouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleOwner)
+	if !ok {
+		return
+	}
+
+	if _, err := db.HouseholdCollection.UpdateOne(ctx, bson.M{"groupId": h.GroupId}, bson.M{"$set": bson.M{"name": req.Name}}); err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update household"})
+		return
+	}
+	h.Name = req.Name
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Household updated successfully",
+		"household": h,
+	})
+}
+
+// DeleteHousehold removes a household. Its records go back to the personal ledger of
+// the members who saved them.
+func DeleteHousehold(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleOwner)
+	if !ok {
+		return
+	}
+
+	moved, err := updateRecords(ctx, account.Uid, bson.M{"groupId": h.GroupId}, bson.M{"$unset": bson.M{"groupId": ""}})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move household records"})
+		return
+	}
+
+	if _, err := db.InvitationCollection.DeleteMany(ctx, bson.M{"groupId": h.GroupId}); err != nil {
+		log.Printf("Delete error: %v\n", err)
+	}
+	if _, err := db.HouseholdCollection.DeleteOne(ctx, bson.M{"groupId": h.GroupId}); err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete household"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Household deleted successfully",
+		"moved":   moved,
+	})
+}
+
+func CreateInvitation(c *gin.Context) {
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleOwner)
+	if !ok {
+		return
+	}
+
+	token, err := household.NewToken()
+	if err != nil {
+		log.Printf("Token error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	now := time.Now()
+	invitation := models.HouseholdInvitation{
+		InviteId:  uuid.NewString(),
+		GroupId:   h.GroupId,
+		Token:     token,
+		Role:      req.Role,
+		InvitedBy: account.Uid,
+		ExpiresAt: now.Add(household.InvitationTTL),
+		CreatedAt: now,
+	}
+
+	if _, err := db.InvitationCollection.InsertOne(ctx, invitation); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation created successfully",
+		"invitation": invitation,
+	})
+}
+
+// ListInvitations returns the household's invitations that can still be accepted
+func ListInvitations(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleOwner)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{
+		"groupId":    h.GroupId,
+		"acceptedBy": bson.M{"$exists": false},
+		"expiresAt":  bson.M{"$gt": time.Now()},
+	}
+	cursor, err := db.InvitationCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.HouseholdInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func RevokeInvitation(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleOwner)
+	if !ok {
+		return
+	}
+
+	result, err := db.InvitationCollection.DeleteOne(ctx, bson.M{
+		"groupId":    h.GroupId,
+		"inviteId":   c.Param("inviteId"),
+		"acceptedBy": bson.M{"$exists": false},
+	})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+func AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	valid := bson.M{
+		"token":      req.Token,
+		"acceptedBy": bson.M{"$exists": false},
+		"expiresAt":  bson.M{"$gt": now},
+	}
+
+	var invitation models.HouseholdInvitation
+	err = db.InvitationCollection.FindOne(ctx, valid).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return
+	}
+
+	count, err := db.HouseholdCollection.CountDocuments(ctx, bson.M{"groupId": invitation.GroupId, "members.uid": account.Uid})
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch household"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already a member of this household"})
+		return
+	}
+
+	// 초대는 한 번만 사용할 수 있으므로 먼저 수락 처리
+	result, err := db.InvitationCollection.UpdateOne(ctx, valid, bson.M{"$set": bson.M{"acceptedBy": account.Uid, "acceptedAt": now}})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation is invalid or has expired"})
+		return
+	}
+
+	member := models.HouseholdMember{Uid: account.Uid, Role: invitation.Role, JoinedAt: now}
+	result, err = db.HouseholdCollection.UpdateOne(ctx,
+		bson.M{"groupId": invitation.GroupId, "members.uid": bson.M{"$ne": account.Uid}},
+		bson.M{"$push": bson.M{"members": member}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join household"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already a member of this household"})
+		return
+	}
+
+	var h models.Household
+	if err := db.HouseholdCollection.FindOne(ctx, bson.M{"groupId": invitation.GroupId}).Decode(&h); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch household"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Joined household successfully",
+		"household": h,
+	})
+}
+
+// UpdateMember changes a member's role. Giving someone the owner role transfers
+// ownership; the previous owner becomes an editor.
+func UpdateMember(c *gin.Context) {
+	var req models.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h, ok := findHousehold(c, ctx, account.Uid, models.HouseholdRoleOwner)
+	if !ok {
+		return
+	}
+
+	uid := c.Param("uid")
+	if household.RoleOf(*h, uid) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if uid == account.Uid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer ownership to another member instead"})
+		return
+	}
+
+	for i := range h.Members {
+		switch {
+		case h.Members[i].Uid == uid:
+			h.Members[i].Role = req.Role
+		case req.Role == models.HouseholdRoleOwner && h.Members[i].Uid == account.Uid:
+			h.Members[i].Role = models.HouseholdRoleEditor
+		}
+	}
+
+	_, err = db.HouseholdCollection.UpdateOne(ctx, bson.M{"groupId": h.GroupId}, bson.M{"$set": bson.M{"members": h.Members}})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Member updated successfully",
+		"household": h,
+	})
+}
+
+// RemoveMember removes a member; members can also remove themselves to leave. The
+// owner has to transfer ownership before leaving a household with other members.
+func RemoveMember(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	uid := c.Param("uid")
+	required := models.HouseholdRoleOwner
+	if uid == account.Uid {
+		required = models.HouseholdRoleViewer
+	}
+
+	h, ok := findHousehold(c, ctx, account.Uid, required)
+	if !ok {
+		return
+	}
+
+	role := household.RoleOf(*h, uid)
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if role == models.HouseholdRoleOwner {
+		if len(h.Members) > 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Transfer ownership before leaving the household"})
+		} else {
+			c.JSON(http.StatusConflict, gin.H{"error": "Delete the household instead"})
+		}
+		return
+	}
+
+	_, err = db.HouseholdCollection.UpdateOne(ctx, bson.M{"groupId": h.GroupId}, bson.M{"$pull": bson.M{"members": bson.M{"uid": uid}}})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// MoveRecord moves a record into a household the user edits, or back to the personal
+// ledger of the member who saved it
+func MoveRecord(c *gin.Context) {
+	var req models.MoveRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	rid := c.Param("rid")
+	var record models.RecordInput
+	err = db.Collection.FindOne(ctx, bson.M{"record.rid": rid, "$or": access.Writable()}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		recordNotWritable(c, ctx, access, rid, "Record not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		return
+	}
+
+	var update bson.M
+	if req.GroupId == "" {
+		if record.Uid != account.Uid {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the member who saved the record can make it personal"})
+			return
+		}
+		update = bson.M{"$unset": bson.M{"groupId": ""}}
+	} else {
+		if !household.AtLeast(access.Role(req.GroupId), models.HouseholdRoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only household editors can add records"})
+			return
+		}
+		update = bson.M{"$set": bson.M{"groupId": req.GroupId}}
+	}
+
+	if _, err := updateRecords(ctx, account.Uid, bson.M{"record.rid": rid}, update); err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Record moved successfully"})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/storage"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetRecordImage sends the receipt photo of a record, or its thumbnail with
+// ?size=thumbnail. Anyone who can read the record can see the photo.
+func GetRecordImage(c *gin.Context) {
+	rid := c.Param("rid")
+	thumbnail := c.Query("size") == "thumbnail"
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	var record struct {
+		Image *models.ReceiptImage `bson:"image"`
+	}
+	err = db.Collection.FindOne(ctx,
+		bson.M{"record.rid": rid, "$or": access.Readable()},
+		options.FindOne().SetProjection(bson.M{"image": 1}),
+	).Decode(&record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		} else {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		}
+		return
+	}
+	if record.Image == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record has no image"})
+		return
+	}
+
+	key, size, contentType := record.Image.Key, record.Image.Size, record.Image.ContentType
+	// 썸네일을 만들지 못한 이미지는 원본을 보냄
+	if thumbnail && record.Image.ThumbnailKey != "" {
+		key, size, contentType = record.Image.ThumbnailKey, record.Image.ThumbnailSize, "image/jpeg"
+	}
+
+	reader, err := storage.Images.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		} else {
+			log.Printf("Image error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image"})
+		}
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"Cache-Control": "private, max-age=86400",
+	})
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/budget"
+	"dbserver/category"
+	"dbserver/db"
+	"dbserver/imports"
+	"dbserver/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func ListImportPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"presets": imports.Presets})
+}
+
+// previewImport validates the batch rows against its mapping and flags duplicates
+func previewImport(ctx context.Context, batch *models.ImportBatch, loc *time.Location) error {
+	rows, err := imports.Build(batch.Header, batch.Cells, batch.Mapping, loc)
+	if err != nil {
+		return err
+	}
+	if err := imports.MarkDuplicates(ctx, batch.Uid, rows, loc); err != nil {
+		log.Printf("Duplicate check error: %v\n", err)
+	}
+	batch.Rows = rows
+	batch.Summary = imports.Summarize(rows)
+	return nil
+}
+
+// CreateImport uploads a CSV file (multipart field "file") and returns a preview.
+// The columns are detected from the card company presets unless "preset" names one
+// or "mapping" gives the column mapping as JSON.
+func CreateImport(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if file.Size > imports.MaxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	header, cells, err := imports.ReadCSV(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV: " + err.Error()})
+		return
+	}
+
+	batch := models.ImportBatch{
+		BatchId:   uuid.NewString(),
+		Uid:       account.Uid,
+		Status:    models.ImportStatusPreview,
+		FileName:  file.Filename,
+		Header:    header,
+		Cells:     cells,
+		CreatedAt: time.Now(),
+	}
+
+	if value := c.PostForm("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &batch.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping: " + err.Error()})
+			return
+		}
+	} else if name := c.PostForm("preset"); name != "" {
+		preset, ok := imports.FindPreset(name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown preset"})
+			return
+		}
+		mapping, _, ok := preset.Mapping(header)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File does not match the " + preset.Label + " layout", "header": header})
+			return
+		}
+		batch.Preset, batch.Mapping = preset.Name, mapping
+	} else if preset, mapping, ok := imports.Detect(header); ok {
+		batch.Preset, batch.Mapping = preset.Name, mapping
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// 매핑을 알 수 없으면 헤더만 돌려주고 매핑 단계에서 미리보기를 만듦
+	if batch.Mapping.Date != "" {
+		if err := previewImport(ctx, &batch, userLocation(ctx, account.Uid)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if _, err := db.ImportCollection.InsertOne(ctx, batch); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save import"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"import": batch})
+}
+
+func ListImports(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetProjection(bson.M{"cells": 0, "rows": 0})
+	cursor, err := db.ImportCollection.Find(ctx, bson.M{"uid": account.Uid}, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch imports"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	batches := []models.ImportBatch{}
+	if err := cursor.All(ctx, &batches); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imports": batches})
+}
+
+// findImport loads one of the user's batches, writing the error response if it fails
+func findImport(c *gin.Context, ctx context.Context, uid string) (*models.ImportBatch, bool) {
+	var batch models.ImportBatch
+	err := db.ImportCollection.FindOne(ctx, bson.M{"batchId": c.Param("batchId"), "uid": uid}).Decode(&batch)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import"})
+		return nil, false
+	}
+	return &batch, true
+}
+
+func GetImport(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	batch, ok := findImport(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"import": batch})
+}
+
+// UpdateImportMapping changes the column mapping of a batch and rebuilds its preview
+func UpdateImportMapping(c *gin.Context) {
+	var req models.ColumnMapping
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	batch, ok := findImport(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+	if batch.Status != models.ImportStatusPreview {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import has already been committed"})
+		return
+	}
+
+	batch.Mapping = req
+	batch.Preset = ""
+	if err := previewImport(ctx, batch, userLocation(ctx, account.Uid)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := db.ImportCollection.UpdateOne(ctx,
+		bson.M{"batchId": batch.BatchId, "status": models.ImportStatusPreview},
+		bson.M{"$set": bson.M{
+			"mapping": batch.Mapping,
+			"preset":  batch.Preset,
+			"rows":    batch.Rows,
+			"summary": batch.Summary,
+		}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update import"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import has already been committed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"import": batch})
+}
+
+// CommitImport creates the records of a previewed batch
+func CommitImport(c *gin.Context) {
+	var req models.CommitImportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	batch, ok := findImport(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+	if batch.Mapping.Date == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set the column mapping before committing"})
+		return
+	}
+
+	// 같은 배치를 두 번 커밋하지 못하도록 상태를 먼저 바꿈
+	now := time.Now()
+	result, err := db.ImportCollection.UpdateOne(ctx,
+		bson.M{"batchId": batch.BatchId, "status": models.ImportStatusPreview},
+		bson.M{"$set": bson.M{"status": models.ImportStatusCommitted, "committedAt": now}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit import"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import has already been committed"})
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+
+	// 미리보기 이후 추가된 레코드와의 중복도 다시 확인
+	if err := imports.MarkDuplicates(ctx, account.Uid, batch.Rows, loc); err != nil {
+		log.Printf("Duplicate check error: %v\n", err)
+	}
+
+	categorizer, err := category.Load(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Category error: %v\n", err)
+		categorizer = category.New(nil)
+	}
+
+	if err := imports.Commit(ctx, batch, req.IncludeDuplicates, categorizer); err != nil {
+		log.Printf("Import error: %v\n", err)
+		db.ImportCollection.UpdateOne(ctx,
+			bson.M{"batchId": batch.BatchId},
+			bson.M{"$set": bson.M{"status": models.ImportStatusPreview}, "$unset": bson.M{"committedAt": ""}},
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import records: " + err.Error()})
+		return
+	}
+
+	batch.Status = models.ImportStatusCommitted
+	batch.CommittedAt = &now
+	_, err = db.ImportCollection.UpdateOne(ctx,
+		bson.M{"batchId": batch.BatchId},
+		bson.M{"$set": bson.M{"rows": batch.Rows, "summary": batch.Summary}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+	}
+
+	// 과거 기간까지 알림을 보내지 않도록 현재 기간의 예산만 확인
+	alerts, err := budget.Evaluate(ctx, account.Uid, now, homeCurrency(ctx, account.Uid), loc)
+	if err != nil {
+		log.Printf("Budget error: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Records imported successfully",
+		"import":  batch,
+		"alerts":  alerts,
+	})
+}
+
+// DeleteImport discards a preview, or undoes a committed batch by deleting every
+// record it created (including later edits to those records)
+func DeleteImport(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	batch, ok := findImport(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	switch batch.Status {
+	case models.ImportStatusPreview:
+		if _, err := db.ImportCollection.DeleteOne(ctx, bson.M{"batchId": batch.BatchId, "status": models.ImportStatusPreview}); err != nil {
+			log.Printf("Delete error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete import"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Import discarded"})
+
+	case models.ImportStatusCommitted:
+		access, ok := loadAccess(c, ctx, account.Uid)
+		if !ok {
+			return
+		}
+
+		deleted, rids, err := imports.Undo(ctx, account.Uid, batch.BatchId)
+		if err != nil {
+			log.Printf("Delete error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo import"})
+			return
+		}
+
+		// 지운 레코드를 가리키던 중복 표시 해제
+		if len(rids) > 0 {
+			_, err = updateRecords(ctx, account.Uid,
+				bson.M{"$or": access.Writable(), "record.duplicateOf": bson.M{"$in": rids}},
+				bson.M{"$unset": bson.M{"record.duplicateOf": ""}},
+			)
+			if err != nil {
+				log.Printf("Update error: %v\n", err)
+			}
+		}
+
+		_, err = db.ImportCollection.UpdateOne(ctx,
+			bson.M{"batchId": batch.BatchId},
+			bson.M{"$set": bson.M{"status": models.ImportStatusUndone, "undoneAt": time.Now()}},
+		)
+		if err != nil {
+			log.Printf("Update error: %v\n", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Import undone",
+			"deleted": deleted,
+		})
+
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "Import has already been undone"})
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/reconcile"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListRecordIssues lists the receipts with open reconciliation issues, newest first.
+// ?resolved=true also lists receipts whose issues were all resolved. The group, date
+// and tag filters of GET /records apply.
+func ListRecordIssues(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter, err := recordFilter(c, access, userLocation(ctx, account.Uid))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("resolved") == "true" {
+		filter["issues.0"] = bson.M{"$exists": true}
+	} else {
+		filter["issues"] = bson.M{"$elemMatch": bson.M{"resolved": false}}
+	}
+
+	cursor, err := db.Collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "record.timeStamp.at", Value: -1}}))
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	records := []models.RecordList{}
+	if err := cursor.All(ctx, &records); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode records"})
+		return
+	}
+
+	open := 0
+	for _, record := range records {
+		open += reconcile.Open(record.Issues)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"records": records, "open": open})
+}
+
+// ResolveRecordIssue marks an issue of a receipt resolved, e.g. when the printed
+// total really differs from the lines, or open again with {"resolved": false}
+func ResolveRecordIssue(c *gin.Context) {
+	var req models.ResolveIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resolved := req.Resolved == nil || *req.Resolved
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	rid, issueId := c.Param("rid"), c.Param("issueId")
+	filter := bson.M{"record.rid": rid, "$or": access.Writable()}
+
+	var record models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			recordNotWritable(c, ctx, access, rid, "Record not found")
+		} else {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		}
+		return
+	}
+
+	found := false
+	for _, issue := range record.Issues {
+		found = found || issue.IssueId == issueId
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+
+	var update bson.M
+	if resolved {
+		update = bson.M{"$set": bson.M{
+			"issues.$[issue].resolved":   true,
+			"issues.$[issue].resolvedBy": account.Uid,
+			"issues.$[issue].resolvedAt": time.Now(),
+		}}
+	} else {
+		update = bson.M{
+			"$set":   bson.M{"issues.$[issue].resolved": false},
+			"$unset": bson.M{"issues.$[issue].resolvedBy": "", "issues.$[issue].resolvedAt": ""},
+		}
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"issue.issueId": issueId}},
+	})
+
+	if _, err := updateWithHistory(ctx, account.Uid, &record, bson.M{"record.rid": rid}, update, opts); err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update issue"})
+		return
+	}
+
+	var updated models.DBRequest
+	if err := db.Collection.FindOne(ctx, bson.M{"record.rid": rid}).Decode(&updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Issue updated successfully",
+		"record":  updated,
+	})
+}
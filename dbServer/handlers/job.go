@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"dbserver/scheduler"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs returns the background jobs with their schedule, next run and latest run
+// (admin only)
+func ListJobs(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	jobs, err := scheduler.List(ctx)
+	if err != nil {
+		log.Printf("Job error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// RunJob starts a job now, outside its schedule (admin only). The run continues in the
+// background; its outcome shows in GET /admin/jobs/:name/runs.
+func RunJob(c *gin.Context) {
+	run, err := scheduler.Trigger(c.Param("name"))
+	switch err {
+	case nil:
+	case scheduler.ErrUnknownJob:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	case scheduler.ErrRunning:
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is already running"})
+		return
+	default:
+		log.Printf("Job error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start job"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Job started",
+		"run":     run,
+	})
+}
+
+// ListJobRuns returns the latest runs of a job, newest first (admin only)
+func ListJobRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	runs, err := scheduler.Runs(ctx, c.Param("name"), int64(limit))
+	if err == scheduler.ErrUnknownJob {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Job error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job runs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
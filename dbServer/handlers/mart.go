@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/marts"
+	"dbserver/models"
+	"dbserver/normalize"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// findMart returns the directory entry martId of uid, writing 404 if there is none
+func findMart(c *gin.Context, ctx context.Context, uid string, martId string) (*models.Mart, bool) {
+	var mart models.Mart
+	err := db.MartCollection.FindOne(ctx, bson.M{"martId": martId, "uid": uid}).Decode(&mart)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Mart not found"})
+		} else {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mart"})
+		}
+		return nil, false
+	}
+	return &mart, true
+}
+
+// syncMartRecords copies the name, address and phone number of mart to the records
+// pointing to it or to one of from, and points them to mart
+func syncMartRecords(ctx context.Context, actor string, mart *models.Mart, from ...string) (int, error) {
+	ids := append([]string{mart.MartId}, from...)
+	return updateRecords(ctx, actor,
+		bson.M{"uid": mart.Uid, "mart.martId": bson.M{"$in": ids}},
+		bson.M{"$set": bson.M{
+			"mart.martId":      mart.MartId,
+			"mart.martName":    mart.Name,
+			"mart.martAddress": mart.Address,
+			"mart.tel":         mart.Tel,
+			"mart.bizNum":      mart.BizNum,
+		}},
+	)
+}
+
+// ListMarts lists the mart directory with visit counts and spend. ?q= searches the
+// names and aliases; ?sort= is visits (default), spend, recent or name.
+func ListMarts(c *gin.Context) {
+	sortBy := c.DefaultQuery("sort", "visits")
+	if sortBy != "visits" && sortBy != "spend" && sortBy != "recent" && sortBy != "name" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of visits, spend, recent, name"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"uid": account.Uid}
+	if q := normalize.ProductName(c.Query("q")); q != "" {
+		filter["normNames"] = bson.M{"$regex": regexp.QuoteMeta(q)}
+	}
+
+	cursor, err := db.MartCollection.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch marts"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var directory []models.Mart
+	if err := cursor.All(ctx, &directory); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch marts"})
+		return
+	}
+
+	ids := make([]string, 0, len(directory))
+	for _, mart := range directory {
+		ids = append(ids, mart.MartId)
+	}
+	home := homeCurrency(ctx, account.Uid)
+	stats, err := marts.Stats(ctx, account.Uid, ids, home, userLocation(ctx, account.Uid))
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute mart stats"})
+		return
+	}
+
+	summaries := make([]models.MartSummary, 0, len(directory))
+	for _, mart := range directory {
+		summaries = append(summaries, models.MartSummary{Mart: mart, Stats: stats[mart.MartId]})
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		switch sortBy {
+		case "spend":
+			if a.Stats.Spend != b.Stats.Spend {
+				return a.Stats.Spend > b.Stats.Spend
+			}
+		case "recent":
+			if a.Stats.LastVisit != nil && b.Stats.LastVisit != nil && !a.Stats.LastVisit.Equal(*b.Stats.LastVisit) {
+				return a.Stats.LastVisit.After(*b.Stats.LastVisit)
+			}
+			if (a.Stats.LastVisit == nil) != (b.Stats.LastVisit == nil) {
+				return a.Stats.LastVisit != nil
+			}
+		case "visits":
+			if a.Stats.Visits != b.Stats.Visits {
+				return a.Stats.Visits > b.Stats.Visits
+			}
+		}
+		return a.Name < b.Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{"currency": home, "marts": summaries})
+}
+
+// GetMart returns a mart with its visit count, spend and latest receipts
+func GetMart(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mart, ok := findMart(c, ctx, account.Uid, c.Param("martId"))
+	if !ok {
+		return
+	}
+
+	home := homeCurrency(ctx, account.Uid)
+	stats, err := marts.Stats(ctx, account.Uid, []string{mart.MartId}, home, userLocation(ctx, account.Uid))
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute mart stats"})
+		return
+	}
+
+	cursor, err := db.Collection.Find(ctx,
+		bson.M{"uid": account.Uid, "mart.martId": mart.MartId},
+		options.Find().
+			SetProjection(bson.M{"product": 0}).
+			SetSort(bson.D{{Key: "record.timeStamp.at", Value: -1}}).
+			SetLimit(20),
+	)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	records := []models.RecordList{}
+	if err := cursor.All(ctx, &records); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"currency": home,
+		"mart":     models.MartSummary{Mart: *mart, Stats: stats[mart.MartId]},
+		"records":  records,
+	})
+}
+
+// EditMart renames a mart or corrects its address, phone or business number. The
+// change is copied to every record of the mart.
+func EditMart(c *gin.Context) {
+	var req models.EditMartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mart, ok := findMart(c, ctx, account.Uid, c.Param("martId"))
+	if !ok {
+		return
+	}
+
+	updated, err := editMart(ctx, account.Uid, mart, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Mart updated successfully",
+		"mart":    mart,
+		"records": updated,
+	})
+}
+
+// editMart applies req to mart and copies the change to its records, returning
+// how many records changed
+func editMart(ctx context.Context, actor string, mart *models.Mart, req models.EditMartRequest) (int, error) {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return 0, &opError{http.StatusBadRequest, "name must not be blank"}
+		}
+		// 이전 이름은 별칭으로 남겨 다음 영수증도 같은 매장으로 인식
+		if name != mart.Name {
+			mart.Aliases = appendAlias(mart.Aliases, mart.Name, name)
+			mart.Name = name
+		}
+	}
+	if req.Address != nil {
+		mart.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.Tel != nil {
+		mart.Tel = strings.TrimSpace(*req.Tel)
+	}
+	if req.BizNum != nil {
+		mart.BizNum = strings.TrimSpace(*req.BizNum)
+	}
+	marts.Refresh(mart)
+	mart.UpdatedAt = time.Now()
+
+	if _, err := db.MartCollection.ReplaceOne(ctx, bson.M{"martId": mart.MartId}, mart); err != nil {
+		log.Printf("Update error: %v\n", err)
+		return 0, &opError{http.StatusInternalServerError, "Failed to update mart"}
+	}
+
+	updated, err := syncMartRecords(ctx, actor, mart)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		return 0, &opError{http.StatusInternalServerError, "Failed to update records of mart"}
+	}
+	return updated, nil
+}
+
+// MergeMarts merges other marts into the mart in the path: their records move to
+// it, their names become aliases and missing details are taken over
+func MergeMarts(c *gin.Context) {
+	var req models.MergeMartsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	target, ok := findMart(c, ctx, account.Uid, c.Param("martId"))
+	if !ok {
+		return
+	}
+
+	for _, id := range req.Sources {
+		if id == target.MartId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A mart cannot be merged into itself"})
+			return
+		}
+	}
+
+	cursor, err := db.MartCollection.Find(ctx, bson.M{"uid": account.Uid, "martId": bson.M{"$in": req.Sources}})
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch marts"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var sources []models.Mart
+	if err := cursor.All(ctx, &sources); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch marts"})
+		return
+	}
+	if len(sources) != len(uniqueStrings(req.Sources)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mart not found"})
+		return
+	}
+
+	sourceIds := make([]string, 0, len(sources))
+	for _, source := range sources {
+		sourceIds = append(sourceIds, source.MartId)
+		target.Aliases = appendAlias(target.Aliases, append([]string{source.Name}, source.Aliases...)...)
+		if target.Address == "" {
+			target.Address = source.Address
+		}
+		if target.Tel == "" {
+			target.Tel = source.Tel
+		}
+		if target.BizNum == "" {
+			target.BizNum = source.BizNum
+		}
+	}
+	target.Aliases = removeAlias(target.Aliases, target.Name)
+	marts.Refresh(target)
+	target.UpdatedAt = time.Now()
+
+	if _, err := db.MartCollection.ReplaceOne(ctx, bson.M{"martId": target.MartId}, target); err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mart"})
+		return
+	}
+
+	moved, err := syncMartRecords(ctx, account.Uid, target, sourceIds...)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move records"})
+		return
+	}
+
+	if _, err := db.MartCollection.DeleteMany(ctx, bson.M{"uid": account.Uid, "martId": bson.M{"$in": sourceIds}}); err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete merged marts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Marts merged successfully",
+		"mart":    target,
+		"merged":  len(sourceIds),
+		"records": moved,
+	})
+}
+
+// appendAlias adds names that are not aliases yet
+func appendAlias(aliases []string, names ...string) []string {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, alias := range aliases {
+			if alias == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			aliases = append(aliases, name)
+		}
+	}
+	return aliases
+}
+
+func removeAlias(aliases []string, name string) []string {
+	kept := aliases[:0]
+	for _, alias := range aliases {
+		if alias != name {
+			kept = append(kept, alias)
+		}
+	}
+	return kept
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	jwt "dbserver/auth"
+	"dbserver/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly rejects users that are not listed in the admin configuration.
+// It must run after AuthMiddleware.
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account, err := jwt.GetAccount(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+			c.Abort()
+			return
+		}
+		if !config.IsAdmin(account.Uid) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
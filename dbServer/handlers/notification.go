@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/notify"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListNotifications returns the in-app inbox, newest first, with the number of unread
+// notifications. unread=true leaves out the read ones and before, an RFC 3339 time,
+// pages back from the createdAt of the last notification of the previous page.
+func ListNotifications(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	filter := bson.M{"uid": account.Uid, "inbox": true}
+	if c.Query("unread") == "true" {
+		filter["readAt"] = bson.M{"$exists": false}
+	}
+	if before := c.Query("before"); before != "" {
+		at, err := time.Parse(time.RFC3339Nano, before)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be an RFC 3339 time"})
+			return
+		}
+		filter["createdAt"] = bson.M{"$lt": at}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := db.NotificationCollection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode notifications"})
+		return
+	}
+
+	unread, err := db.NotificationCollection.CountDocuments(ctx, bson.M{
+		"uid":    account.Uid,
+		"inbox":  true,
+		"readAt": bson.M{"$exists": false},
+	})
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "unreadCount": unread})
+}
+
+func MarkNotificationRead(c *gin.Context) {
+	setNotificationRead(c, true)
+}
+
+func MarkNotificationUnread(c *gin.Context) {
+	setNotificationRead(c, false)
+}
+
+func setNotificationRead(c *gin.Context, read bool) {
+	nid := c.Param("nid")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"readAt": ""}}
+	if read {
+		// 이미 읽은 알림은 처음 읽은 시각을 유지
+		update = bson.M{"$min": bson.M{"readAt": time.Now()}}
+	}
+	result, err := db.NotificationCollection.UpdateOne(ctx,
+		bson.M{"nid": nid, "uid": account.Uid, "inbox": true},
+		update,
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification updated successfully", "read": read})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the inbox read
+func MarkAllNotificationsRead(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.NotificationCollection.UpdateMany(ctx,
+		bson.M{"uid": account.Uid, "inbox": true, "readAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"readAt": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked read", "updated": result.ModifiedCount})
+}
+
+func GetNotificationPreferences(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefs, err := notify.LoadPreferences(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// UpdateNotificationPreferences changes the language, the channels of the kinds given
+// and the quiet hours. Fields left out keep their value.
+func UpdateNotificationPreferences(c *gin.Context) {
+	var req models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := notify.ValidatePreferences(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefs, err := notify.SavePreferences(ctx, account.Uid, req)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification preferences updated successfully", "preferences": prefs})
+}
+
+// GetWebPushKey returns the VAPID public key browsers pass to pushManager.subscribe
+func GetWebPushKey(c *gin.Context) {
+	key, ok := notify.WebPushKey()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Web push is not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": key})
+}
+
+// SubscribeWebPush stores the PushSubscription of a browser. A subscription is tied to
+// one browser profile, so it moves to the user who signed in last.
+func SubscribeWebPush(c *gin.Context) {
+	var req models.PushSubscription
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req.Uid = account.Uid
+	req.CreatedAt = time.Now()
+	_, err = db.PushSubscriptionCollection.ReplaceOne(ctx, bson.M{"endpoint": req.Endpoint}, req, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save push subscription"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Push subscription saved successfully", "subscription": req})
+}
+
+// UnsubscribeWebPush removes the subscription with the endpoint query parameter
+func UnsubscribeWebPush(c *gin.Context) {
+	endpoint := c.Query("endpoint")
+	if endpoint == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endpoint is required"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.PushSubscriptionCollection.DeleteOne(ctx, bson.M{"endpoint": endpoint, "uid": account.Uid})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete push subscription"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Push subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Push subscription deleted successfully"})
+}
+
+// RegisterPushDevice stores the FCM or APNs token of a mobile app
+func RegisterPushDevice(c *gin.Context) {
+	var req models.PushDevice
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req.Uid = account.Uid
+	req.CreatedAt = time.Now()
+	_, err = db.PushDeviceCollection.ReplaceOne(ctx, bson.M{"token": req.Token}, req, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Device registered successfully", "device": req})
+}
+
+func DeletePushDevice(c *gin.Context) {
+	token := c.Param("token")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.PushDeviceCollection.DeleteOne(ctx, bson.M{"token": token, "uid": account.Uid})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete device"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device deleted successfully"})
+}
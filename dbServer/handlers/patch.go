@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/category"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/household"
+	"dbserver/marts"
+	"dbserver/models"
+	"dbserver/normalize"
+	"dbserver/patch"
+	"dbserver/split"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 패치 본문 최대 크기
+const maxPatchSize = 1 << 20
+
+// recordDocument builds the editable form of a record
+func recordDocument(record *models.RecordInput) models.RecordDocument {
+	ts := record.Record.TimeStamp
+	doc := models.RecordDocument{
+		Rname:    record.Record.Rname,
+		TimeZone: ts.TimeZone,
+		Note:     record.Record.Note,
+		Tags:     record.Record.Tags,
+		Currency: currency.Normalize(record.Currency),
+		Mart: models.DocumentMart{
+			MartName:    record.Mart.MartName,
+			MartAddress: record.Mart.MartAddress,
+			Tel:         record.Mart.Tel,
+			BizNum:      record.Mart.BizNum,
+		},
+		Products:   make([]models.DocumentProduct, 0, len(record.Product)),
+		Discount:   record.Discount,
+		TotalPrice: record.TotalPrice,
+		Tax:        record.Tax,
+		Payment:    record.Payment,
+	}
+	if doc.TimeZone == "" {
+		doc.TimeZone = dates.DefaultTimeZone
+	}
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	if ts.HasTime {
+		doc.Time = ts.Local().Format("2006-01-02 15:04:05")
+	} else {
+		doc.Time = ts.Date()
+	}
+	for _, p := range record.Product {
+		doc.Products = append(doc.Products, models.DocumentProduct{
+			Pname:    p.Pname,
+			Price:    p.Price,
+			Amount:   p.Amount,
+			Discount: p.Discount,
+			Category: p.Category,
+		})
+	}
+	return doc
+}
+
+// PatchRecord changes any fields of a record in one update. The body is an RFC 7396
+// merge patch (application/merge-patch+json) or an RFC 6902 JSON Patch
+// (application/json-patch+json) against models.RecordDocument; with plain
+// application/json an array is read as a JSON Patch and an object as a merge patch.
+func PatchRecord(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(body) > maxPatchSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Patch is too large"})
+		return
+	}
+
+	apply := patch.Merge
+	switch c.ContentType() {
+	case models.MergePatchType:
+	case models.JSONPatchType:
+		apply = patch.Apply
+	case "application/json", "":
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+			apply = patch.Apply
+		}
+	default:
+		c.Header("Accept-Patch", models.MergePatchType+", "+models.JSONPatchType)
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported patch format"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	result, err := patchRecord(ctx, account.Uid, access, c.Param("rid"), apply, body)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if !result.Changed {
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Record unchanged",
+			"document": result.Document,
+		})
+		return
+	}
+
+	response := gin.H{
+		"message":  "Record updated successfully",
+		"record":   result.Record,
+		"document": result.Document,
+	}
+	if result.Job != nil {
+		response["job"] = result.Job
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// patchResult is a record after patchRecord
+type patchResult struct {
+	Changed  bool
+	Record   models.RecordInput
+	Document models.RecordDocument
+	// Job recategorizes the other records when a category was set by hand
+	Job *models.CategoryJob
+}
+
+// patchRecord applies body to the document of record rid with apply, validates the
+// result and saves the changed fields in one update. Errors are opErrors; other
+// failures are logged here.
+func patchRecord(ctx context.Context, actor string, access *household.Access, rid string, apply func(doc, patch []byte) ([]byte, error), body []byte) (*patchResult, error) {
+	filter := bson.M{"record.rid": rid, "$or": access.Writable()}
+
+	var before models.RecordInput
+	err := db.Collection.FindOne(ctx, filter).Decode(&before)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, notWritable(ctx, access, rid, "Record not found")
+		}
+		log.Printf("Find error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch record"}
+	}
+
+	current := recordDocument(&before)
+	next, err := patchDocument(current, apply, body)
+	if err != nil {
+		return nil, err
+	}
+
+	set, unset := bson.M{}, bson.M{}
+
+	if next.Rname != current.Rname {
+		set["record.rname"] = next.Rname
+	}
+
+	if next.Time != current.Time || next.TimeZone != current.TimeZone {
+		if !dates.ValidTimeZone(next.TimeZone) {
+			return nil, &opError{http.StatusBadRequest, "Unknown time zone"}
+		}
+		timeStamp, err := models.ParsePurchaseTime(next.Time, dates.LoadLocation(next.TimeZone))
+		if err != nil {
+			return nil, &opError{http.StatusBadRequest, "Invalid time: " + err.Error()}
+		}
+		set["record.timeStamp"] = timeStamp
+	}
+
+	if next.Note != current.Note {
+		if next.Note == "" {
+			unset["record.note"] = ""
+		} else {
+			set["record.note"] = next.Note
+		}
+	}
+
+	var newTags []string
+	if !reflect.DeepEqual(next.Tags, current.Tags) {
+		tags, ok := normalizeTags(next.Tags)
+		if !ok {
+			return nil, &opError{http.StatusBadRequest, "Tags must be 1 to 30 characters long"}
+		}
+		if len(tags) == 0 {
+			unset["record.tags"] = ""
+		} else {
+			set["record.tags"] = tags
+			newTags = tags
+		}
+	}
+
+	if next.Currency != current.Currency {
+		if !currency.Valid(next.Currency) {
+			return nil, &opError{http.StatusBadRequest, "Unsupported currency"}
+		}
+		set["currency"] = currency.Normalize(next.Currency)
+	}
+
+	if next.Mart != current.Mart {
+		mart := models.DBMart{
+			MartName:    next.Mart.MartName,
+			MartAddress: next.Mart.MartAddress,
+			Tel:         next.Mart.Tel,
+			BizNum:      next.Mart.BizNum,
+		}
+		// 바뀐 매장을 레코드 주인의 매장 목록에서 다시 찾음
+		if resolved, err := marts.Resolve(ctx, before.Uid, mart); err != nil {
+			log.Printf("Mart error: %v\n", err)
+		} else {
+			mart.MartId = resolved.MartId
+		}
+		set["mart"] = mart
+	}
+
+	productsChanged := !reflect.DeepEqual(next.Products, current.Products)
+	var learned []models.DocumentProduct
+	if productsChanged {
+		products, changed := patchProducts(ctx, actor, before.Product, next.Products)
+		set["product"] = products
+		learned = changed
+
+		// 나눈 품목은 위치로 가리키므로 바뀐 상품 목록에 맞춰 옮김
+		if before.Split != nil {
+			remapped, err := split.Remap(before.Split, before.Product, products)
+			if err != nil {
+				return nil, &opError{http.StatusConflict, err.Error() + ", change the split first"}
+			}
+			set["split.items"] = remapped.Items
+		}
+	}
+
+	if next.Discount != current.Discount {
+		set["discount"] = next.Discount
+	}
+
+	if !reflect.DeepEqual(next.Tax, current.Tax) {
+		if next.Tax == nil {
+			unset["tax"] = ""
+		} else {
+			set["tax"] = next.Tax
+		}
+	}
+
+	if !reflect.DeepEqual(next.Payment, current.Payment) {
+		if next.Payment == nil {
+			unset["payment"] = ""
+		} else {
+			if next.Payment.Method != models.PaymentMethodCard && next.Payment.Method != models.PaymentMethodCash {
+				return nil, &opError{http.StatusBadRequest, "payment method must be card or cash"}
+			}
+			set["payment"] = maskPayment(next.Payment)
+		}
+	}
+
+	// 합계를 직접 고치지 않았으면 상품과 할인으로 다시 계산
+	if next.TotalPrice != current.TotalPrice {
+		set["totalPrice"] = next.TotalPrice
+	} else if productsChanged || next.Discount != current.Discount {
+		total := 0
+		for _, p := range next.Products {
+			total += p.Price*p.Amount - p.Discount
+		}
+		set["totalPrice"] = total - next.Discount
+	}
+
+	if len(set) == 0 && len(unset) == 0 {
+		return &patchResult{Record: before, Document: current}, nil
+	}
+
+	if len(newTags) > 0 {
+		if err := addToCatalogue(ctx, actor, newTags); err != nil {
+			log.Printf("Tag error: %v\n", err)
+			return nil, &opError{http.StatusInternalServerError, "Failed to update tag catalogue"}
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	updated, err := updateWithHistory(ctx, actor, &before, filter, update)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to update record"}
+	}
+	if updated.MatchedCount == 0 {
+		return nil, &opError{http.StatusNotFound, "Record not found"}
+	}
+
+	// 직접 고친 카테고리는 학습해서 같은 상품에 다시 적용
+	for _, p := range learned {
+		if err := learnCategory(ctx, actor, p.Pname, p.Category); err != nil {
+			log.Printf("Learn error: %v\n", err)
+		}
+	}
+
+	var after models.RecordInput
+	if err := db.Collection.FindOne(ctx, bson.M{"record.rid": rid}).Decode(&after); err != nil {
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch updated record"}
+	}
+
+	result := &patchResult{Changed: true, Record: after, Document: recordDocument(&after)}
+	if len(learned) > 0 {
+		result.Job = startRecategorize(actor)
+	}
+	return result, nil
+}
+
+// patchDocument applies body to current with apply and reads the result back as a
+// record document, rejecting fields the document does not have and values its schema
+// does not allow. Errors are opErrors.
+func patchDocument(current models.RecordDocument, apply func(doc, patch []byte) ([]byte, error), body []byte) (models.RecordDocument, error) {
+	var next models.RecordDocument
+	original, err := json.Marshal(current)
+	if err != nil {
+		log.Printf("Marshal error: %v\n", err)
+		return next, &opError{http.StatusInternalServerError, "Failed to read record"}
+	}
+
+	patched, err := apply(original, body)
+	if err != nil {
+		if errors.Is(err, patch.ErrTestFailed) {
+			return next, &opError{http.StatusConflict, err.Error()}
+		}
+		return next, &opError{http.StatusBadRequest, err.Error()}
+	}
+
+	// 패치 결과를 레코드 스키마로 검증
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&next); err != nil {
+		return next, &opError{http.StatusBadRequest, "Invalid record: " + err.Error()}
+	}
+	if err := binding.Validator.ValidateStruct(&next); err != nil {
+		return next, &opError{http.StatusBadRequest, "Invalid record: " + err.Error()}
+	}
+
+	if next.Tags == nil {
+		next.Tags = []string{}
+	}
+	return next, nil
+}
+
+// patchProducts builds the products of a patched record. A category left as it was
+// keeps its source, a new category counts as set by hand and is returned to be
+// learned, and products without a category are categorized by the user's rules.
+func patchProducts(ctx context.Context, uid string, old []models.DBProduct, next []models.DocumentProduct) ([]models.DBProduct, []models.DocumentProduct) {
+	products := make([]models.DBProduct, 0, len(next))
+	var learned []models.DocumentProduct
+	var uncategorized []int
+
+	for i, p := range next {
+		product := models.DBProduct{
+			Pname:    p.Pname,
+			NormName: normalize.ProductName(p.Pname),
+			Price:    p.Price,
+			Amount:   p.Amount,
+			Discount: p.Discount,
+			Category: p.Category,
+		}
+
+		if p.Category == "" {
+			uncategorized = append(uncategorized, i)
+		} else {
+			product.CategorySource = models.CategorySourceManual
+			kept := false
+			for _, o := range old {
+				if o.Pname == p.Pname && o.Category == p.Category {
+					product.CategorySource = o.CategorySource
+					kept = true
+					break
+				}
+			}
+			if !kept {
+				learned = append(learned, p)
+			}
+		}
+		products = append(products, product)
+	}
+
+	if len(uncategorized) > 0 {
+		categorizer, err := category.Load(ctx, uid)
+		if err != nil {
+			log.Printf("Category error: %v\n", err)
+			categorizer = category.New(nil)
+		}
+		for _, i := range uncategorized {
+			categorizer.Apply(products[i : i+1])
+		}
+	}
+	return products, learned
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"dbserver/dates"
+	"dbserver/models"
+	"dbserver/patch"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func patchFixture() models.RecordDocument {
+	at := time.Date(2026, 3, 14, 18, 30, 0, 0, dates.LoadLocation("Asia/Seoul"))
+	record := models.RecordInput{
+		Record: models.DBRecord{
+			Rid:       "r1",
+			Rname:     "2026-03-14 마트",
+			TimeStamp: models.NewPurchaseTime(at, true),
+			Note:      "주말 장보기",
+			Tags:      []string{"식비"},
+		},
+		Mart: models.DBMart{MartName: "마트"},
+		Product: []models.DBProduct{
+			{Pname: "우유", Price: 2500, Amount: 2, Category: "유제품"},
+			{Pname: "식빵", Price: 3200, Amount: 1},
+		},
+		TotalPrice: 8200,
+		Tax:        &models.TaxInfo{Supply: 7455, Vat: 745},
+	}
+	return recordDocument(&record)
+}
+
+func TestPatchDocument(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(doc, patch []byte) ([]byte, error)
+		body  string
+		check func(t *testing.T, doc models.RecordDocument)
+	}{
+		{
+			name:  "remove note",
+			apply: patch.Apply,
+			body:  `[{"op":"remove","path":"/note"}]`,
+			check: func(t *testing.T, doc models.RecordDocument) {
+				if doc.Note != "" {
+					t.Errorf("note = %q, want empty", doc.Note)
+				}
+			},
+		},
+		{
+			name:  "remove tax",
+			apply: patch.Apply,
+			body:  `[{"op":"remove","path":"/tax"}]`,
+			check: func(t *testing.T, doc models.RecordDocument) {
+				if doc.Tax != nil {
+					t.Errorf("tax = %+v, want nil", doc.Tax)
+				}
+			},
+		},
+		{
+			name:  "replace product price",
+			apply: patch.Apply,
+			body:  `[{"op":"replace","path":"/products/1/price","value":3000}]`,
+			check: func(t *testing.T, doc models.RecordDocument) {
+				if doc.Products[1].Price != 3000 || doc.Products[0].Price != 2500 {
+					t.Errorf("products = %+v", doc.Products)
+				}
+			},
+		},
+		{
+			name:  "test then replace",
+			apply: patch.Apply,
+			body:  `[{"op":"test","path":"/rname","value":"2026-03-14 마트"},{"op":"replace","path":"/rname","value":"장보기"}]`,
+			check: func(t *testing.T, doc models.RecordDocument) {
+				if doc.Rname != "장보기" {
+					t.Errorf("rname = %q, want 장보기", doc.Rname)
+				}
+			},
+		},
+		{
+			name:  "remove last tag",
+			apply: patch.Apply,
+			body:  `[{"op":"remove","path":"/tags/0"}]`,
+			check: func(t *testing.T, doc models.RecordDocument) {
+				if doc.Tags == nil || len(doc.Tags) != 0 {
+					t.Errorf("tags = %#v, want empty", doc.Tags)
+				}
+			},
+		},
+		{
+			name:  "merge patch",
+			apply: patch.Merge,
+			body:  `{"note":null,"mart":{"tel":"02-123-4567"}}`,
+			check: func(t *testing.T, doc models.RecordDocument) {
+				if doc.Note != "" || doc.Mart.Tel != "02-123-4567" || doc.Mart.MartName != "마트" {
+					t.Errorf("note = %q, mart = %+v", doc.Note, doc.Mart)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := patchDocument(patchFixture(), tt.apply, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("patchDocument() error = %v", err)
+			}
+			tt.check(t, doc)
+		})
+	}
+}
+
+func TestPatchDocumentRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(doc, patch []byte) ([]byte, error)
+		body  string
+		code  int
+	}{
+		{"failed test", patch.Apply, `[{"op":"test","path":"/totalPrice","value":1},{"op":"replace","path":"/rname","value":"x"}]`, http.StatusConflict},
+		{"unknown field", patch.Apply, `[{"op":"add","path":"/color","value":"red"}]`, http.StatusBadRequest},
+		{"unknown product field", patch.Merge, `{"products":[{"pname":"우유","price":2500,"amount":1,"sku":"880"}]}`, http.StatusBadRequest},
+		{"required field removed", patch.Apply, `[{"op":"remove","path":"/rname"}]`, http.StatusBadRequest},
+		{"no products", patch.Merge, `{"products":[]}`, http.StatusBadRequest},
+		{"missing path", patch.Apply, `[{"op":"remove","path":"/products/5"}]`, http.StatusBadRequest},
+		{"wrong type", patch.Merge, `{"totalPrice":"8200"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patchDocument(patchFixture(), tt.apply, []byte(tt.body))
+			var opErr *opError
+			if !errors.As(err, &opErr) {
+				t.Fatalf("patchDocument() error = %v, want an opError", err)
+			}
+			if opErr.code != tt.code {
+				t.Errorf("status = %d (%s), want %d", opErr.code, opErr.message, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"dbserver/analytics"
+	jwt "dbserver/auth"
+	"dbserver/normalize"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetProductPrices(c *gin.Context) {
+	normName := normalize.ProductName(c.Param("name"))
+	if normName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product name"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	filter, err := recordFilter(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prices, err := analytics.PriceHistory(ctx, filter, normName, homeCurrency(ctx, account.Uid), loc)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
+	}
+
+	if len(prices.Purchases) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prices": prices})
+}
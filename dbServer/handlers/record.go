@@ -3,15 +3,27 @@ package handlers
 import (
 	"context"
 	jwt "dbserver/auth"
+	"dbserver/category"
+	"dbserver/currency"
+	"dbserver/dates"
 	"dbserver/db"
+	"dbserver/fingerprint"
+	"dbserver/history"
+	"dbserver/household"
+	"dbserver/marts"
 	"dbserver/models"
+	"dbserver/normalize"
+	"dbserver/reconcile"
+	"dbserver/shopping"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func SearchByUid(c *gin.Context) {
@@ -42,8 +54,20 @@ func SearchByUid(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Find all documents for the user
-	cursor, err := db.Collection.Find(ctx, bson.M{"uid": uid, "record.rid": bson.M{"$exists": true}})
+	access, ok := loadAccess(c, ctx, uid)
+	if !ok {
+		return
+	}
+
+	filter, err := recordFilter(c, access, userLocation(ctx, uid))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Find all documents for the user, newest purchase first
+	opts := options.Find().SetSort(bson.D{{Key: "record.timeStamp.at", Value: -1}})
+	cursor, err := db.Collection.Find(ctx, filter, opts)
 	if err != nil {
 		log.Printf("Find error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
@@ -70,12 +94,23 @@ func SearchByUid(c *gin.Context) {
 func GetRecordInfo(c *gin.Context) {
 	rid := c.Param("rid")
 
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
 	// Find the record with the given rid
 	var record models.DBRequest
-	err := db.Collection.FindOne(ctx, bson.M{"record.rid": rid}).Decode(&record)
+	err = db.Collection.FindOne(ctx, bson.M{"record.rid": rid, "$or": access.Readable()}).Decode(&record)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			log.Printf("Record not found: %s\n", rid)
@@ -97,12 +132,23 @@ func GetProductInfo(c *gin.Context) {
 		return
 	}
 
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
 	// Find the record with the given rid
 	var record models.DBProduct
-	err := db.Collection.FindOne(ctx, bson.M{"record.rid": req.Rid, "product.pname": req.Pname}).Decode(&record)
+	err = db.Collection.FindOne(ctx, bson.M{"record.rid": req.Rid, "product.pname": req.Pname, "$or": access.Readable()}).Decode(&record)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Record or product not found"})
@@ -122,21 +168,33 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
 	// First check if the record and product exist
 	filter := bson.M{
 		"record.rid":    req.Rid,
 		"product.pname": req.Pname,
+		"$or":           access.Writable(),
 	}
 
-	var existingRecord models.DBRequest
-	err := db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			log.Printf("Record or product not found: %v\n", err)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Record or product not found"})
+			recordNotWritable(c, ctx, access, req.Rid, "Record or product not found")
 		} else {
 			log.Printf("Find error: %v\n", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
@@ -147,22 +205,28 @@ func UpdateProduct(c *gin.Context) {
 	totalPrice := 0
 	for _, product := range existingRecord.Product {
 		if product.Pname == req.Pname {
-			totalPrice += (req.NewPrice * req.NewAmount)
-		} else {
-			totalPrice += (product.Price * product.Amount)
+			product.Price = req.NewPrice
+			product.Amount = req.NewAmount
+			if req.NewDiscount != nil {
+				product.Discount = *req.NewDiscount
+			}
 		}
+		totalPrice += product.LineTotal()
 	}
+	totalPrice -= existingRecord.Discount
 
 	// Update the price of the specific product
-	update := bson.M{
-		"$set": bson.M{
-			"product.$.price":  req.NewPrice,
-			"product.$.amount": req.NewAmount,
-			"totalPrice":       totalPrice,
-		},
+	set := bson.M{
+		"product.$.price":  req.NewPrice,
+		"product.$.amount": req.NewAmount,
+		"totalPrice":       totalPrice,
 	}
+	if req.NewDiscount != nil {
+		set["product.$.discount"] = *req.NewDiscount
+	}
+	update := bson.M{"$set": set}
 
-	result, err := db.Collection.UpdateOne(ctx, filter, update)
+	result, err := updateWithHistory(ctx, account.Uid, &existingRecord, filter, update)
 	if err != nil {
 		log.Printf("Update error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
@@ -195,35 +259,59 @@ func UpdateMart(c *gin.Context) {
 		return
 	}
 
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
 	// First check if the record and product exist
 	filter := bson.M{
 		"record.rid": req.Rid,
+		"$or":        access.Writable(),
 	}
 
-	var existingRecord models.DBRequest
-	err := db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			recordNotWritable(c, ctx, access, req.Rid, "Record not found")
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
 		}
 		return
 	}
 
-	// Update the price of the specific product
-	update := bson.M{
-		"$set": bson.M{
-			"mart.martAddress": req.NewMartAddr,
-			"mart.martName":    req.NewMartName,
-			"mart.tel":         req.NewMartTel,
-		},
+	set := bson.M{
+		"mart.martAddress": req.NewMartAddr,
+		"mart.martName":    req.NewMartName,
+		"mart.tel":         req.NewMartTel,
+	}
+
+	// 바뀐 매장을 레코드 주인의 매장 목록에서 다시 찾음
+	mart, err := marts.Resolve(ctx, existingRecord.Uid, models.DBMart{
+		MartName:    req.NewMartName,
+		MartAddress: req.NewMartAddr,
+		Tel:         req.NewMartTel,
+		BizNum:      existingRecord.Mart.BizNum,
+	})
+	if err != nil {
+		log.Printf("Mart error: %v\n", err)
+	} else {
+		set["mart.martId"] = mart.MartId
 	}
 
-	result, err := db.Collection.UpdateOne(ctx, filter, update)
+	update := bson.M{"$set": set}
+
+	result, err := updateWithHistory(ctx, account.Uid, &existingRecord, filter, update)
 	if err != nil {
 		log.Printf("Update error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mart"})
@@ -249,6 +337,110 @@ func UpdateMart(c *gin.Context) {
 	})
 }
 
+// UpdatePayment edits the receipt-level discount, tax breakdown and payment method
+func UpdatePayment(c *gin.Context) {
+	var req models.UpdatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Payment != nil && req.Payment.Method != models.PaymentMethodCard && req.Payment.Method != models.PaymentMethodCash {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment method must be card or cash"})
+		return
+	}
+	if req.Tax != nil && (req.Tax.Supply < 0 || req.Tax.Vat < 0 || req.Tax.TaxFree < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tax amounts must not be negative"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{
+		"record.rid": req.Rid,
+		"$or":        access.Writable(),
+	}
+
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			recordNotWritable(c, ctx, access, req.Rid, "Record not found")
+		} else {
+			log.Printf("Find error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		}
+		return
+	}
+
+	set := bson.M{}
+	if req.Discount != nil {
+		// 영수증 할인이 바뀐 만큼 합계도 조정
+		set["discount"] = *req.Discount
+		set["totalPrice"] = existingRecord.TotalPrice + existingRecord.Discount - *req.Discount
+	}
+	if req.Tax != nil {
+		set["tax"] = req.Tax
+	}
+	if req.Payment != nil {
+		set["payment"] = maskPayment(req.Payment)
+	}
+	if len(set) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	result, err := updateWithHistory(ctx, account.Uid, &existingRecord, filter, bson.M{"$set": set})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+
+	var updatedRecord models.DBRequest
+	err = db.Collection.FindOne(ctx, bson.M{"record.rid": req.Rid}).Decode(&updatedRecord)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment updated successfully",
+		"record":  updatedRecord,
+	})
+}
+
+// maskPayment returns a copy of payment with the card number masked, so a full
+// card number never reaches the database
+func maskPayment(payment *models.PaymentInfo) *models.PaymentInfo {
+	if payment == nil {
+		return nil
+	}
+	masked := *payment
+	masked.CardNumber = normalize.CardNumber(masked.CardNumber)
+	if masked.Method == models.PaymentMethodCash {
+		masked.CardCompany = ""
+		masked.CardNumber = ""
+	}
+	return &masked
+}
+
 func UpdateRecord(c *gin.Context) {
 	var req models.UpdateRecordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -256,33 +448,66 @@ func UpdateRecord(c *gin.Context) {
 		return
 	}
 
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
 	// First check if the record and product exist
 	filter := bson.M{
 		"record.rid": req.Rid,
+		"$or":        access.Writable(),
 	}
 
-	var existingRecord models.DBRequest
-	err := db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			recordNotWritable(c, ctx, access, req.Rid, "Record not found")
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
 		}
 		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"record.rname":     req.NewRname,
-			"record.timeStamp": req.NewTime,
-		},
+	loc := userLocation(ctx, account.Uid)
+	if req.NewTimeZone != "" {
+		if !dates.ValidTimeZone(req.NewTimeZone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone"})
+			return
+		}
+		loc = dates.LoadLocation(req.NewTimeZone)
 	}
 
-	result, err := db.Collection.UpdateOne(ctx, filter, update)
+	timeStamp, err := models.ParsePurchaseTime(req.NewTime, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time: " + err.Error()})
+		return
+	}
+
+	set := bson.M{
+		"record.rname":     req.NewRname,
+		"record.timeStamp": timeStamp,
+	}
+	if req.NewCurrency != "" {
+		if !currency.Valid(req.NewCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
+		set["currency"] = currency.Normalize(req.NewCurrency)
+	}
+	update := bson.M{"$set": set}
+
+	result, err := updateWithHistory(ctx, account.Uid, &existingRecord, filter, update)
 	if err != nil {
 		log.Printf("Update error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update record"})
@@ -307,3 +532,252 @@ func UpdateRecord(c *gin.Context) {
 		"record":  updatedRecord,
 	})
 }
+
+// updateWithHistory applies update to the record matched by filter and stores the new
+// version in its history in one transaction, so a change is never applied without its
+// version. Inside a transaction already it joins that one. No version is stored when
+// the update changed nothing.
+func updateWithHistory(ctx context.Context, actor string, before *models.RecordInput, filter bson.M, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	var result *mongo.UpdateResult
+	apply := func(ctx context.Context) error {
+		var err error
+		result, err = db.Collection.UpdateOne(ctx, filter, update, opts...)
+		if err != nil || result.ModifiedCount == 0 {
+			return err
+		}
+		return saveHistory(ctx, actor, before, before.Record.Rid)
+	}
+
+	if mongo.SessionFromContext(ctx) != nil {
+		return result, apply(ctx)
+	}
+	err := db.Transaction(ctx, func(sc mongo.SessionContext) error {
+		return apply(sc)
+	})
+	return result, err
+}
+
+// saveHistory stores the state of the record after a mutation as a new history version.
+// The fingerprint and the reconciliation issues are recomputed first since the edit may
+// have changed them.
+func saveHistory(ctx context.Context, actor string, before *models.RecordInput, rid string) error {
+	var after models.RecordInput
+	if err := db.Collection.FindOne(ctx, bson.M{"record.rid": rid}).Decode(&after); err != nil {
+		return err
+	}
+
+	if err := refreshChecks(ctx, &after); err != nil {
+		return err
+	}
+
+	_, err := history.Record(ctx, actor, models.HistoryActionUpdate, before, after)
+	return err
+}
+
+// refreshChecks recomputes the values derived from the contents of a record, the
+// duplicate fingerprint and the reconciliation issues, and saves those that changed
+func refreshChecks(ctx context.Context, record *models.RecordInput) error {
+	set, unset := bson.M{}, bson.M{}
+	if fp := fingerprint.Of(*record); fp != record.Record.Fingerprint {
+		set["record.fingerprint"] = fp
+		record.Record.Fingerprint = fp
+	}
+	if issues := reconcile.Check(*record, record.Issues, time.Now()); !reconcile.Equal(issues, record.Issues) {
+		if len(issues) == 0 {
+			unset["issues"] = ""
+		} else {
+			set["issues"] = issues
+		}
+		record.Issues = issues
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	_, err := db.Collection.UpdateOne(ctx, bson.M{"record.rid": record.Record.Rid}, update)
+	return err
+}
+
+// updateRecords applies update to every record matched by filter one at a time so each
+// change is stored in the record's history. It returns the number of records changed.
+func updateRecords(ctx context.Context, actor string, filter bson.M, update interface{}) (int, error) {
+	cursor, err := db.Collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.RecordInput
+	if err := cursor.All(ctx, &records); err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for i := range records {
+		before := &records[i]
+		result, err := updateWithHistory(ctx, actor, before, bson.M{"record.rid": before.Record.Rid}, update)
+		if err != nil {
+			return updated, err
+		}
+		if result.ModifiedCount > 0 {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func CreateRecord(c *gin.Context) {
+	var req models.CreateRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Payment != nil && req.Payment.Method != models.PaymentMethodCard && req.Payment.Method != models.PaymentMethodCash {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment method must be card or cash"})
+		return
+	}
+	if !currency.Valid(req.Currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if req.GroupId != "" {
+		access, ok := loadAccess(c, ctx, account.Uid)
+		if !ok {
+			return
+		}
+		if !household.AtLeast(access.Role(req.GroupId), models.HouseholdRoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only household editors can add records"})
+			return
+		}
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	if req.TimeZone != "" {
+		if !dates.ValidTimeZone(req.TimeZone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone"})
+			return
+		}
+		loc = dates.LoadLocation(req.TimeZone)
+	}
+
+	timeStamp, err := models.ParsePurchaseTime(req.Time, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time: " + err.Error()})
+		return
+	}
+
+	products := make([]models.DBProduct, 0, len(req.Products))
+	totalPrice := 0
+	for _, p := range req.Products {
+		products = append(products, models.DBProduct{
+			Pname:    p.Pname,
+			NormName: normalize.ProductName(p.Pname),
+			Price:    p.Price,
+			Amount:   p.Amount,
+			Discount: p.Discount,
+		})
+		totalPrice += products[len(products)-1].LineTotal()
+	}
+	totalPrice -= req.Discount
+	if req.TotalPrice != nil {
+		totalPrice = *req.TotalPrice
+	}
+
+	categorizer, err := category.Load(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Category error: %v\n", err)
+		categorizer = category.New(nil)
+	}
+	categorizer.Apply(products)
+
+	rname := req.Rname
+	if rname == "" {
+		rname = timeStamp.Date() + req.Mart.MartName
+	}
+
+	record := models.RecordInput{
+		Uid:     account.Uid,
+		GroupId: req.GroupId,
+		Record: models.DBRecord{
+			Rid:       uuid.NewString(),
+			Rname:     rname,
+			TimeStamp: timeStamp,
+		},
+		Mart: models.DBMart{
+			MartName:    req.Mart.MartName,
+			MartAddress: req.Mart.MartAddress,
+			Tel:         req.Mart.Tel,
+			BizNum:      req.Mart.BizNum,
+		},
+		Product:    products,
+		Currency:   currency.Normalize(req.Currency),
+		TotalPrice: totalPrice,
+		Discount:   req.Discount,
+		Tax:        req.Tax,
+		Payment:    maskPayment(req.Payment),
+	}
+
+	// 매장 목록에 연결
+	if err := marts.Link(ctx, &record); err != nil {
+		log.Printf("Mart error: %v\n", err)
+	}
+
+	// 합계가 맞지 않거나 이상한 항목 표시
+	record.Issues = reconcile.Check(record, nil, time.Now())
+
+	// 같은 영수증이 이미 있으면 중복 후보로 표시
+	record.Record.Fingerprint = fingerprint.Of(record)
+	duplicate, exact, err := fingerprint.FindDuplicate(ctx, record)
+	if err != nil {
+		log.Printf("Duplicate check error: %v\n", err)
+	}
+	if duplicate != nil {
+		record.Record.DuplicateOf = duplicate.Record.Rid
+	}
+
+	if _, err := db.Collection.InsertOne(ctx, record); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save record"})
+		return
+	}
+
+	if _, err := history.Record(ctx, account.Uid, models.HistoryActionCreate, nil, record); err != nil {
+		log.Printf("History error: %v\n", err)
+	}
+
+	alerts := evaluateBudgets(ctx, record)
+
+	// 장보기 목록에서 이번에 산 품목 체크
+	bought, err := shopping.MarkBought(ctx, account.Uid, record)
+	if err != nil {
+		log.Printf("Shopping list error: %v\n", err)
+	}
+
+	response := gin.H{
+		"message": "Record created successfully",
+		"record":  record,
+		"alerts":  alerts,
+		"bought":  bought,
+	}
+	if duplicate != nil {
+		response["duplicate"] = gin.H{"rid": duplicate.Record.Rid, "exact": exact}
+	}
+	c.JSON(http.StatusCreated, response)
+}
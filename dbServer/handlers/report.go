@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/reports"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMonthlyReport serves the spending report of a month as PDF, e.g.
+// GET /reports/2024-05.pdf. group narrows it to the personal ledger or a household
+// like the analytics routes. The report is only rendered again after the records it
+// covers have changed; its hash is sent as the ETag.
+func GetMonthlyReport(c *gin.Context) {
+	month, ok := strings.CutSuffix(c.Param("month"), ".pdf")
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	period, err := reports.ParseMonth(month, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if period.From.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Month has not started yet"})
+		return
+	}
+	group := c.Query("group")
+	if _, err := access.Scope(group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pdf, hash, err := reports.Generate(ctx, reports.Request{
+		Access: access,
+		Group:  group,
+		Month:  month,
+		Home:   homeCurrency(ctx, account.Uid),
+		Loc:    loc,
+	})
+	if errors.Is(err, reports.ErrNoFont) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Report font is not installed"})
+		return
+	}
+	if err != nil {
+		log.Printf("Report error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report"})
+		return
+	}
+
+	etag := `"` + hash + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", "report-"+month+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/normalize"
+	"dbserver/recurring"
+	"dbserver/shopping"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// 반복 구매 분석에 사용하는 기본 기간(일)
+	defaultRecurringDays = 365
+	// 장보기 목록에 제안하는 기본 기간(일)
+	defaultSuggestWithin = 3
+)
+
+// openItems returns the shopping list items of uid that are not ticked off
+func openItems(ctx context.Context, uid string) ([]models.ShoppingItem, error) {
+	cursor, err := db.ShoppingCollection.Find(ctx, bson.M{"uid": uid, "checked": false})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.ShoppingItem
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+// markOnList sets OnList of the recurring items that are already on the list
+func markOnList(items []models.RecurringItem, open []models.ShoppingItem) {
+	for i := range items {
+		for _, item := range open {
+			if shopping.Matches(item.NormName, items[i].NormName) {
+				items[i].OnList = true
+				break
+			}
+		}
+	}
+}
+
+// GetRecurringPurchases lists the products bought at a regular interval with the
+// date each is due next. Without from/to the last ?days= (default a year) are used.
+func GetRecurringPurchases(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultRecurringDays)))
+	if err != nil || days < 14 || days > 3650 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 14 and 3650"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	filter, err := recordFilter(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	if _, ok := filter["record.timeStamp.at"]; !ok {
+		filter["record.timeStamp.at"] = bson.M{"$gte": now.AddDate(0, 0, -days)}
+	}
+
+	items, err := recurring.Detect(ctx, filter, loc, now)
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect recurring purchases"})
+		return
+	}
+
+	open, err := openItems(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shopping list"})
+		return
+	}
+	markOnList(items, open)
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// GetShoppingList returns the shopping list, open items first, with the recurring
+// purchases due within ?within= days (default 3) that are not on it yet
+func GetShoppingList(c *gin.Context) {
+	within, err := strconv.Atoi(c.DefaultQuery("within", strconv.Itoa(defaultSuggestWithin)))
+	if err != nil || within < 0 || within > 60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "within must be between 0 and 60"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"uid": account.Uid}
+	if value := c.Query("checked"); value != "" {
+		checked, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "checked must be true or false"})
+			return
+		}
+		filter["checked"] = checked
+	}
+
+	cursor, err := db.ShoppingCollection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "checked", Value: 1}, {Key: "addedAt", Value: 1}}))
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shopping list"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.ShoppingItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shopping list"})
+		return
+	}
+
+	open, err := openItems(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shopping list"})
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	detected, err := recurring.Detect(ctx, recurringScope(account.Uid), loc, time.Now())
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect recurring purchases"})
+		return
+	}
+	markOnList(detected, open)
+
+	suggestions := []models.RecurringItem{}
+	for _, item := range recurring.Due(detected, within) {
+		if !item.OnList {
+			suggestions = append(suggestions, item)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":       items,
+		"suggestions": suggestions,
+	})
+}
+
+// recurringScope matches the personal records of uid of the last year, which is
+// what the shopping list suggestions are based on
+func recurringScope(uid string) bson.M {
+	return bson.M{
+		"uid":                 uid,
+		"groupId":             bson.M{"$exists": false},
+		"record.rid":          bson.M{"$exists": true},
+		"record.timeStamp.at": bson.M{"$gte": time.Now().AddDate(0, 0, -defaultRecurringDays)},
+	}
+}
+
+// AddShoppingItem adds an item by hand. Adding an item that is already open on the
+// list raises its quantity instead.
+func AddShoppingItem(c *gin.Context) {
+	var req models.ShoppingItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	normName := normalize.ProductName(req.Name)
+	if normName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must contain letters or digits"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, created, err := addShoppingItem(ctx, account.Uid, req.Name, normName, req.Quantity, req.Note, models.ShoppingSourceManual)
+	if err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add shopping item"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{"item": item})
+}
+
+func addShoppingItem(ctx context.Context, uid, name, normName string, quantity int, note, source string) (*models.ShoppingItem, bool, error) {
+	var existing models.ShoppingItem
+	err := db.ShoppingCollection.FindOneAndUpdate(ctx,
+		bson.M{"uid": uid, "normName": normName, "checked": false},
+		bson.M{"$inc": bson.M{"quantity": quantity}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&existing)
+	if err == nil {
+		return &existing, false, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, false, err
+	}
+
+	item := models.ShoppingItem{
+		ItemId:   uuid.NewString(),
+		Uid:      uid,
+		Name:     name,
+		NormName: normName,
+		Quantity: quantity,
+		Note:     note,
+		Source:   source,
+		AddedAt:  time.Now(),
+	}
+	if _, err := db.ShoppingCollection.InsertOne(ctx, item); err != nil {
+		return nil, false, err
+	}
+	return &item, true, nil
+}
+
+// AddShoppingSuggestions puts the recurring purchases that are due on the list
+func AddShoppingSuggestions(c *gin.Context) {
+	req := models.AddSuggestionsRequest{Within: defaultSuggestWithin}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	open, err := openItems(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shopping list"})
+		return
+	}
+
+	detected, err := recurring.Detect(ctx, recurringScope(account.Uid), userLocation(ctx, account.Uid), time.Now())
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect recurring purchases"})
+		return
+	}
+	markOnList(detected, open)
+
+	wanted := map[string]bool{}
+	for _, name := range req.NormNames {
+		wanted[name] = true
+	}
+
+	added := []models.ShoppingItem{}
+	for _, suggestion := range recurring.Due(detected, req.Within) {
+		if suggestion.OnList || (len(wanted) > 0 && !wanted[suggestion.NormName]) {
+			continue
+		}
+		item, _, err := addShoppingItem(ctx, account.Uid, suggestion.Pname, suggestion.NormName, 1, "", models.ShoppingSourceRecurring)
+		if err != nil {
+			log.Printf("Insert error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add shopping items"})
+			return
+		}
+		added = append(added, *item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+// UpdateShoppingItem edits an item or ticks it off
+func UpdateShoppingItem(c *gin.Context) {
+	var req models.UpdateShoppingItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	if req.Name != nil {
+		normName := normalize.ProductName(*req.Name)
+		if normName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must contain letters or digits"})
+			return
+		}
+		set["name"] = *req.Name
+		set["normName"] = normName
+	}
+	if req.Quantity != nil {
+		set["quantity"] = *req.Quantity
+	}
+	if req.Note != nil {
+		set["note"] = *req.Note
+	}
+	if req.Checked != nil {
+		set["checked"] = *req.Checked
+		if *req.Checked {
+			set["checkedAt"] = time.Now()
+		} else {
+			unset["checkedAt"] = ""
+		}
+		// 직접 체크를 바꾸면 영수증 연결은 해제
+		unset["boughtRid"] = ""
+	}
+	if len(set) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var item models.ShoppingItem
+	err = db.ShoppingCollection.FindOneAndUpdate(ctx,
+		bson.M{"itemId": c.Param("itemId"), "uid": account.Uid},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shopping item not found"})
+		} else {
+			log.Printf("Update error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shopping item"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+func DeleteShoppingItem(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.ShoppingCollection.DeleteOne(ctx, bson.M{"itemId": c.Param("itemId"), "uid": account.Uid})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shopping item"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shopping item not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shopping item deleted successfully"})
+}
+
+// ClearCheckedShoppingItems removes the ticked-off items from the list
+func ClearCheckedShoppingItems(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.ShoppingCollection.DeleteMany(ctx, bson.M{"uid": account.Uid, "checked": true})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear shopping list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Checked items cleared successfully",
+		"deleted": result.DeletedCount,
+	})
+}
@@ -0,0 +1,601 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/household"
+	"dbserver/models"
+	"dbserver/split"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ledgerFilter matches documents in the same ledger as a record: the household it
+// belongs to, or the personal ledger of the user who saved it
+func ledgerFilter(uid string, groupId string) bson.M {
+	if groupId != "" {
+		return bson.M{"groupId": groupId}
+	}
+	return bson.M{"uid": uid, "groupId": bson.M{"$exists": false}}
+}
+
+// findPeople returns the people matching filter keyed by id
+func findPeople(ctx context.Context, filter bson.M) (map[string]models.Person, error) {
+	cursor, err := db.PersonCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	people := map[string]models.Person{}
+	for cursor.Next(ctx) {
+		var p models.Person
+		if err := cursor.Decode(&p); err != nil {
+			return nil, err
+		}
+		people[p.PersonId] = p
+	}
+	return people, cursor.Err()
+}
+
+// inHome converts the total of record to home at the rate of its purchase date. The
+// shares of a split follow the total, so the products are left as they are.
+func inHome(ctx context.Context, record *models.RecordInput, home string, loc *time.Location) error {
+	from := currency.Normalize(record.Currency)
+	if from == home {
+		return nil
+	}
+	date := record.Record.TimeStamp.At.In(loc).Format(dates.DateLayout)
+	total, err := currency.Convert(ctx, record.TotalPrice, from, home, date)
+	if err != nil {
+		return err
+	}
+	record.TotalPrice, record.Currency = total, home
+	return nil
+}
+
+// personTotals names the per-person totals of a split record
+func personTotals(record models.RecordInput, people map[string]models.Person) []models.PersonTotal {
+	totals := []models.PersonTotal{}
+	for person, amount := range split.Totals(record) {
+		totals = append(totals, models.PersonTotal{
+			PersonId: person,
+			Name:     people[person].Name,
+			Amount:   amount,
+		})
+	}
+	return totals
+}
+
+func ListPeople(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	scope, err := access.Scope(c.Query("group"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := db.PersonCollection.Find(ctx, bson.M{"$or": scope}, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch people"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	people := []models.Person{}
+	if err := cursor.All(ctx, &people); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode people"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"people": people})
+}
+
+func CreatePerson(c *gin.Context) {
+	var req models.PersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if req.GroupId != "" {
+		access, ok := loadAccess(c, ctx, account.Uid)
+		if !ok {
+			return
+		}
+		if !household.AtLeast(access.Role(req.GroupId), models.HouseholdRoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only household editors can add people"})
+			return
+		}
+	}
+
+	person := models.Person{
+		PersonId:  uuid.NewString(),
+		Uid:       account.Uid,
+		GroupId:   req.GroupId,
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := db.PersonCollection.InsertOne(ctx, person); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add person"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Person added successfully",
+		"person":  person,
+	})
+}
+
+// DeletePerson removes a person who is not part of any split or settlement
+func DeletePerson(c *gin.Context) {
+	personId := c.Param("personId")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	used, err := db.Collection.CountDocuments(ctx, bson.M{"$or": bson.A{
+		bson.M{"split.paidBy": personId},
+		bson.M{"split.items.shares.personId": personId},
+		bson.M{"split.default.personId": personId},
+	}})
+	if err == nil && used == 0 {
+		used, err = db.SettlementCollection.CountDocuments(ctx, bson.M{"$or": bson.A{
+			bson.M{"from": personId},
+			bson.M{"to": personId},
+		}})
+	}
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete person"})
+		return
+	}
+	if used > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Person is part of a split or settlement"})
+		return
+	}
+
+	result, err := db.PersonCollection.DeleteOne(ctx, bson.M{"personId": personId, "$or": access.Writable()})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete person"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Person deleted successfully"})
+}
+
+func GetRecordSplit(c *gin.Context) {
+	rid := c.Param("rid")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	var record models.RecordInput
+	err = db.Collection.FindOne(ctx, bson.M{"record.rid": rid, "$or": access.Readable()}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		return
+	}
+	if record.Split == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record is not split"})
+		return
+	}
+
+	people, err := findPeople(ctx, ledgerFilter(record.Uid, record.GroupId))
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch people"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"split":  record.Split,
+		"totals": personTotals(record, people),
+	})
+}
+
+// UpdateRecordSplit assigns the line items of a record to people
+func UpdateRecordSplit(c *gin.Context) {
+	rid := c.Param("rid")
+
+	var req models.SplitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"record.rid": rid, "$or": access.Writable()}
+	var record models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		recordNotWritable(c, ctx, access, rid, "Record not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		return
+	}
+
+	// 같은 장부에 등록된 사람에게만 나눌 수 있음
+	people, err := findPeople(ctx, ledgerFilter(record.Uid, record.GroupId))
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch people"})
+		return
+	}
+	known := map[string]bool{}
+	for id := range people {
+		known[id] = true
+	}
+
+	s := models.RecordSplit{
+		PaidBy:    req.PaidBy,
+		Items:     req.Items,
+		Default:   req.Default,
+		UpdatedAt: time.Now(),
+	}
+	if s.Items == nil {
+		s.Items = []models.ItemSplit{}
+	}
+	if err := split.Validate(s, len(record.Product), known); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := updateRecords(ctx, account.Uid, filter, bson.M{"$set": bson.M{"split": s}}); err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update split"})
+		return
+	}
+	record.Split = &s
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Split updated successfully",
+		"split":   s,
+		"totals":  personTotals(record, people),
+	})
+}
+
+func DeleteRecordSplit(c *gin.Context) {
+	rid := c.Param("rid")
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"record.rid": rid, "$or": access.Writable(), "split": bson.M{"$exists": true}}
+	updated, err := updateRecords(ctx, account.Uid, filter, bson.M{"$unset": bson.M{"split": ""}})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete split"})
+		return
+	}
+	if updated == 0 {
+		recordNotWritable(c, ctx, access, rid, "Record is not split")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Split deleted successfully"})
+}
+
+// GetBalances returns each person's balance over the split receipts matching the
+// listing filters and every settlement, with the fewest payments that settle up
+func GetBalances(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	loc := userLocation(ctx, account.Uid)
+	filter, err := recordFilter(c, access, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter["split"] = bson.M{"$exists": true}
+
+	// 다른 통화의 영수증은 구매일 환율로 기준 통화에 맞춰 합산
+	home := homeCurrency(ctx, account.Uid)
+	ledger := split.NewLedger(home)
+
+	cursor, err := db.Collection.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			log.Printf("Decode error: %v\n", err)
+			continue
+		}
+		if err := inHome(ctx, &record, home, loc); err != nil {
+			if errors.Is(err, currency.ErrNoRate) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Cannot settle receipts in " + currency.Normalize(record.Currency) + ": " + err.Error()})
+			} else {
+				log.Printf("Convert error: %v\n", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert receipts"})
+			}
+			return
+		}
+		if err := ledger.AddRecord(record); err != nil {
+			log.Printf("Ledger error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute balances"})
+			return
+		}
+	}
+
+	scope, _ := access.Scope(c.Query("group"))
+	settlements, err := db.SettlementCollection.Find(ctx, bson.M{"$or": scope})
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+		return
+	}
+	defer settlements.Close(ctx)
+	for settlements.Next(ctx) {
+		var s models.Settlement
+		if err := settlements.Decode(&s); err != nil {
+			log.Printf("Decode error: %v\n", err)
+			continue
+		}
+		ledger.AddSettlement(s)
+	}
+
+	people, err := findPeople(ctx, bson.M{"$or": access.Readable()})
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch people"})
+		return
+	}
+	names := map[string]string{}
+	for id, p := range people {
+		names[id] = p.Name
+	}
+
+	balances := ledger.Balances(names)
+	c.JSON(http.StatusOK, gin.H{
+		"currency": home,
+		"balances": balances,
+		"payments": split.Simplify(balances),
+	})
+}
+
+func ListSettlements(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	scope, err := access.Scope(c.Query("group"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := db.SettlementCollection.Find(ctx, bson.M{"$or": scope}, opts)
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	settlements := []models.Settlement{}
+	if err := cursor.All(ctx, &settlements); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode settlements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settlements": settlements})
+}
+
+// CreateSettlement records that one person paid another to settle up
+func CreateSettlement(c *gin.Context) {
+	var req models.SettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if req.GroupId != "" {
+		access, ok := loadAccess(c, ctx, account.Uid)
+		if !ok {
+			return
+		}
+		if !household.AtLeast(access.Role(req.GroupId), models.HouseholdRoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only household editors can record settlements"})
+			return
+		}
+	}
+
+	filter := ledgerFilter(account.Uid, req.GroupId)
+	filter["personId"] = bson.M{"$in": bson.A{req.From, req.To}}
+	count, err := db.PersonCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch people"})
+		return
+	}
+	if count != 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown person"})
+		return
+	}
+
+	settlement := models.Settlement{
+		SettlementId: uuid.NewString(),
+		Uid:          account.Uid,
+		GroupId:      req.GroupId,
+		From:         req.From,
+		To:           req.To,
+		Amount:       req.Amount,
+		Note:         req.Note,
+		CreatedAt:    time.Now(),
+	}
+
+	if _, err := db.SettlementCollection.InsertOne(ctx, settlement); err != nil {
+		log.Printf("Insert error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save settlement"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Settlement recorded successfully",
+		"settlement": settlement,
+	})
+}
+
+func DeleteSettlement(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	result, err := db.SettlementCollection.DeleteOne(ctx, bson.M{"settlementId": c.Param("settlementId"), "$or": access.Writable()})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete settlement"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Settlement deleted successfully"})
+}
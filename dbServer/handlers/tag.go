@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/normalize"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// normalizeTags normalizes and de-duplicates tags; ok is false if any tag is invalid
+func normalizeTags(tags []string) ([]string, bool) {
+	seen := map[string]bool{}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name := normalize.Tag(tag)
+		if name == "" {
+			return nil, false
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, true
+}
+
+// addToCatalogue makes sure every tag is in the user's catalogue
+func addToCatalogue(ctx context.Context, uid string, tags []string) error {
+	now := time.Now()
+	for _, name := range tags {
+		_, err := db.TagCollection.UpdateOne(ctx,
+			bson.M{"uid": uid, "name": name},
+			bson.M{"$setOnInsert": models.Tag{Uid: uid, Name: name, CreatedAt: now}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func AddRecordTags(c *gin.Context) {
+	changeRecordTags(c, true)
+}
+
+func RemoveRecordTags(c *gin.Context) {
+	changeRecordTags(c, false)
+}
+
+func changeRecordTags(c *gin.Context, add bool) {
+	var req models.RecordTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, ok := normalizeTags(req.Tags)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tags must be 1 to 30 characters long"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{
+		"record.rid": bson.M{"$in": req.Rids},
+		"$or":        access.Writable(),
+	}
+
+	matched, err := db.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+	if matched == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+
+	var update bson.M
+	if add {
+		if err := addToCatalogue(ctx, account.Uid, tags); err != nil {
+			log.Printf("Tag error: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag catalogue"})
+			return
+		}
+		update = bson.M{"$addToSet": bson.M{"record.tags": bson.M{"$each": tags}}}
+	} else {
+		update = bson.M{"$pull": bson.M{"record.tags": bson.M{"$in": tags}}}
+	}
+
+	updated, err := updateRecords(ctx, account.Uid, filter, update)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags updated successfully",
+		"matched": matched,
+		"updated": updated,
+		"tags":    tags,
+	})
+}
+
+func UpdateRecordNote(c *gin.Context) {
+	rid := c.Param("rid")
+
+	var req models.UpdateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	access, ok := loadAccess(c, ctx, account.Uid)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{
+		"record.rid": rid,
+		"$or":        access.Writable(),
+	}
+
+	var existingRecord models.RecordInput
+	err = db.Collection.FindOne(ctx, filter).Decode(&existingRecord)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			recordNotWritable(c, ctx, access, rid, "Record not found")
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch record"})
+		}
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"record.note": req.Note}}
+	if req.Note == "" {
+		update = bson.M{"$unset": bson.M{"record.note": ""}}
+	}
+
+	if _, err := updateWithHistory(ctx, account.Uid, &existingRecord, filter, update); err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+		return
+	}
+
+	var updatedRecord models.DBRequest
+	err = db.Collection.FindOne(ctx, filter).Decode(&updatedRecord)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Note updated successfully",
+		"record":  updatedRecord,
+	})
+}
+
+func ListTags(c *gin.Context) {
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tags, err := tagCounts(ctx, account.Uid)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// tagCounts returns the tag catalogue of uid by name with the number of records per tag
+func tagCounts(ctx context.Context, uid string) ([]models.TagCount, error) {
+	cursor, err := db.TagCollection.Find(ctx, bson.M{"uid": uid})
+	if err != nil {
+		log.Printf("Find error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to fetch tags"}
+	}
+	var catalogue []models.Tag
+	if err := cursor.All(ctx, &catalogue); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to decode tags"}
+	}
+
+	// 태그별 레코드 수 집계
+	countCursor, err := db.Collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"uid": uid, "record.tags": bson.M{"$exists": true}}}},
+		{{Key: "$unwind", Value: "$record.tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$record.tags", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		log.Printf("Aggregate error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to count tags"}
+	}
+	var counts []struct {
+		Name  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		log.Printf("Cursor error: %v\n", err)
+		return nil, &opError{http.StatusInternalServerError, "Failed to count tags"}
+	}
+
+	countByName := make(map[string]int, len(counts))
+	for _, tc := range counts {
+		countByName[tc.Name] = tc.Count
+	}
+
+	tags := make([]models.TagCount, 0, len(catalogue))
+	for _, tag := range catalogue {
+		tags = append(tags, models.TagCount{
+			Name:      tag.Name,
+			Count:     countByName[tag.Name],
+			CreatedAt: tag.CreatedAt,
+		})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func CreateTag(c *gin.Context) {
+	var req models.CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := normalize.Tag(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tags must be 1 to 30 characters long"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := addToCatalogue(ctx, account.Uid, []string{name}); err != nil {
+		log.Printf("Tag error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tag created successfully",
+		"name":    name,
+	})
+}
+
+func RenameTag(c *gin.Context) {
+	var req models.RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldName := normalize.Tag(c.Param("name"))
+	newName := normalize.Tag(req.NewName)
+	if oldName == "" || newName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tags must be 1 to 30 characters long"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	count, err := db.TagCollection.CountDocuments(ctx, bson.M{"uid": account.Uid, "name": newName})
+	if err != nil {
+		log.Printf("Count error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	if count > 0 && newName != oldName {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tag already exists, merge the tags instead"})
+		return
+	}
+
+	result, err := db.TagCollection.UpdateOne(ctx,
+		bson.M{"uid": account.Uid, "name": oldName},
+		bson.M{"$set": bson.M{"name": newName}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename tag"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	updated, err := retagRecords(ctx, account.Uid, []string{oldName}, newName)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename tag on records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag renamed successfully",
+		"name":    newName,
+		"updated": updated,
+	})
+}
+
+func MergeTags(c *gin.Context) {
+	var req models.MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sources, ok := normalizeTags(req.Sources)
+	target := normalize.Tag(req.Target)
+	if !ok || target == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tags must be 1 to 30 characters long"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := addToCatalogue(ctx, account.Uid, []string{target}); err != nil {
+		log.Printf("Tag error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag catalogue"})
+		return
+	}
+
+	merged := []string{}
+	for _, source := range sources {
+		if source != target {
+			merged = append(merged, source)
+		}
+	}
+
+	updated, err := retagRecords(ctx, account.Uid, merged, target)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags on records"})
+		return
+	}
+
+	if _, err := db.TagCollection.DeleteMany(ctx, bson.M{"uid": account.Uid, "name": bson.M{"$in": merged}}); err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag catalogue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags merged successfully",
+		"name":    target,
+		"updated": updated,
+	})
+}
+
+func DeleteTag(c *gin.Context) {
+	name := normalize.Tag(c.Param("name"))
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := db.TagCollection.DeleteOne(ctx, bson.M{"uid": account.Uid, "name": name})
+	if err != nil {
+		log.Printf("Delete error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	updated, err := updateRecords(ctx, account.Uid,
+		bson.M{"uid": account.Uid, "record.tags": name},
+		bson.M{"$pull": bson.M{"record.tags": name}},
+	)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove tag from records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag deleted successfully",
+		"updated": updated,
+	})
+}
+
+// retagRecords replaces the sources tags by target on every record of uid
+func retagRecords(ctx context.Context, uid string, sources []string, target string) (int, error) {
+	if len(sources) == 0 {
+		return 0, nil
+	}
+
+	// 같은 배열에 $addToSet과 $pull을 한 번에 쓸 수 없어 파이프라인 업데이트 사용
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"record.tags": bson.M{
+			"$setUnion": bson.A{
+				bson.M{"$setDifference": bson.A{"$record.tags", sources}},
+				bson.A{target},
+			},
+		}}}},
+	}
+
+	return updateRecords(ctx, uid, bson.M{"uid": uid, "record.tags": bson.M{"$in": sources}}, update)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	jwt "dbserver/auth"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// userFilter matches the user document itself, not the records stored next to it
+func userFilter(uid string) bson.M {
+	return bson.M{"uid": uid, "record": bson.M{"$exists": false}}
+}
+
+// userLocation returns the timezone the user has chosen, or the default one
+func userLocation(ctx context.Context, uid string) *time.Location {
+	var user models.User
+	if err := db.Collection.FindOne(ctx, userFilter(uid)).Decode(&user); err != nil {
+		return dates.LoadLocation("")
+	}
+	return dates.LoadLocation(user.TimeZone)
+}
+
+// homeCurrency returns the currency the analytics of uid are converted to
+func homeCurrency(ctx context.Context, uid string) string {
+	var user models.User
+	if err := db.Collection.FindOne(ctx, userFilter(uid)).Decode(&user); err != nil {
+		return currency.Default
+	}
+	return currency.Normalize(user.HomeCurrency)
+}
+
+func UpdateTimeZone(c *gin.Context) {
+	var req models.UpdateTimeZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !dates.ValidTimeZone(req.TimeZone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone"})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.Collection.UpdateOne(ctx, userFilter(account.Uid), bson.M{
+		"$set": bson.M{"timeZone": req.TimeZone},
+	})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update time zone"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Time zone updated successfully",
+		"timeZone": req.TimeZone,
+	})
+}
+
+// UpdateHomeCurrency sets the currency analytics are converted to
+func UpdateHomeCurrency(c *gin.Context) {
+	var req models.UpdateCurrencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !currency.Valid(req.Currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+	home := currency.Normalize(req.Currency)
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.Collection.UpdateOne(ctx, userFilter(account.Uid), bson.M{
+		"$set": bson.M{"homeCurrency": home},
+	})
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update currency"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Home currency updated successfully",
+		"currency": home,
+	})
+}
+
+// UpdateReportSubscription turns the monthly report on or off. Subscribed users have
+// the report of the past month generated on the first of every month.
+func UpdateReportSubscription(c *gin.Context) {
+	var req models.UpdateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := jwt.GetAccount(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"monthlyReport": ""}}
+	if *req.Monthly {
+		update = bson.M{"$set": bson.M{"monthlyReport": true}}
+	}
+	result, err := db.Collection.UpdateOne(ctx, userFilter(account.Uid), update)
+	if err != nil {
+		log.Printf("Update error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report subscription"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report subscription updated successfully",
+		"monthly": *req.Monthly,
+	})
+}
@@ -0,0 +1,107 @@
+package history
+
+import (
+	"dbserver/models"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Diff returns the field-level changes between two documents using their bson field names
+func Diff(before, after interface{}) ([]models.FieldChange, error) {
+	oldDoc, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []models.FieldChange{}
+	diffValue("", oldDoc, newDoc, &changes)
+	return changes, nil
+}
+
+func toMap(v interface{}) (bson.M, error) {
+	if v == nil {
+		return bson.M{}, nil
+	}
+
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func diffValue(path string, oldValue, newValue interface{}, changes *[]models.FieldChange) {
+	switch o := oldValue.(type) {
+	case bson.M:
+		if n, ok := newValue.(bson.M); ok {
+			for _, key := range unionKeys(o, n) {
+				diffValue(join(path, key), o[key], n[key], changes)
+			}
+			return
+		}
+	case bson.A:
+		if n, ok := newValue.(bson.A); ok {
+			length := len(o)
+			if len(n) > length {
+				length = len(n)
+			}
+			for i := 0; i < length; i++ {
+				var ov, nv interface{}
+				if i < len(o) {
+					ov = o[i]
+				}
+				if i < len(n) {
+					nv = n[i]
+				}
+				diffValue(join(path, strconv.Itoa(i)), ov, nv, changes)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, models.FieldChange{
+			Field: path,
+			Old:   oldValue,
+			New:   newValue,
+		})
+	}
+}
+
+func unionKeys(a, b bson.M) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for k := range b {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
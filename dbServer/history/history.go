@@ -0,0 +1,151 @@
+package history
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/events"
+	"dbserver/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 동시에 같은 버전을 쓰려고 할 때 재시도하는 횟수
+const maxVersionRetries = 3
+
+// Record stores a new version of a record. before is the state prior to the mutation;
+// if the record has no history yet it is saved first as the "original" version so the
+// values read by OCR are never lost.
+func Record(ctx context.Context, actor string, action string, before *models.RecordInput, after models.RecordInput) (*models.RecordHistory, error) {
+	return record(ctx, actor, action, 0, before, after)
+}
+
+// RecordRevert stores a new version produced by reverting to an older one.
+func RecordRevert(ctx context.Context, actor string, version int, before *models.RecordInput, after models.RecordInput) (*models.RecordHistory, error) {
+	return record(ctx, actor, models.HistoryActionRevert, version, before, after)
+}
+
+func record(ctx context.Context, actor string, action string, revertedTo int, before *models.RecordInput, after models.RecordInput) (*models.RecordHistory, error) {
+	var changes []models.FieldChange
+	if before != nil {
+		diff, err := Diff(before, after)
+		if err != nil {
+			return nil, err
+		}
+		changes = diff
+	}
+
+	var err error
+	for i := 0; i < maxVersionRetries; i++ {
+		var latest int
+		latest, err = latestVersion(ctx, after.Record.Rid)
+		if err != nil {
+			return nil, err
+		}
+
+		if latest == 0 && before != nil {
+			original := models.RecordHistory{
+				Rid:       before.Record.Rid,
+				Uid:       before.Uid,
+				Version:   1,
+				Action:    models.HistoryActionOriginal,
+				Actor:     before.Uid,
+				Changes:   []models.FieldChange{},
+				Snapshot:  *before,
+				CreatedAt: time.Now(),
+			}
+			if _, err = db.HistoryCollection.InsertOne(ctx, original); err != nil {
+				if mongo.IsDuplicateKeyError(err) {
+					continue
+				}
+				return nil, err
+			}
+			latest = 1
+		}
+
+		entry := models.RecordHistory{
+			Rid:        after.Record.Rid,
+			Uid:        after.Uid,
+			Version:    latest + 1,
+			Action:     action,
+			Actor:      actor,
+			RevertedTo: revertedTo,
+			Changes:    changes,
+			Snapshot:   after,
+			CreatedAt:  time.Now(),
+		}
+		if entry.Changes == nil {
+			entry.Changes = []models.FieldChange{}
+		}
+
+		if _, err = db.HistoryCollection.InsertOne(ctx, entry); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		// 열려 있는 이벤트 스트림에 바뀐 기록을 알림
+		if err := events.Publish(ctx, changeEvent(action, actor, before, after)); err != nil {
+			return nil, err
+		}
+		return &entry, nil
+	}
+
+	return nil, err
+}
+
+// changeEvent is the event of a new version. A record moved to another household
+// remembers the old one so its members can drop it.
+func changeEvent(action string, actor string, before *models.RecordInput, after models.RecordInput) models.RecordEvent {
+	eventType := models.RecordEventUpdated
+	if action == models.HistoryActionCreate {
+		eventType = models.RecordEventCreated
+	}
+	event := events.New(eventType, actor, after)
+	if before != nil && before.GroupId != after.GroupId {
+		event.PrevGroupId = before.GroupId
+	}
+	return event
+}
+
+func latestVersion(ctx context.Context, rid string) (int, error) {
+	var latest models.RecordHistory
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	err := db.HistoryCollection.FindOne(ctx, bson.M{"rid": rid}, opts).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return latest.Version, nil
+}
+
+// List returns every stored version of a record, oldest first.
+func List(ctx context.Context, rid string) ([]models.RecordHistory, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := db.HistoryCollection.Find(ctx, bson.M{"rid": rid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.RecordHistory{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Get returns a single version of a record.
+func Get(ctx context.Context, rid string, version int) (*models.RecordHistory, error) {
+	var entry models.RecordHistory
+	err := db.HistoryCollection.FindOne(ctx, bson.M{"rid": rid, "version": version}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,126 @@
+package household
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PersonalScope selects only the records that belong to no household
+const PersonalScope = "personal"
+
+var roleRank = map[string]int{
+	models.HouseholdRoleViewer: 1,
+	models.HouseholdRoleEditor: 2,
+	models.HouseholdRoleOwner:  3,
+}
+
+// AtLeast reports whether role grants at least the permissions of required
+func AtLeast(role string, required string) bool {
+	return roleRank[role] >= roleRank[required]
+}
+
+// Access is what a user may see and change: their personal records and the records
+// of every household they belong to, according to their role there
+type Access struct {
+	Uid   string
+	Roles map[string]string
+}
+
+// Load reads the user's household memberships
+func Load(ctx context.Context, uid string) (*Access, error) {
+	access := &Access{Uid: uid, Roles: map[string]string{}}
+
+	cursor, err := db.HouseholdCollection.Find(ctx, bson.M{"members.uid": uid})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var h models.Household
+		if err := cursor.Decode(&h); err != nil {
+			return nil, err
+		}
+		for _, m := range h.Members {
+			if m.Uid == uid {
+				access.Roles[h.GroupId] = m.Role
+			}
+		}
+	}
+	return access, cursor.Err()
+}
+
+// Role returns the user's role in a household, or "" if not a member
+func (a *Access) Role(groupId string) string {
+	return a.Roles[groupId]
+}
+
+func (a *Access) personal() bson.M {
+	return bson.M{"uid": a.Uid, "groupId": bson.M{"$exists": false}}
+}
+
+func (a *Access) groups(required string) bson.A {
+	groups := bson.A{}
+	for groupId, role := range a.Roles {
+		if AtLeast(role, required) {
+			groups = append(groups, groupId)
+		}
+	}
+	return groups
+}
+
+// Readable is a $or clause matching every record the user may read
+func (a *Access) Readable() bson.A {
+	return bson.A{a.personal(), bson.M{"groupId": bson.M{"$in": a.groups(models.HouseholdRoleViewer)}}}
+}
+
+// Writable is a $or clause matching every record the user may change
+func (a *Access) Writable() bson.A {
+	return bson.A{a.personal(), bson.M{"groupId": bson.M{"$in": a.groups(models.HouseholdRoleEditor)}}}
+}
+
+// Scope is a $or clause for the listing's group parameter: "" for every readable
+// record, "personal" for the personal ledger only, or a household id
+func (a *Access) Scope(group string) (bson.A, error) {
+	switch group {
+	case "":
+		return a.Readable(), nil
+	case PersonalScope:
+		return bson.A{a.personal()}, nil
+	}
+	if a.Role(group) == "" {
+		return nil, fmt.Errorf("not a member of household %s", group)
+	}
+	return bson.A{bson.M{"groupId": group}}, nil
+}
+
+// CanRead reports whether the user may read a record of uid kept in groupId, "" for
+// the personal ledger
+func (a *Access) CanRead(uid string, groupId string) bool {
+	if groupId == "" {
+		return uid == a.Uid
+	}
+	return AtLeast(a.Role(groupId), models.HouseholdRoleViewer)
+}
+
+// CanWrite reports whether the user may change record
+func (a *Access) CanWrite(record models.RecordInput) bool {
+	if record.GroupId == "" {
+		return record.Uid == a.Uid
+	}
+	return AtLeast(a.Role(record.GroupId), models.HouseholdRoleEditor)
+}
+
+// RoleOf returns the role of uid in h, or "" if not a member
+func RoleOf(h models.Household, uid string) string {
+	for _, m := range h.Members {
+		if m.Uid == uid {
+			return m.Role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package household
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// InvitationTTL is how long an invitation can be accepted
+const InvitationTTL = 7 * 24 * time.Hour
+
+// NewToken returns a random token that is hard to guess
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,162 @@
+package imports
+
+import (
+	"context"
+	"dbserver/category"
+	"dbserver/db"
+	"dbserver/events"
+	"dbserver/fingerprint"
+	"dbserver/marts"
+	"dbserver/models"
+	"dbserver/normalize"
+	"dbserver/reconcile"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// 한 번에 저장하는 레코드 수
+const insertChunk = 500
+
+// Commit creates a record for every valid row of a previewed batch. Rows flagged as
+// duplicates are skipped unless includeDuplicates is set. The batch must already be
+// marked committed by the caller so it cannot be committed twice.
+func Commit(ctx context.Context, batch *models.ImportBatch, includeDuplicates bool, categorizer *category.Categorizer) error {
+	now := time.Now()
+	var records []interface{}
+	var entries []interface{}
+	var created []models.RecordEvent
+	// 같은 매장이 여러 행에 나오므로 매장명별로 한 번만 찾음
+	martIds := map[string]string{}
+
+	for i := range batch.Rows {
+		row := &batch.Rows[i]
+		row.Rid = ""
+		if len(row.Errors) > 0 || (row.DuplicateOf != "" && !includeDuplicates) {
+			continue
+		}
+
+		pname := row.Item
+		if pname == "" {
+			pname = row.MartName
+		}
+		products := []models.DBProduct{{
+			Pname:    pname,
+			NormName: normalize.ProductName(pname),
+			Price:    row.Amount,
+			Amount:   1,
+		}}
+		categorizer.Apply(products)
+
+		record := models.RecordInput{
+			Uid: batch.Uid,
+			Record: models.DBRecord{
+				Rid:           uuid.NewString(),
+				Rname:         row.TimeStamp.Date() + row.MartName,
+				TimeStamp:     row.TimeStamp,
+				Note:          row.Note,
+				ImportBatchId: batch.BatchId,
+				DuplicateOf:   row.DuplicateOf,
+			},
+			Mart:       models.DBMart{MartName: row.MartName},
+			Product:    products,
+			TotalPrice: row.Amount,
+		}
+		if row.MartName != "" {
+			martId, ok := martIds[row.MartName]
+			if !ok {
+				mart, err := marts.Resolve(ctx, batch.Uid, record.Mart)
+				if err != nil {
+					return fmt.Errorf("resolve mart of row %d: %w", row.Row, err)
+				}
+				martId = mart.MartId
+				martIds[row.MartName] = martId
+			}
+			record.Mart.MartId = martId
+		}
+		record.Issues = reconcile.Check(record, nil, now)
+		record.Record.Fingerprint = fingerprint.Of(record)
+		row.Rid = record.Record.Rid
+
+		records = append(records, record)
+		entries = append(entries, models.RecordHistory{
+			Rid:       record.Record.Rid,
+			Uid:       batch.Uid,
+			Version:   1,
+			Action:    models.HistoryActionCreate,
+			Actor:     batch.Uid,
+			Changes:   []models.FieldChange{},
+			Snapshot:  record,
+			CreatedAt: now,
+		})
+		created = append(created, events.New(models.RecordEventCreated, batch.Uid, record))
+	}
+
+	if len(records) == 0 {
+		return fmt.Errorf("no rows to import")
+	}
+
+	for start := 0; start < len(records); start += insertChunk {
+		end := start + insertChunk
+		if end > len(records) {
+			end = len(records)
+		}
+		if _, err := db.Collection.InsertMany(ctx, records[start:end]); err != nil {
+			// 일부만 저장된 경우 되돌림
+			Undo(ctx, batch.Uid, batch.BatchId)
+			return err
+		}
+		if _, err := db.HistoryCollection.InsertMany(ctx, entries[start:end]); err != nil {
+			Undo(ctx, batch.Uid, batch.BatchId)
+			return err
+		}
+		if err := events.Publish(ctx, created[start:end]...); err != nil {
+			Undo(ctx, batch.Uid, batch.BatchId)
+			return err
+		}
+	}
+
+	batch.Summary = Summarize(batch.Rows)
+	return nil
+}
+
+// Undo deletes every record created by the batch and their history. It returns the
+// rids of the deleted records so the caller can clear marks pointing at them.
+func Undo(ctx context.Context, uid string, batchId string) (int64, []string, error) {
+	filter := bson.M{"uid": uid, "record.importBatchId": batchId}
+
+	var rids []string
+	var deleted []models.RecordEvent
+	cursor, err := db.Collection.Find(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			return 0, nil, err
+		}
+		rids = append(rids, record.Record.Rid)
+		deleted = append(deleted, events.New(models.RecordEventDeleted, uid, record))
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	result, err := db.Collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(rids) > 0 {
+		if _, err := db.HistoryCollection.DeleteMany(ctx, bson.M{"rid": bson.M{"$in": rids}}); err != nil {
+			return result.DeletedCount, rids, err
+		}
+	}
+	if err := events.Publish(ctx, deleted...); err != nil {
+		return result.DeletedCount, rids, err
+	}
+	return result.DeletedCount, rids, nil
+}
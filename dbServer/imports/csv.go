@@ -0,0 +1,101 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/korean"
+)
+
+const (
+	// MaxFileSize is the largest file accepted for import
+	MaxFileSize = 5 << 20
+	// MaxRows is the largest number of data rows accepted for import
+	MaxRows = 5000
+)
+
+// ReadCSV reads an uploaded CSV file. Card company downloads are often EUC-KR and
+// start with a few title lines, so the text is converted to UTF-8 and the header is
+// taken from the first line that matches a preset (or the first line otherwise).
+func ReadCSV(r io.Reader) (header []string, cells [][]string, err error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, nil, fmt.Errorf("file is larger than %d MB", MaxFileSize>>20)
+	}
+
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	if !utf8.Valid(data) {
+		data, err = korean.EUCKR.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("file is neither UTF-8 nor EUC-KR")
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Count(data, []byte{'\t'}) > bytes.Count(data, []byte{','}) {
+		reader.Comma = '\t'
+	}
+
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := -1
+	for i, line := range lines {
+		if _, _, ok := Detect(trimCells(line)); ok {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for i, line := range lines {
+			if !emptyLine(line) {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return nil, nil, fmt.Errorf("file is empty")
+	}
+
+	header = trimCells(lines[start])
+	for _, line := range lines[start+1:] {
+		if emptyLine(line) {
+			continue
+		}
+		cells = append(cells, trimCells(line))
+	}
+	if len(cells) > MaxRows {
+		return nil, nil, fmt.Errorf("file has more than %d rows", MaxRows)
+	}
+
+	return header, cells, nil
+}
+
+func trimCells(line []string) []string {
+	trimmed := make([]string, len(line))
+	for i, cell := range line {
+		trimmed[i] = strings.TrimSpace(cell)
+	}
+	return trimmed
+}
+
+func emptyLine(line []string) bool {
+	for _, cell := range line {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
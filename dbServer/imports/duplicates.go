@@ -0,0 +1,114 @@
+package imports
+
+import (
+	"context"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/normalize"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MarkDuplicates sets DuplicateOf on rows that look like one of the user's existing
+// records: same purchase day, same total and a matching merchant name. Each existing
+// record is matched to at most one row.
+func MarkDuplicates(ctx context.Context, uid string, rows []models.ImportRow, loc *time.Location) error {
+	var from, to time.Time
+	amounts := bson.A{}
+	for i := range rows {
+		rows[i].DuplicateOf = ""
+		if len(rows[i].Errors) > 0 {
+			continue
+		}
+		at := rows[i].TimeStamp.At
+		if from.IsZero() || at.Before(from) {
+			from = at
+		}
+		if to.IsZero() || at.After(to) {
+			to = at
+		}
+		amounts = append(amounts, rows[i].Amount)
+	}
+	if len(amounts) == 0 {
+		return nil
+	}
+
+	filter := bson.M{
+		"uid":        uid,
+		"groupId":    bson.M{"$exists": false},
+		"record.rid": bson.M{"$exists": true},
+		"record.timeStamp.at": bson.M{
+			"$gte": dates.StartOfDay(from, loc),
+			"$lt":  dates.StartOfDay(to, loc).AddDate(0, 0, 1),
+		},
+		"totalPrice": bson.M{"$in": amounts},
+	}
+	opts := options.Find().SetProjection(bson.M{
+		"record.rid":       1,
+		"record.timeStamp": 1,
+		"mart.martName":    1,
+		"totalPrice":       1,
+	})
+	cursor, err := db.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.RecordInput
+	if err := cursor.All(ctx, &records); err != nil {
+		return err
+	}
+	matchDuplicates(rows, records, loc)
+	return nil
+}
+
+// matchDuplicates pairs valid rows with records of the same day, total and merchant
+func matchDuplicates(rows []models.ImportRow, records []models.RecordInput, loc *time.Location) {
+	type candidate struct {
+		rid  string
+		mart string
+	}
+	existing := map[string][]candidate{}
+	for _, record := range records {
+		key := duplicateKey(record.Record.TimeStamp.At, record.TotalPrice, loc)
+		existing[key] = append(existing[key], candidate{
+			rid:  record.Record.Rid,
+			mart: normalize.ProductName(record.Mart.MartName),
+		})
+	}
+
+	for i := range rows {
+		if len(rows[i].Errors) > 0 {
+			continue
+		}
+		key := duplicateKey(rows[i].TimeStamp.At, rows[i].Amount, loc)
+		mart := normalize.ProductName(rows[i].MartName)
+		for j, c := range existing[key] {
+			if sameMerchant(mart, c.mart) {
+				rows[i].DuplicateOf = c.rid
+				existing[key] = append(existing[key][:j], existing[key][j+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func duplicateKey(t time.Time, amount int, loc *time.Location) string {
+	return fmt.Sprintf("%s|%d", t.In(loc).Format("2006-01-02"), amount)
+}
+
+// sameMerchant compares normalized merchant names. Card statements often shorten or
+// decorate the store name (e.g. "이마트 성수점" vs "(주)이마트 성수점"), so one name
+// containing the other is enough. A record without a merchant matches any row.
+func sameMerchant(a, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+	return strings.Contains(a, b) || strings.Contains(b, a)
+}
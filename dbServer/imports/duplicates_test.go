@@ -0,0 +1,68 @@
+package imports
+
+import (
+	"dbserver/dates"
+	"dbserver/models"
+	"testing"
+	"time"
+)
+
+func TestSameMerchant(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"이마트성수점", "이마트성수점", true},
+		{"주이마트성수점", "이마트성수점", true},
+		{"이마트", "이마트성수점", true},
+		{"", "스타벅스", true},
+		{"이마트", "", true},
+		{"이마트", "홈플러스", false},
+		{"이마트성수점", "이마트왕십리점", false},
+	}
+	for _, tt := range tests {
+		if got := sameMerchant(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameMerchant(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchDuplicates(t *testing.T) {
+	loc := dates.LoadLocation("Asia/Seoul")
+	at := func(day, hour int) models.PurchaseTime {
+		return models.NewPurchaseTime(time.Date(2026, 3, day, hour, 0, 0, 0, loc), true)
+	}
+	record := func(rid string, day, hour int, mart string, total int) models.RecordInput {
+		return models.RecordInput{
+			Record:     models.DBRecord{Rid: rid, TimeStamp: at(day, hour)},
+			Mart:       models.DBMart{MartName: mart},
+			TotalPrice: total,
+		}
+	}
+	records := []models.RecordInput{
+		record("r1", 14, 18, "(주)이마트 성수점", 12300),
+		record("r2", 14, 9, "스타벅스", 4500),
+		record("r3", 15, 10, "", 8000),
+		record("r4", 16, 0, "홈플러스", 3000),
+	}
+	rows := []models.ImportRow{
+		// 시각이 달라도 같은 날, 같은 금액, 가맹점 이름이 포함되면 중복
+		{Row: 1, TimeStamp: at(14, 1), MartName: "이마트 성수점", Amount: 12300},
+		// 같은 기록은 한 줄에만 짝지어짐
+		{Row: 2, TimeStamp: at(14, 2), MartName: "이마트", Amount: 12300},
+		{Row: 3, TimeStamp: at(14, 9), MartName: "STARBUCKS", Amount: 4500},
+		// 가맹점이 없는 기록은 어떤 줄과도 맞음
+		{Row: 4, TimeStamp: at(15, 20), MartName: "다이소", Amount: 8000},
+		{Row: 5, TimeStamp: at(15, 20), MartName: "홈플러스", Amount: 3000},
+		{Row: 6, TimeStamp: at(16, 0), MartName: "홈플러스", Amount: 3000, Errors: []string{"invalid"}},
+	}
+
+	matchDuplicates(rows, records, loc)
+
+	want := []string{"r1", "", "", "r3", "", ""}
+	for i, row := range rows {
+		if row.DuplicateOf != want[i] {
+			t.Errorf("row %d duplicateOf = %q, want %q", row.Row, row.DuplicateOf, want[i])
+		}
+	}
+}
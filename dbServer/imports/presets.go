@@ -0,0 +1,130 @@
+package imports
+
+import (
+	"dbserver/models"
+	"strings"
+)
+
+// Preset describes the column headers of a card company's statement download. Each
+// field lists the header names the company has used.
+type Preset struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Date     []string `json:"date"`
+	Time     []string `json:"time,omitempty"`
+	Merchant []string `json:"merchant"`
+	Amount   []string `json:"amount"`
+	Item     []string `json:"item,omitempty"`
+	Note     []string `json:"note,omitempty"`
+}
+
+// Presets are tried in order; the first preset matching the most columns wins
+var Presets = []Preset{
+	{
+		Name:     "shinhan",
+		Label:    "신한카드",
+		Date:     []string{"거래일자", "이용일자"},
+		Time:     []string{"거래시간"},
+		Merchant: []string{"가맹점명"},
+		Amount:   []string{"이용금액", "금액"},
+		Note:     []string{"이용카드"},
+	},
+	{
+		Name:     "samsung",
+		Label:    "삼성카드",
+		Date:     []string{"승인일자"},
+		Time:     []string{"승인시각"},
+		Merchant: []string{"가맹점명"},
+		Amount:   []string{"승인금액(원)", "승인금액"},
+	},
+	{
+		Name:     "kb",
+		Label:    "KB국민카드",
+		Date:     []string{"이용일"},
+		Time:     []string{"이용시간"},
+		Merchant: []string{"이용하신곳"},
+		Amount:   []string{"국내이용금액(원)", "이용금액(원)", "이용금액"},
+	},
+	{
+		Name:     "hyundai",
+		Label:    "현대카드",
+		Date:     []string{"이용일"},
+		Merchant: []string{"이용가맹점"},
+		Amount:   []string{"이용금액"},
+		Note:     []string{"이용카드"},
+	},
+	{
+		Name:     "lotte",
+		Label:    "롯데카드",
+		Date:     []string{"이용일자"},
+		Time:     []string{"이용시간"},
+		Merchant: []string{"이용가맹점"},
+		Amount:   []string{"이용금액"},
+	},
+	{
+		Name:     "generic",
+		Label:    "일반 CSV",
+		Date:     []string{"date", "날짜", "구매일", "구매일자"},
+		Time:     []string{"time", "시간", "구매시각"},
+		Merchant: []string{"merchant", "mart", "store", "매장명", "상호", "가게"},
+		Amount:   []string{"amount", "price", "total", "금액", "합계", "결제금액"},
+		Item:     []string{"item", "product", "품목", "품목명", "상품명"},
+		Note:     []string{"note", "memo", "메모", "비고"},
+	},
+}
+
+// FindPreset returns the preset called name
+func FindPreset(name string) (Preset, bool) {
+	for _, preset := range Presets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Mapping matches the preset's column names against header. ok is false when one of
+// the required columns is missing.
+func (p Preset) Mapping(header []string) (mapping models.ColumnMapping, matched int, ok bool) {
+	find := func(names []string) string {
+		for _, name := range names {
+			for _, column := range header {
+				if headerKey(column) == headerKey(name) {
+					matched++
+					return column
+				}
+			}
+		}
+		return ""
+	}
+
+	mapping = models.ColumnMapping{
+		Date:     find(p.Date),
+		Time:     find(p.Time),
+		Merchant: find(p.Merchant),
+		Amount:   find(p.Amount),
+		Item:     find(p.Item),
+		Note:     find(p.Note),
+	}
+	ok = mapping.Date != "" && mapping.Merchant != "" && mapping.Amount != ""
+	return mapping, matched, ok
+}
+
+// Detect picks the preset that matches the most columns of header
+func Detect(header []string) (Preset, models.ColumnMapping, bool) {
+	var best Preset
+	var bestMapping models.ColumnMapping
+	bestMatched := 0
+	for _, preset := range Presets {
+		mapping, matched, ok := preset.Mapping(header)
+		if ok && matched > bestMatched {
+			best, bestMapping, bestMatched = preset, mapping, matched
+		}
+	}
+	return best, bestMapping, bestMatched > 0
+}
+
+// headerKey compares headers ignoring case and spaces
+func headerKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
@@ -0,0 +1,88 @@
+package imports
+
+import (
+	"dbserver/models"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   string
+		wanted models.ColumnMapping
+	}{
+		{
+			"shinhan",
+			[]string{"이용일자", "거래시간", "이용카드", "가맹점명", "이용금액", "승인번호"},
+			"shinhan",
+			models.ColumnMapping{Date: "이용일자", Time: "거래시간", Merchant: "가맹점명", Amount: "이용금액", Note: "이용카드"},
+		},
+		{
+			"samsung",
+			[]string{"승인일자", "승인시각", "가맹점명", "승인금액(원)"},
+			"samsung",
+			models.ColumnMapping{Date: "승인일자", Time: "승인시각", Merchant: "가맹점명", Amount: "승인금액(원)"},
+		},
+		{
+			"kb",
+			[]string{"이용일", "이용시간", "이용하신곳", "국내이용금액(원)", "해외이용금액($)"},
+			"kb",
+			models.ColumnMapping{Date: "이용일", Time: "이용시간", Merchant: "이용하신곳", Amount: "국내이용금액(원)"},
+		},
+		{
+			"hyundai",
+			[]string{"이용일", "이용카드", "이용가맹점", "이용금액"},
+			"hyundai",
+			models.ColumnMapping{Date: "이용일", Merchant: "이용가맹점", Amount: "이용금액", Note: "이용카드"},
+		},
+		{
+			"lotte",
+			[]string{"이용일자", "이용시간", "이용가맹점", "이용금액"},
+			"lotte",
+			models.ColumnMapping{Date: "이용일자", Time: "이용시간", Merchant: "이용가맹점", Amount: "이용금액"},
+		},
+		{
+			"generic with spacing and case",
+			[]string{"Date", "Store", "Item", " Total ", "Memo"},
+			"generic",
+			models.ColumnMapping{Date: "Date", Merchant: "Store", Amount: " Total ", Item: "Item", Note: "Memo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preset, mapping, ok := Detect(tt.header)
+			if !ok {
+				t.Fatalf("Detect() found no preset")
+			}
+			if preset.Name != tt.want {
+				t.Errorf("Detect() = %s, want %s", preset.Name, tt.want)
+			}
+			if mapping != tt.wanted {
+				t.Errorf("mapping = %+v, want %+v", mapping, tt.wanted)
+			}
+		})
+	}
+}
+
+func TestDetectNone(t *testing.T) {
+	// 필수 열이 빠지면 어떤 양식도 고르지 않음
+	for _, header := range [][]string{
+		nil,
+		{"승인일자", "가맹점명"},
+		{"foo", "bar", "baz"},
+	} {
+		if preset, _, ok := Detect(header); ok {
+			t.Errorf("Detect(%q) = %s, want no preset", header, preset.Name)
+		}
+	}
+}
+
+func TestFindPreset(t *testing.T) {
+	if preset, ok := FindPreset("kb"); !ok || preset.Label != "KB국민카드" {
+		t.Errorf("FindPreset(kb) = %+v, %v", preset, ok)
+	}
+	if _, ok := FindPreset("visa"); ok {
+		t.Errorf("FindPreset(visa) found a preset")
+	}
+}
@@ -0,0 +1,146 @@
+package imports
+
+import (
+	"dbserver/dates"
+	"dbserver/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build validates every data row against mapping. Rows with errors are kept so they
+// can be shown in the preview but are never imported.
+func Build(header []string, cells [][]string, mapping models.ColumnMapping, loc *time.Location) ([]models.ImportRow, error) {
+	index := func(name string, required bool) (int, error) {
+		if name == "" {
+			if required {
+				return -1, fmt.Errorf("a required column is not mapped")
+			}
+			return -1, nil
+		}
+		for i, column := range header {
+			if headerKey(column) == headerKey(name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("column %q not found", name)
+	}
+
+	var columns [6]int
+	for i, field := range []struct {
+		name     string
+		required bool
+	}{
+		{mapping.Date, true},
+		{mapping.Time, false},
+		{mapping.Merchant, true},
+		{mapping.Amount, true},
+		{mapping.Item, false},
+		{mapping.Note, false},
+	} {
+		column, err := index(field.name, field.required)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = column
+	}
+
+	cell := func(line []string, column int) string {
+		if column < 0 || column >= len(line) {
+			return ""
+		}
+		return line[column]
+	}
+
+	rows := make([]models.ImportRow, 0, len(cells))
+	for i, line := range cells {
+		row := models.ImportRow{
+			Row:      i + 1,
+			MartName: cell(line, columns[2]),
+			Item:     cell(line, columns[4]),
+			Note:     cell(line, columns[5]),
+		}
+
+		value := strings.TrimSpace(cell(line, columns[0]) + " " + cell(line, columns[1]))
+		t, hasTime, err := dates.Parse(value, loc)
+		if err != nil {
+			row.Errors = append(row.Errors, "invalid date: "+value)
+		} else {
+			row.TimeStamp = models.NewPurchaseTime(t, hasTime)
+		}
+
+		if row.MartName == "" {
+			row.Errors = append(row.Errors, "merchant is empty")
+		}
+
+		amount, err := ParseAmount(cell(line, columns[3]))
+		switch {
+		case err != nil:
+			row.Errors = append(row.Errors, err.Error())
+		case amount < 0:
+			// 승인 취소나 환불 건은 가져오지 않음
+			row.Errors = append(row.Errors, "cancelled or refunded transaction")
+		case amount == 0:
+			row.Errors = append(row.Errors, "amount is zero")
+		}
+		row.Amount = amount
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ParseAmount reads an amount such as "12,300", "12,300원", "₩12300" or "(5,000)"
+func ParseAmount(value string) (int, error) {
+	s := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	s = strings.NewReplacer(",", "", "원", "", "₩", "", " ", "").Replace(s)
+	if strings.HasPrefix(s, "-") {
+		negative = !negative
+		s = s[1:]
+	}
+
+	if s == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+	amount, err := strconv.Atoi(s)
+	if err != nil {
+		// 소수점이 있는 금액은 원 단위로 반올림
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return 0, fmt.Errorf("invalid amount: %s", value)
+		}
+		amount = int(f + 0.5)
+	}
+
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// Summarize counts the rows of a preview
+func Summarize(rows []models.ImportRow) models.ImportSummary {
+	summary := models.ImportSummary{Total: len(rows)}
+	for _, row := range rows {
+		switch {
+		case len(row.Errors) > 0:
+			summary.Invalid++
+		case row.DuplicateOf != "":
+			summary.Duplicates++
+			summary.Valid++
+		default:
+			summary.Valid++
+		}
+		if row.Rid != "" {
+			summary.Imported++
+		}
+	}
+	return summary
+}
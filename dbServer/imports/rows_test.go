@@ -0,0 +1,118 @@
+package imports
+
+import (
+	"dbserver/dates"
+	"dbserver/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"12300", 12300},
+		{"12,300", 12300},
+		{" 12,300원 ", 12300},
+		{"₩12,300", 12300},
+		{"₩ 12,300", 12300},
+		{"-1,000", -1000},
+		{"(5,000)", -5000},
+		{"(-5,000)", 5000},
+		{"1,234.5", 1235},
+		{"12.4", 12},
+		{"-12.5", -13},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.value)
+		if err != nil {
+			t.Errorf("ParseAmount(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "  ", "원", "()", "12a", "1.2.3"} {
+		if _, err := ParseAmount(value); err == nil {
+			t.Errorf("ParseAmount(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	loc := dates.LoadLocation("Asia/Seoul")
+	header := []string{"거래일자", "거래 시간", "가맹점명", "이용금액", "이용카드"}
+	mapping := models.ColumnMapping{Date: "거래일자", Time: "거래시간", Merchant: "가맹점명", Amount: "이용금액", Note: "이용카드"}
+	cells := [][]string{
+		{"2026-03-14", "18:30", "이마트 성수점", "₩12,300", "본인"},
+		{"2026-03-15", "", "스타벅스", "4,500"},
+		{"2026-03-16", "09:00", "환불가게", "(5,000)"},
+		{"어제", "", "", "0"},
+		{"2026-03-17", "", "편의점", "12원a"},
+	}
+
+	rows, err := Build(header, cells, mapping, loc)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if len(rows) != len(cells) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(cells))
+	}
+
+	first := rows[0]
+	wantAt := time.Date(2026, 3, 14, 18, 30, 0, 0, loc)
+	if first.Row != 1 || first.MartName != "이마트 성수점" || first.Amount != 12300 || first.Note != "본인" || len(first.Errors) > 0 {
+		t.Errorf("row 1 = %+v", first)
+	}
+	if !first.TimeStamp.At.Equal(wantAt) || !first.TimeStamp.HasTime {
+		t.Errorf("row 1 time = %+v, want %s with time", first.TimeStamp, wantAt)
+	}
+
+	// 짧은 줄은 빈 칸으로 보고, 시간이 없으면 날짜만 있는 기록
+	second := rows[1]
+	if len(second.Errors) > 0 || second.Note != "" || second.TimeStamp.HasTime {
+		t.Errorf("row 2 = %+v", second)
+	}
+
+	wantErrors := [][]string{
+		nil,
+		nil,
+		{"cancelled or refunded transaction"},
+		{"invalid date", "merchant is empty", "amount is zero"},
+		{"invalid amount"},
+	}
+	for i, want := range wantErrors {
+		got := strings.Join(rows[i].Errors, "; ")
+		if len(want) == 0 && got != "" {
+			t.Errorf("row %d errors = %q, want none", i+1, got)
+		}
+		for _, w := range want {
+			if !strings.Contains(got, w) {
+				t.Errorf("row %d errors = %q, want %q", i+1, got, w)
+			}
+		}
+	}
+
+	summary := Summarize(rows)
+	if summary.Total != 5 || summary.Valid != 2 || summary.Invalid != 3 {
+		t.Errorf("Summarize() = %+v", summary)
+	}
+}
+
+func TestBuildMappingErrors(t *testing.T) {
+	header := []string{"date", "merchant", "amount"}
+	for _, mapping := range []models.ColumnMapping{
+		{Merchant: "merchant", Amount: "amount"},
+		{Date: "date", Merchant: "store", Amount: "amount"},
+		{Date: "date", Merchant: "merchant", Amount: "amount", Note: "memo"},
+	} {
+		if _, err := Build(header, nil, mapping, time.UTC); err == nil {
+			t.Errorf("Build() with %+v succeeded, want an error", mapping)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package jobs defines the background jobs of dbServer run by the scheduler
+package jobs
+
+import (
+	"context"
+	"dbserver/budget"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/household"
+	"dbserver/models"
+	"dbserver/notify"
+	"dbserver/reports"
+	"dbserver/scheduler"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// importPreviewTTL is how long an import that was uploaded but never committed is kept
+	importPreviewTTL = 7 * 24 * time.Hour
+	// undoneImportTTL is how long the batch of an undone import is kept
+	undoneImportTTL = 30 * 24 * time.Hour
+	// reportTTL is how long a rendered report is kept after it was last rendered
+	reportTTL = 90 * 24 * time.Hour
+)
+
+// Register adds the jobs to the scheduler
+func Register() {
+	scheduler.Register(scheduler.Job{
+		Name:        "trash-purge",
+		Description: "Deletes abandoned import previews, undone import batches and old rendered reports",
+		Schedule:    "0 4 * * *",
+		Timeout:     5 * time.Minute,
+		Retries:     2,
+		RetryDelay:  time.Minute,
+		Run:         purgeTrash,
+	})
+	scheduler.Register(scheduler.Job{
+		Name:        "token-cleanup",
+		Description: "Deletes household invitations whose token expired before it was accepted",
+		Schedule:    "30 * * * *",
+		Timeout:     time.Minute,
+		Retries:     2,
+		RetryDelay:  time.Minute,
+		Run:         cleanupTokens,
+	})
+	scheduler.Register(scheduler.Job{
+		Name:        "budget-evaluation",
+		Description: "Re-evaluates every budget for its current period and raises the alerts that were missed",
+		Schedule:    "15 * * * *",
+		Timeout:     10 * time.Minute,
+		Retries:     2,
+		RetryDelay:  2 * time.Minute,
+		Run:         evaluateBudgets,
+	})
+	scheduler.Register(scheduler.Job{
+		Name:        "monthly-reports",
+		Description: "Renders the report of the past month for users subscribed to monthly reports",
+		Schedule:    "0 3 1 * *",
+		Timeout:     30 * time.Minute,
+		Retries:     3,
+		RetryDelay:  5 * time.Minute,
+		Run:         generateReports,
+	})
+}
+
+func purgeTrash(ctx context.Context) (string, error) {
+	now := time.Now()
+	previews, err := db.ImportCollection.DeleteMany(ctx, bson.M{
+		"status":    models.ImportStatusPreview,
+		"createdAt": bson.M{"$lt": now.Add(-importPreviewTTL)},
+	})
+	if err != nil {
+		return "", err
+	}
+	undone, err := db.ImportCollection.DeleteMany(ctx, bson.M{
+		"status":   models.ImportStatusUndone,
+		"undoneAt": bson.M{"$lt": now.Add(-undoneImportTTL)},
+	})
+	if err != nil {
+		return "", err
+	}
+	rendered, err := db.ReportCollection.DeleteMany(ctx, bson.M{"createdAt": bson.M{"$lt": now.Add(-reportTTL)}})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted %d import previews, %d undone imports, %d reports",
+		previews.DeletedCount, undone.DeletedCount, rendered.DeletedCount), nil
+}
+
+func cleanupTokens(ctx context.Context) (string, error) {
+	// 수락된 초대는 누가 초대했는지 남기기 위해 유지
+	result, err := db.InvitationCollection.DeleteMany(ctx, bson.M{
+		"expiresAt":  bson.M{"$lt": time.Now()},
+		"acceptedBy": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted %d expired invitations", result.DeletedCount), nil
+}
+
+// evaluateBudgets catches the thresholds crossed by records that did not go through
+// POST /records, e.g. imports, OCR receipts and edits
+func evaluateBudgets(ctx context.Context) (string, error) {
+	uids, err := db.BudgetCollection.Distinct(ctx, "uid", bson.M{})
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	raised, failed := 0, 0
+	var lastErr error
+	for _, value := range uids {
+		uid, ok := value.(string)
+		if !ok {
+			continue
+		}
+		user, err := loadUser(ctx, uid)
+		if err != nil {
+			return "", err
+		}
+		alerts, err := budget.Evaluate(ctx, uid, now, currency.Normalize(user.HomeCurrency), dates.LoadLocation(user.TimeZone))
+		raised += len(alerts)
+		if err != nil {
+			// 한 사용자의 실패로 나머지를 건너뛰지 않음, 알림은 중복 생성되지 않아 재시도해도 안전
+			log.Printf("Budget error for %s: %v\n", uid, err)
+			failed++
+			lastErr = err
+		}
+	}
+
+	message := fmt.Sprintf("evaluated budgets of %d users, raised %d alerts", len(uids), raised)
+	if failed > 0 {
+		return message, fmt.Errorf("%d users failed, last error: %v", failed, lastErr)
+	}
+	return message, nil
+}
+
+func generateReports(ctx context.Context) (string, error) {
+	cursor, err := db.Collection.Find(ctx, bson.M{"record": bson.M{"$exists": false}, "monthlyReport": true})
+	if err != nil {
+		return "", err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return "", err
+	}
+
+	// 이미 만든 보고서는 레코드가 그대로면 캐시에서 바로 나오므로 재시도해도 부담이 적음
+	generated, failed := 0, 0
+	var lastErr error
+	for _, user := range users {
+		if err := generateReport(ctx, user); err != nil {
+			log.Printf("Report error for %s: %v\n", user.Uid, err)
+			failed++
+			lastErr = err
+			continue
+		}
+		generated++
+	}
+
+	message := fmt.Sprintf("generated %d monthly reports", generated)
+	if failed > 0 {
+		return message, fmt.Errorf("%d reports failed, last error: %v", failed, lastErr)
+	}
+	return message, nil
+}
+
+func generateReport(ctx context.Context, user models.User) error {
+	access, err := household.Load(ctx, user.Uid)
+	if err != nil {
+		return err
+	}
+	loc := dates.LoadLocation(user.TimeZone)
+	now := time.Now().In(loc)
+	month := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, loc).Format("2006-01")
+
+	_, _, err = reports.Generate(ctx, reports.Request{
+		Access: access,
+		Month:  month,
+		Home:   currency.Normalize(user.HomeCurrency),
+		Loc:    loc,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 보고서 알림은 달마다 한 번만 보냄, 재시도된 실행에서 중복되지 않게 확인
+	sent, err := db.NotificationCollection.CountDocuments(ctx, bson.M{
+		"uid":        user.Uid,
+		"kind":       models.NotificationMonthlyReport,
+		"data.month": month,
+	})
+	if err != nil || sent > 0 {
+		return err
+	}
+	_, err = notify.Send(ctx, user.Uid, models.NotificationMonthlyReport, map[string]string{
+		"month": month,
+		"path":  "/reports/" + month + ".pdf",
+	})
+	return err
+}
+
+func loadUser(ctx context.Context, uid string) (models.User, error) {
+	var user models.User
+	err := db.Collection.FindOne(ctx, bson.M{"uid": uid, "record": bson.M{"$exists": false}}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, nil
+	}
+	return user, err
+}
@@ -0,0 +1,191 @@
+package marts
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/normalize"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// keys are the normalized values a mart is recognized by
+type keys struct {
+	name    string
+	address string
+	tel     string
+	bizNum  string
+}
+
+func keysOf(mart models.DBMart) keys {
+	return keys{
+		name:    normalize.ProductName(mart.MartName),
+		address: normalize.Address(mart.MartAddress),
+		tel:     Tel(mart.Tel),
+		bizNum:  normalize.Digits(mart.BizNum),
+	}
+}
+
+// Tel returns the digits of a store phone number, or "" for numbers that do not
+// identify one store such as the 1588-xxxx numbers shared by a whole chain
+func Tel(tel string) string {
+	digits := normalize.Digits(tel)
+	if len(digits) < 8 {
+		return ""
+	}
+	if len(digits) == 8 && (strings.HasPrefix(digits, "15") || strings.HasPrefix(digits, "16") || strings.HasPrefix(digits, "18")) {
+		return ""
+	}
+	return digits
+}
+
+// compatible matches marts that have no value for field or the same value, so two
+// branches with different phone numbers are never merged by name
+func compatible(field string, value string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{"$exists": false}},
+		bson.M{field: value},
+	}}
+}
+
+// Find returns the mart of uid that mart from a receipt belongs to, looking at the
+// business registration number, the phone number, the address and finally the
+// name in that order. It returns nil if there is none.
+func Find(ctx context.Context, uid string, mart models.DBMart) (*models.Mart, error) {
+	k := keysOf(mart)
+
+	lookups := []struct {
+		field string
+		value string
+	}{
+		{"normBizNum", k.bizNum},
+		{"normTel", k.tel},
+		{"normAddress", k.address},
+		{"normNames", k.name},
+	}
+	for _, lookup := range lookups {
+		if lookup.value == "" {
+			continue
+		}
+
+		conditions := bson.A{bson.M{"uid": uid, lookup.field: lookup.value}}
+		for _, other := range lookups[:3] {
+			if other.field != lookup.field && other.value != "" {
+				conditions = append(conditions, compatible(other.field, other.value))
+			}
+		}
+
+		var found models.Mart
+		err := db.MartCollection.FindOne(ctx, bson.M{"$and": conditions}).Decode(&found)
+		if err == nil {
+			return &found, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// Resolve returns the mart of uid that mart from a receipt belongs to, creating it
+// if it is new. A new spelling of the name is kept as an alias and values the
+// directory entry is missing are filled in.
+func Resolve(ctx context.Context, uid string, mart models.DBMart) (*models.Mart, error) {
+	found, err := Find(ctx, uid, mart)
+	if err != nil {
+		return nil, err
+	}
+
+	k := keysOf(mart)
+	now := time.Now()
+	if found == nil {
+		created := models.Mart{
+			MartId:      uuid.NewString(),
+			Uid:         uid,
+			Name:        strings.TrimSpace(mart.MartName),
+			Address:     strings.TrimSpace(mart.MartAddress),
+			Tel:         strings.TrimSpace(mart.Tel),
+			BizNum:      strings.TrimSpace(mart.BizNum),
+			Aliases:     []string{},
+			NormNames:   []string{},
+			NormAddress: k.address,
+			NormTel:     k.tel,
+			NormBizNum:  k.bizNum,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if k.name != "" {
+			created.NormNames = append(created.NormNames, k.name)
+		}
+		if _, err := db.MartCollection.InsertOne(ctx, created); err != nil {
+			return nil, err
+		}
+		return &created, nil
+	}
+
+	set := bson.M{"updatedAt": now}
+	addToSet := bson.M{}
+	if name := strings.TrimSpace(mart.MartName); name != "" && name != found.Name {
+		addToSet["aliases"] = name
+	}
+	if k.name != "" {
+		addToSet["normNames"] = k.name
+	}
+	if found.Address == "" && k.address != "" {
+		set["address"] = strings.TrimSpace(mart.MartAddress)
+		set["normAddress"] = k.address
+	}
+	if found.Tel == "" && k.tel != "" {
+		set["tel"] = strings.TrimSpace(mart.Tel)
+		set["normTel"] = k.tel
+	}
+	if found.BizNum == "" && k.bizNum != "" {
+		set["bizNum"] = strings.TrimSpace(mart.BizNum)
+		set["normBizNum"] = k.bizNum
+	}
+
+	update := bson.M{"$set": set}
+	if len(addToSet) > 0 {
+		update["$addToSet"] = addToSet
+	}
+	if _, err := db.MartCollection.UpdateOne(ctx, bson.M{"martId": found.MartId}, update); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// Link points the mart of record to the directory of the record's owner
+func Link(ctx context.Context, record *models.RecordInput) error {
+	if record.Mart.MartName == "" && record.Mart.BizNum == "" && record.Mart.Tel == "" {
+		return nil
+	}
+
+	mart, err := Resolve(ctx, record.Uid, record.Mart)
+	if err != nil {
+		return err
+	}
+	record.Mart.MartId = mart.MartId
+	return nil
+}
+
+// Refresh recomputes the normalized keys of a directory entry after its fields changed
+func Refresh(mart *models.Mart) {
+	k := keysOf(models.DBMart{MartName: mart.Name, MartAddress: mart.Address, Tel: mart.Tel, BizNum: mart.BizNum})
+	mart.NormAddress = k.address
+	mart.NormTel = k.tel
+	mart.NormBizNum = k.bizNum
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range append([]string{mart.Name}, mart.Aliases...) {
+		if norm := normalize.ProductName(name); norm != "" && !seen[norm] {
+			seen[norm] = true
+			names = append(names, norm)
+		}
+	}
+	mart.NormNames = names
+}
@@ -0,0 +1,54 @@
+package marts
+
+import (
+	"context"
+	"dbserver/currency"
+	"dbserver/db"
+	"dbserver/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Stats returns the visit counts and spend of uid at the given marts, converted to
+// home at the rates of each purchase date in loc. Marts without records are missing
+// from the result.
+func Stats(ctx context.Context, uid string, martIds []string, home string, loc *time.Location) (map[string]models.MartStats, error) {
+	match := bson.M{
+		"uid":         uid,
+		"record.rid":  bson.M{"$exists": true},
+		"mart.martId": bson.M{"$in": martIds},
+	}
+	stages, err := currency.Stages(ctx, match, home, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: match}}}, stages...)
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.M{
+		"_id":        "$mart.martId",
+		"visits":     bson.M{"$sum": 1},
+		"spend":      bson.M{"$sum": currency.Converted("$totalPrice")},
+		"avgBasket":  bson.M{"$avg": currency.Converted("$totalPrice")},
+		"firstVisit": bson.M{"$min": "$record.timeStamp.at"},
+		"lastVisit":  bson.M{"$max": "$record.timeStamp.at"},
+	}}})
+
+	cursor, err := db.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.MartStats
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]models.MartStats, len(results))
+	for _, s := range results {
+		stats[s.MartId] = s
+	}
+	return stats, nil
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/fingerprint"
+	"dbserver/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func init() {
+	register("fingerprints", Fingerprints)
+}
+
+// Fingerprints fills record.fingerprint in records saved before duplicate detection.
+// Existing duplicates are not flagged; only new records are checked on save.
+func Fingerprints(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{}
+
+	filter := bson.M{
+		"record.rid":         bson.M{"$exists": true},
+		"record.fingerprint": bson.M{"$exists": false},
+	}
+	cursor, err := db.Collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+
+		if !dryRun {
+			update := bson.M{"$set": bson.M{"record.fingerprint": fingerprint.Of(record)}}
+			if _, err := db.Collection.UpdateOne(ctx, bson.M{"record.rid": record.Record.Rid}, update); err != nil {
+				report.fail("User", record.Record.Rid, nil, err)
+				continue
+			}
+		}
+		report.Converted++
+	}
+
+	return report, cursor.Err()
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"dbserver/reconcile"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func init() {
+	register("record-issues", RecordIssues)
+}
+
+// RecordIssues runs the reconciliation checks on records saved before they existed.
+// Converted counts the records that got issues.
+func RecordIssues(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{}
+
+	filter := bson.M{
+		"record.rid": bson.M{"$exists": true},
+		"issues":     bson.M{"$exists": false},
+	}
+	cursor, err := db.Collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+
+		issues := reconcile.Check(record, nil, now)
+		if len(issues) == 0 {
+			continue
+		}
+		if !dryRun {
+			update := bson.M{"$set": bson.M{"issues": issues}}
+			if _, err := db.Collection.UpdateOne(ctx, bson.M{"record.rid": record.Record.Rid}, update); err != nil {
+				report.fail("User", record.Record.Rid, len(issues), err)
+				continue
+			}
+		}
+		report.Converted++
+	}
+
+	return report, cursor.Err()
+}
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/marts"
+	"dbserver/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register("marts", Marts)
+}
+
+// Marts links records saved before the mart directory to a mart of their owner,
+// creating the directory entries on the way. Older records go first so a mart is
+// named after its first receipt. A dry run only counts the records to link.
+func Marts(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{}
+
+	filter := bson.M{
+		"record.rid":    bson.M{"$exists": true},
+		"mart.martId":   bson.M{"$exists": false},
+		"mart.martName": bson.M{"$nin": bson.A{nil, ""}},
+	}
+	cursor, err := db.Collection.Find(ctx, filter, options.Find().
+		SetProjection(bson.M{"uid": 1, "record.rid": 1, "mart": 1}).
+		SetSort(bson.D{{Key: "record.timeStamp.at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record models.RecordInput
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+
+		if !dryRun {
+			if err := marts.Link(ctx, &record); err != nil {
+				report.fail("Mart", record.Record.Rid, record.Mart.MartName, err)
+				continue
+			}
+			update := bson.M{"$set": bson.M{"mart.martId": record.Mart.MartId}}
+			if _, err := db.Collection.UpdateOne(ctx, bson.M{"record.rid": record.Record.Rid}, update); err != nil {
+				report.fail("User", record.Record.Rid, record.Mart.MartId, err)
+				continue
+			}
+		}
+		report.Converted++
+	}
+
+	return report, cursor.Err()
+}
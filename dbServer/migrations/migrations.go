@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Failure is a document a migration could not convert
+type Failure struct {
+	Collection string
+	ID         string
+	Value      interface{}
+	Reason     string
+}
+
+// Report summarizes a migration run
+type Report struct {
+	Scanned   int
+	Converted int
+	Failures  []Failure
+}
+
+func (r *Report) fail(collection string, id string, value interface{}, reason error) {
+	r.Failures = append(r.Failures, Failure{
+		Collection: collection,
+		ID:         id,
+		Value:      value,
+		Reason:     reason.Error(),
+	})
+}
+
+// Print writes the report in a human readable form
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "scanned: %d, converted: %d, failed: %d\n", r.Scanned, r.Converted, len(r.Failures))
+	for _, f := range r.Failures {
+		fmt.Fprintf(w, "  [%s] %s: %v (%s)\n", f.Collection, f.ID, f.Value, f.Reason)
+	}
+}
+
+// Migration converts existing documents. When dryRun is set nothing is written.
+type Migration func(ctx context.Context, dryRun bool) (*Report, error)
+
+var registry = map[string]Migration{}
+
+func register(name string, m Migration) {
+	registry[name] = m
+}
+
+// Get returns the migration registered under name
+func Get(name string) (Migration, bool) {
+	m, ok := registry[name]
+	return m, ok
+}
+
+// Names lists the registered migrations
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
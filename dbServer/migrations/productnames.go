@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/normalize"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func init() {
+	register("product-names", ProductNames)
+}
+
+// ProductNames fills product.normName in records saved before it was computed at ingest
+func ProductNames(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{}
+
+	filter := bson.M{
+		"record.rid": bson.M{"$exists": true},
+		"product":    bson.M{"$elemMatch": bson.M{"normName": bson.M{"$exists": false}}},
+	}
+	cursor, err := db.Collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			Record struct {
+				Rid string `bson:"rid"`
+			} `bson:"record"`
+			Product []struct {
+				Pname string `bson:"pname"`
+			} `bson:"product"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+
+		set := bson.M{}
+		for i, p := range doc.Product {
+			set["product."+strconv.Itoa(i)+".normName"] = normalize.ProductName(p.Pname)
+		}
+
+		if !dryRun {
+			if _, err := db.Collection.UpdateOne(ctx, bson.M{"record.rid": doc.Record.Rid}, bson.M{"$set": set}); err != nil {
+				return nil, err
+			}
+		}
+		report.Converted++
+	}
+
+	return report, cursor.Err()
+}
@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"context"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register("timestamps", Timestamps)
+}
+
+// legacyRecord is the part of a record document written before timestamps were typed
+type legacyRecord struct {
+	Uid    string `bson:"uid"`
+	Record struct {
+		Rid       string `bson:"rid"`
+		TimeStamp string `bson:"timeStamp"`
+	} `bson:"record"`
+}
+
+type legacyHistory struct {
+	Rid      string       `bson:"rid"`
+	Version  int          `bson:"version"`
+	Uid      string       `bson:"uid"`
+	Snapshot legacyRecord `bson:"snapshot"`
+}
+
+// Timestamps converts string record.timeStamp values ("2024-11-30", "2024-1-3", ...)
+// into PurchaseTime documents in the owner's timezone, in records and in the
+// snapshots of their history.
+func Timestamps(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{}
+	locations := newLocationCache()
+
+	cursor, err := db.Collection.Find(ctx, bson.M{"record.timeStamp": bson.M{"$type": "string"}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc legacyRecord
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+
+		value := doc.Record.TimeStamp
+		timeStamp, err := models.ParsePurchaseTime(value, locations.get(ctx, doc.Uid))
+		if err != nil {
+			report.fail("User", doc.Record.Rid, value, err)
+			continue
+		}
+
+		if !dryRun {
+			_, err := db.Collection.UpdateOne(ctx,
+				bson.M{"record.rid": doc.Record.Rid, "record.timeStamp": value},
+				bson.M{"$set": bson.M{"record.timeStamp": timeStamp}},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+		report.Converted++
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	historyCursor, err := db.HistoryCollection.Find(ctx, bson.M{"snapshot.record.timeStamp": bson.M{"$type": "string"}})
+	if err != nil {
+		return nil, err
+	}
+	defer historyCursor.Close(ctx)
+
+	for historyCursor.Next(ctx) {
+		var entry legacyHistory
+		if err := historyCursor.Decode(&entry); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+
+		id := fmt.Sprintf("%s@%d", entry.Rid, entry.Version)
+		value := entry.Snapshot.Record.TimeStamp
+		timeStamp, err := models.ParsePurchaseTime(value, locations.get(ctx, entry.Uid))
+		if err != nil {
+			report.fail("RecordHistory", id, value, err)
+			continue
+		}
+
+		if !dryRun {
+			_, err := db.HistoryCollection.UpdateOne(ctx,
+				bson.M{"rid": entry.Rid, "version": entry.Version},
+				bson.M{"$set": bson.M{"snapshot.record.timeStamp": timeStamp}},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+		report.Converted++
+	}
+
+	return report, historyCursor.Err()
+}
+
+// locationCache remembers the timezone of every user seen during a migration
+type locationCache map[string]*time.Location
+
+func newLocationCache() locationCache {
+	return locationCache{}
+}
+
+func (l locationCache) get(ctx context.Context, uid string) *time.Location {
+	if loc, ok := l[uid]; ok {
+		return loc
+	}
+
+	var user models.User
+	err := db.Collection.FindOne(ctx, bson.M{"uid": uid, "record": bson.M{"$exists": false}}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return dates.LoadLocation("")
+	}
+
+	loc := dates.LoadLocation(user.TimeZone)
+	l[uid] = loc
+	return loc
+}
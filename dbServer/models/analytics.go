@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// SpendingBucket is the spending of one day, week, month, mart or category
+type SpendingBucket struct {
+	Key           string   `json:"key" bson:"_id"`
+	Total         int      `json:"total" bson:"total"`
+	Count         int      `json:"count" bson:"count"`
+	PreviousTotal *int     `json:"previousTotal,omitempty" bson:"-"`
+	Change        *float64 `json:"change,omitempty" bson:"-"`
+}
+
+// SpendingTotals summarizes the receipts of a period
+type SpendingTotals struct {
+	Total      int     `json:"total" bson:"total"`
+	Receipts   int     `json:"receipts" bson:"receipts"`
+	Items      int     `json:"items" bson:"items"`
+	AvgBasket  float64 `json:"avgBasket" bson:"avgBasket"`
+	MaxReceipt int     `json:"maxReceipt" bson:"maxReceipt"`
+	// Unconverted counts receipts left out because no exchange rate covers their date
+	Unconverted int `json:"unconverted" bson:"unconverted"`
+}
+
+// Period is a half-open date range [From, To)
+type Period struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// SpendingSummary compares a period with the one of the same length right before it
+type SpendingSummary struct {
+	Period         Period             `json:"period"`
+	PreviousPeriod Period             `json:"previousPeriod"`
+	Current        SpendingTotals     `json:"current"`
+	Previous       SpendingTotals     `json:"previous"`
+	Change         map[string]float64 `json:"change"`
+}
+
+// ProductStat is the spending on one product
+type ProductStat struct {
+	Pname     string `json:"pname" bson:"_id"`
+	Spend     int    `json:"spend" bson:"spend"`
+	Quantity  int    `json:"quantity" bson:"quantity"`
+	Purchases int    `json:"purchases" bson:"purchases"`
+}
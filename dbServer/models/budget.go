@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+const (
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodWeekly  = "weekly"
+
+	BudgetScopeOverall  = "overall"
+	BudgetScopeCategory = "category"
+	BudgetScopeMart     = "mart"
+)
+
+// DefaultBudgetThresholds are the percentages that raise an alert when none are given
+var DefaultBudgetThresholds = []int{80, 100}
+
+// Budget is a spending limit for a week or month, either overall or for one category or mart
+type Budget struct {
+	Bid        string    `json:"bid" bson:"bid"`
+	Uid        string    `json:"uid" bson:"uid"`
+	Name       string    `json:"name" bson:"name"`
+	Period     string    `json:"period" bson:"period"`
+	Scope      string    `json:"scope" bson:"scope"`
+	Target     string    `json:"target,omitempty" bson:"target,omitempty"`
+	Amount     int       `json:"amount" bson:"amount"`
+	Thresholds []int     `json:"thresholds" bson:"thresholds"`
+	CreatedAt  time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// BudgetProgress is the state of a budget in one period
+type BudgetProgress struct {
+	Budget      Budget    `json:"budget"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Spent       int       `json:"spent"`
+	Remaining   int       `json:"remaining"`
+	Percent     float64   `json:"percent"`
+	Reached     []int     `json:"reached"`
+}
+
+// BudgetAlert is raised once per budget, period and threshold
+type BudgetAlert struct {
+	Aid         string    `json:"aid" bson:"aid"`
+	Uid         string    `json:"uid" bson:"uid"`
+	Bid         string    `json:"bid" bson:"bid"`
+	BudgetName  string    `json:"budgetName" bson:"budgetName"`
+	Threshold   int       `json:"threshold" bson:"threshold"`
+	PeriodStart time.Time `json:"periodStart" bson:"periodStart"`
+	Spent       int       `json:"spent" bson:"spent"`
+	Amount      int       `json:"amount" bson:"amount"`
+	// Currency is the home currency of Spent and Amount
+	Currency  string    `json:"currency" bson:"currency,omitempty"`
+	Message   string    `json:"message" bson:"message"`
+	Read      bool      `json:"read" bson:"read"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type BudgetRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Period     string `json:"period" binding:"required,oneof=monthly weekly"`
+	Scope      string `json:"scope" binding:"required,oneof=overall category mart"`
+	Target     string `json:"target"`
+	Amount     int    `json:"amount" binding:"required,gt=0"`
+	Thresholds []int  `json:"thresholds" binding:"omitempty,dive,gt=0,lte=1000"`
+}
@@ -0,0 +1,53 @@
+package models
+
+const (
+	BulkOpDelete   = "delete"
+	BulkOpTag      = "tag"
+	BulkOpRetag    = "retag"
+	BulkOpCategory = "category"
+	BulkOpMove     = "move"
+	BulkOpDate     = "date"
+)
+
+const (
+	BulkStatusApplied    = "applied"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolledBack"
+)
+
+// BulkOperation is one change of a bulk request. Which fields are used depends on Op:
+//   - delete: removes the record with its history and photo
+//   - tag: adds Tags
+//   - retag: replaces the tags with Tags, an empty list removes them all
+//   - category: sets the category of the product Pname to Category
+//   - move: moves the record to the household GroupId, or back to the personal
+//     ledger when GroupId is empty
+//   - date: sets the purchase time to Time, read in TimeZone or the user's time zone
+type BulkOperation struct {
+	Op       string   `json:"op" binding:"required"`
+	Rid      string   `json:"rid" binding:"required"`
+	Tags     []string `json:"tags" binding:"max=20"`
+	Pname    string   `json:"pname"`
+	Category string   `json:"category" binding:"max=50"`
+	GroupId  string   `json:"groupId"`
+	Time     string   `json:"time"`
+	TimeZone string   `json:"timeZone"`
+}
+
+// BulkRequest applies operations in order. With Atomic set either every operation
+// is applied or none is; otherwise each one succeeds or fails on its own.
+type BulkRequest struct {
+	Atomic     bool            `json:"atomic"`
+	Operations []BulkOperation `json:"operations" binding:"required,min=1,max=500,dive"`
+}
+
+// BulkResult is the outcome of the operation at Index of the request
+type BulkResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Rid    string `json:"rid"`
+	Status string `json:"status"`
+	// Code is the HTTP status the single-record route would have answered with
+	Code  int    `json:"code"`
+	Error string `json:"error,omitempty"`
+}
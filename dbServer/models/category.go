@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+const (
+	CategoryRuleKeyword = "keyword"
+	CategoryRuleRegex   = "regex"
+	CategoryRuleExact   = "exact"
+
+	// 카테고리가 정해진 경로
+	CategorySourceDictionary = "dictionary"
+	CategorySourceRule       = "rule"
+	CategorySourceLearned    = "learned"
+	CategorySourceManual     = "manual"
+
+	CategoryJobRunning = "running"
+	CategoryJobDone    = "done"
+	CategoryJobFailed  = "failed"
+)
+
+// CategoryRule assigns a category to products whose name matches Pattern.
+// Exact rules are learned from the user's own corrections and win over every other rule.
+type CategoryRule struct {
+	RuleId    string    `json:"ruleId" bson:"ruleId"`
+	Uid       string    `json:"uid" bson:"uid"`
+	Type      string    `json:"type" bson:"type"`
+	Pattern   string    `json:"pattern" bson:"pattern"`
+	Category  string    `json:"category" bson:"category"`
+	Priority  int       `json:"priority" bson:"priority"`
+	Learned   bool      `json:"learned" bson:"learned"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// CategoryJob tracks a background re-categorization of a user's records
+type CategoryJob struct {
+	JobId      string     `json:"jobId" bson:"jobId"`
+	Uid        string     `json:"uid" bson:"uid"`
+	Status     string     `json:"status" bson:"status"`
+	Scanned    int        `json:"scanned" bson:"scanned"`
+	Updated    int        `json:"updated" bson:"updated"`
+	Error      string     `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt" bson:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+type CategoryRuleRequest struct {
+	Type     string `json:"type" binding:"required,oneof=keyword regex"`
+	Pattern  string `json:"pattern" binding:"required,max=200"`
+	Category string `json:"category" binding:"required,max=50"`
+	Priority int    `json:"priority"`
+}
+
+type UpdateCategoryRequest struct {
+	Rid      string `json:"rid" binding:"required"`
+	Pname    string `json:"pname" binding:"required"`
+	Category string `json:"category" binding:"required,max=50"`
+}
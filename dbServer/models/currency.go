@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ExchangeRate is one row of the local exchange-rate table. Rates are kept
+// against the won: Rate is how many won one major unit of Currency was worth on
+// Date, e.g. {USD, 2024-11-29, 1395.5}. Other pairs are converted through the won.
+type ExchangeRate struct {
+	Currency  string    `json:"currency" bson:"currency" binding:"required"`
+	Date      string    `json:"date" bson:"date" binding:"required"`
+	Rate      float64   `json:"rate" bson:"rate" binding:"required,gt=0"`
+	Source    string    `json:"source,omitempty" bson:"source,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+const (
+	RateSourceFile  = "file"
+	RateSourceAdmin = "admin"
+)
+
+// Currency describes a supported currency. Amounts are stored in minor units,
+// so a price of 1250 is 12.50 in a currency with Exponent 2.
+type Currency struct {
+	Code     string `json:"code"`
+	Exponent int    `json:"exponent"`
+}
+
+type ExchangeRatesRequest struct {
+	Rates []ExchangeRate `json:"rates" binding:"required,min=1,max=5000,dive"`
+}
+
+type UpdateCurrencyRequest struct {
+	Currency string `json:"currency" binding:"required"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// DuplicatePair is a record flagged as a likely duplicate together with the record it
+// appears to duplicate
+type DuplicatePair struct {
+	Duplicate RecordInput  `json:"duplicate"`
+	Original  *RecordInput `json:"original"`
+}
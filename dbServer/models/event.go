@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	RecordEventCreated = "created"
+	RecordEventUpdated = "updated"
+	RecordEventDeleted = "deleted"
+)
+
+// RecordEvent is a change of a record pushed to open clients. Seq orders the events
+// of both servers and is the id clients resume from after a reconnect.
+type RecordEvent struct {
+	Seq     int64  `json:"id" bson:"seq"`
+	Type    string `json:"type" bson:"type"`
+	Rid     string `json:"rid" bson:"rid"`
+	Uid     string `json:"uid" bson:"uid"`
+	GroupId string `json:"groupId,omitempty" bson:"groupId,omitempty"`
+	// PrevGroupId is the household a moved record left; its members see the event as deleted
+	PrevGroupId string    `json:"-" bson:"prevGroupId,omitempty"`
+	Actor       string    `json:"actor" bson:"actor"`
+	At          time.Time `json:"at" bson:"at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// GraphQLRequest is the body of POST /graphql
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
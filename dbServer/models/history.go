@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	HistoryActionCreate   = "create"
+	HistoryActionOriginal = "original"
+	HistoryActionUpdate   = "update"
+	HistoryActionRevert   = "revert"
+)
+
+// RecordHistory is one stored version of a record together with the change that produced it
+type RecordHistory struct {
+	Rid        string        `json:"rid" bson:"rid"`
+	Uid        string        `json:"uid" bson:"uid"`
+	Version    int           `json:"version" bson:"version"`
+	Action     string        `json:"action" bson:"action"`
+	Actor      string        `json:"actor" bson:"actor"`
+	RevertedTo int           `json:"revertedTo,omitempty" bson:"revertedTo,omitempty"`
+	Changes    []FieldChange `json:"changes" bson:"changes"`
+	Snapshot   RecordInput   `json:"snapshot" bson:"snapshot"`
+	CreatedAt  time.Time     `json:"createdAt" bson:"createdAt"`
+}
+
+// FieldChange describes a single field that differs between two versions.
+// Field is the dotted bson path, e.g. "product.1.price".
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
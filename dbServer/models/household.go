@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+const (
+	HouseholdRoleOwner  = "owner"
+	HouseholdRoleEditor = "editor"
+	HouseholdRoleViewer = "viewer"
+)
+
+// Household is a group of users sharing a ledger. Records with a groupId belong to
+// the household instead of the user who saved them.
+type Household struct {
+	GroupId   string            `json:"groupId" bson:"groupId"`
+	Name      string            `json:"name" bson:"name"`
+	Members   []HouseholdMember `json:"members" bson:"members"`
+	CreatedAt time.Time         `json:"createdAt" bson:"createdAt"`
+}
+
+type HouseholdMember struct {
+	Uid      string    `json:"uid" bson:"uid"`
+	Role     string    `json:"role" bson:"role"`
+	JoinedAt time.Time `json:"joinedAt" bson:"joinedAt"`
+}
+
+// HouseholdInvitation lets whoever holds the token join the household with Role
+type HouseholdInvitation struct {
+	InviteId   string     `json:"inviteId" bson:"inviteId"`
+	GroupId    string     `json:"groupId" bson:"groupId"`
+	Token      string     `json:"token,omitempty" bson:"token"`
+	Role       string     `json:"role" bson:"role"`
+	InvitedBy  string     `json:"invitedBy" bson:"invitedBy"`
+	ExpiresAt  time.Time  `json:"expiresAt" bson:"expiresAt"`
+	AcceptedBy string     `json:"acceptedBy,omitempty" bson:"acceptedBy,omitempty"`
+	AcceptedAt *time.Time `json:"acceptedAt,omitempty" bson:"acceptedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+}
+
+type HouseholdRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type InvitationRequest struct {
+	Role string `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// MoveRecordRequest moves a record into a household, or back to the personal ledger
+// of the user who saved it when GroupId is empty
+type MoveRecordRequest struct {
+	GroupId string `json:"groupId"`
+}
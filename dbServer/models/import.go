@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	ImportStatusPreview   = "preview"
+	ImportStatusCommitted = "committed"
+	ImportStatusUndone    = "undone"
+)
+
+// ColumnMapping names the CSV header used for each record field. Only Date, Merchant
+// and Amount are required.
+type ColumnMapping struct {
+	Date     string `json:"date" bson:"date" binding:"required"`
+	Time     string `json:"time,omitempty" bson:"time,omitempty"`
+	Merchant string `json:"merchant" bson:"merchant" binding:"required"`
+	Amount   string `json:"amount" bson:"amount" binding:"required"`
+	Item     string `json:"item,omitempty" bson:"item,omitempty"`
+	Note     string `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// ImportRow is one validated row of an import file
+type ImportRow struct {
+	Row         int          `json:"row" bson:"row"`
+	TimeStamp   PurchaseTime `json:"timeStamp" bson:"timeStamp"`
+	MartName    string       `json:"martName" bson:"martName"`
+	Item        string       `json:"item,omitempty" bson:"item,omitempty"`
+	Amount      int          `json:"amount" bson:"amount"`
+	Note        string       `json:"note,omitempty" bson:"note,omitempty"`
+	Errors      []string     `json:"errors,omitempty" bson:"errors,omitempty"`
+	DuplicateOf string       `json:"duplicateOf,omitempty" bson:"duplicateOf,omitempty"`
+	Rid         string       `json:"rid,omitempty" bson:"rid,omitempty"`
+}
+
+type ImportSummary struct {
+	Total      int `json:"total" bson:"total"`
+	Valid      int `json:"valid" bson:"valid"`
+	Invalid    int `json:"invalid" bson:"invalid"`
+	Duplicates int `json:"duplicates" bson:"duplicates"`
+	Imported   int `json:"imported" bson:"imported"`
+}
+
+// ImportBatch is an uploaded file going through preview, commit and possibly undo.
+// The raw cells are kept so the mapping can be changed before committing.
+type ImportBatch struct {
+	BatchId     string        `json:"batchId" bson:"batchId"`
+	Uid         string        `json:"uid" bson:"uid"`
+	Status      string        `json:"status" bson:"status"`
+	FileName    string        `json:"fileName" bson:"fileName"`
+	Preset      string        `json:"preset,omitempty" bson:"preset,omitempty"`
+	Header      []string      `json:"header" bson:"header"`
+	Cells       [][]string    `json:"-" bson:"cells"`
+	Mapping     ColumnMapping `json:"mapping" bson:"mapping"`
+	Rows        []ImportRow   `json:"rows,omitempty" bson:"rows"`
+	Summary     ImportSummary `json:"summary" bson:"summary"`
+	CreatedAt   time.Time     `json:"createdAt" bson:"createdAt"`
+	CommittedAt *time.Time    `json:"committedAt,omitempty" bson:"committedAt,omitempty"`
+	UndoneAt    *time.Time    `json:"undoneAt,omitempty" bson:"undoneAt,omitempty"`
+}
+
+type CommitImportRequest struct {
+	// IncludeDuplicates imports rows that look like existing records as well
+	IncludeDuplicates bool `json:"includeDuplicates"`
+}
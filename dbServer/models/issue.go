@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	// IssueSumMismatch: the lines less the receipt discount do not add up to the total
+	IssueSumMismatch = "sum_mismatch"
+	// IssueZeroTotal: a receipt with lines has a total of 0
+	IssueZeroTotal = "zero_total"
+	// IssueBadPrice: a line costs 0, or less than 0 on a receipt that is not a refund
+	IssueBadPrice = "bad_price"
+	// IssueBadAmount: a line has an amount below 1 or implausibly large
+	IssueBadAmount = "bad_amount"
+	// IssueDiscountTooLarge: a line discount is more than the line costs
+	IssueDiscountTooLarge = "discount_too_large"
+)
+
+// RecordIssue is a reconciliation problem found on a receipt. Issues are found again
+// after every edit; a resolved issue stays resolved while its values are unchanged.
+type RecordIssue struct {
+	IssueId string `json:"issueId" bson:"issueId"`
+	Code    string `json:"code" bson:"code"`
+	// Pname is the line of a line-level issue
+	Pname      string     `json:"pname,omitempty" bson:"pname,omitempty"`
+	Expected   int        `json:"expected" bson:"expected"`
+	Actual     int        `json:"actual" bson:"actual"`
+	Resolved   bool       `json:"resolved" bson:"resolved"`
+	ResolvedBy string     `json:"resolvedBy,omitempty" bson:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+	DetectedAt time.Time  `json:"detectedAt" bson:"detectedAt"`
+}
+
+// ResolveIssueRequest marks an issue resolved, or open again with Resolved false
+type ResolveIssueRequest struct {
+	Resolved *bool `json:"resolved"`
+}
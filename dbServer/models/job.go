@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobState is the state of a scheduled job shared by the replicas. It is also the
+// lock: only the replica named in LockedBy runs the job until LockedUntil.
+type JobState struct {
+	Name        string     `json:"name" bson:"_id"`
+	Schedule    string     `json:"schedule" bson:"schedule"`
+	NextRun     time.Time  `json:"nextRun" bson:"nextRun"`
+	LockedBy    string     `json:"lockedBy,omitempty" bson:"lockedBy,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty" bson:"lockedUntil,omitempty"`
+}
+
+// JobRun is one run of a job, kept as its history
+type JobRun struct {
+	RunId    string `json:"runId" bson:"runId"`
+	Job      string `json:"job" bson:"job"`
+	Trigger  string `json:"trigger" bson:"trigger"`
+	Instance string `json:"instance" bson:"instance"`
+	Status   string `json:"status" bson:"status"`
+	// Attempts counts the tries, retries included
+	Attempts   int        `json:"attempts" bson:"attempts"`
+	Message    string     `json:"message,omitempty" bson:"message,omitempty"`
+	Error      string     `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt" bson:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
+
+// JobInfo is a job as listed by the admin API
+type JobInfo struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Schedule    string     `json:"schedule"`
+	NextRun     *time.Time `json:"nextRun,omitempty"`
+	Running     bool       `json:"running"`
+	LastRun     *JobRun    `json:"lastRun,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Mart is an entry of a user's store directory. Records point to it with
+// mart.martId and keep a copy of its name, address and phone number.
+type Mart struct {
+	MartId  string `json:"martId" bson:"martId"`
+	Uid     string `json:"uid" bson:"uid"`
+	Name    string `json:"name" bson:"name"`
+	Address string `json:"address,omitempty" bson:"address,omitempty"`
+	Tel     string `json:"tel,omitempty" bson:"tel,omitempty"`
+	BizNum  string `json:"bizNum,omitempty" bson:"bizNum,omitempty"`
+	// Aliases are the other spellings of the name found on receipts
+	Aliases []string `json:"aliases" bson:"aliases"`
+	// 중복 판별에 쓰는 정규화 키
+	NormNames   []string  `json:"-" bson:"normNames"`
+	NormAddress string    `json:"-" bson:"normAddress,omitempty"`
+	NormTel     string    `json:"-" bson:"normTel,omitempty"`
+	NormBizNum  string    `json:"-" bson:"normBizNum,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// MartStats is what a user spent at a mart, in their home currency
+type MartStats struct {
+	MartId     string     `json:"-" bson:"_id"`
+	Visits     int        `json:"visits" bson:"visits"`
+	Spend      int        `json:"spend" bson:"spend"`
+	AvgBasket  float64    `json:"avgBasket" bson:"avgBasket"`
+	FirstVisit *time.Time `json:"firstVisit,omitempty" bson:"firstVisit"`
+	LastVisit  *time.Time `json:"lastVisit,omitempty" bson:"lastVisit"`
+}
+
+// MartSummary is a mart of the directory with its visit count and spend
+type MartSummary struct {
+	Mart
+	Stats MartStats `json:"stats"`
+}
+
+type EditMartRequest struct {
+	Name    *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Address *string `json:"address" binding:"omitempty,max=200"`
+	Tel     *string `json:"tel" binding:"omitempty,max=30"`
+	BizNum  *string `json:"bizNum" binding:"omitempty,max=20"`
+}
+
+// MergeMartsRequest moves the records of Sources to the mart in the path and
+// deletes the sources
+type MergeMartsRequest struct {
+	Sources []string `json:"sources" binding:"required,min=1,max=50"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import "time"
+
+// 알림 종류
+const (
+	NotificationBudgetAlert    = "budget.alert"
+	NotificationOCRFailed      = "ocr.failed"
+	NotificationNewDeviceLogin = "login.new-device"
+	NotificationMonthlyReport  = "report.monthly"
+)
+
+// 알림 채널
+const (
+	ChannelInbox   = "inbox"
+	ChannelEmail   = "email"
+	ChannelWebPush = "webpush"
+	ChannelMobile  = "mobile"
+)
+
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped"
+)
+
+// Notification is a message to a user. Any server may queue one with Kind and Data;
+// dbServer renders it in the user's language and delivers it on the channels the user
+// chose. It is also the entry of the in-app inbox once delivered there.
+type Notification struct {
+	Nid  string            `json:"nid" bson:"nid"`
+	Uid  string            `json:"-" bson:"uid"`
+	Kind string            `json:"kind" bson:"kind"`
+	Data map[string]string `json:"data,omitempty" bson:"data,omitempty"`
+
+	Title string `json:"title" bson:"title,omitempty"`
+	Body  string `json:"body" bson:"body,omitempty"`
+	// Channels are those still to be delivered, chosen when the notification is rendered
+	Channels   []string   `json:"-" bson:"channels,omitempty"`
+	Deliveries []Delivery `json:"-" bson:"deliveries,omitempty"`
+	Inbox      bool       `json:"-" bson:"inbox"`
+	Status     string     `json:"-" bson:"status"`
+	Attempts   int        `json:"-" bson:"attempts"`
+	// DeliverAfter holds the external channels back, e.g. until quiet hours end
+	DeliverAfter time.Time  `json:"-" bson:"deliverAfter"`
+	LockedUntil  *time.Time `json:"-" bson:"lockedUntil,omitempty"`
+	ReadAt       *time.Time `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt" bson:"createdAt"`
+}
+
+// Delivery is the outcome of sending a notification on one channel
+type Delivery struct {
+	Channel string    `json:"channel" bson:"channel"`
+	Status  string    `json:"status" bson:"status"`
+	Error   string    `json:"error,omitempty" bson:"error,omitempty"`
+	At      time.Time `json:"at" bson:"at"`
+}
+
+// NotificationPreferences are the notification settings of a user
+type NotificationPreferences struct {
+	Uid string `json:"-" bson:"uid"`
+	// Language of the templates, "ko" or "en"
+	Language string `json:"language" bson:"language"`
+	// Channels lists the channels of each kind; kinds left out use the defaults
+	Channels   map[string][]string `json:"channels" bson:"channels"`
+	QuietHours *QuietHours         `json:"quietHours,omitempty" bson:"quietHours,omitempty"`
+}
+
+// QuietHours hold push and email back between Start and End, "HH:MM" in the user's
+// time zone. End may be earlier than Start for a range over midnight.
+type QuietHours struct {
+	Start string `json:"start" bson:"start" binding:"required"`
+	End   string `json:"end" bson:"end" binding:"required"`
+}
+
+type NotificationPreferencesRequest struct {
+	Language   *string             `json:"language" binding:"omitempty,oneof=ko en"`
+	Channels   map[string][]string `json:"channels"`
+	QuietHours *QuietHours         `json:"quietHours"`
+	// ClearQuietHours turns quiet hours off
+	ClearQuietHours bool `json:"clearQuietHours"`
+}
+
+// PushSubscription is a browser subscribed to web push
+type PushSubscription struct {
+	Uid      string   `json:"-" bson:"uid"`
+	Endpoint string   `json:"endpoint" bson:"endpoint" binding:"required,url"`
+	Keys     PushKeys `json:"keys" bson:"keys" binding:"required"`
+	// CreatedAt is set by the server
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type PushKeys struct {
+	P256dh string `json:"p256dh" bson:"p256dh" binding:"required"`
+	Auth   string `json:"auth" bson:"auth" binding:"required"`
+}
+
+// PushDevice is a mobile app registered with the push gateway
+type PushDevice struct {
+	Uid       string    `json:"-" bson:"uid"`
+	Token     string    `json:"token" bson:"token" binding:"required"`
+	Platform  string    `json:"platform" bson:"platform" binding:"required,oneof=ios android"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
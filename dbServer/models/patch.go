@@ -0,0 +1,40 @@
+package models
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// RecordDocument is the editable form of a record that PATCH /records/:rid patches.
+// Time is the local purchase time ("2006-01-02 15:04:05", or only the date when the
+// time of day is unknown) in TimeZone. TotalPrice is recomputed from the products
+// and the discount when they change, unless the patch sets it too.
+type RecordDocument struct {
+	Rname      string            `json:"rname" binding:"required,max=100"`
+	Time       string            `json:"time" binding:"required"`
+	TimeZone   string            `json:"timeZone" binding:"required"`
+	Note       string            `json:"note" binding:"max=2000"`
+	Tags       []string          `json:"tags" binding:"max=20"`
+	Currency   string            `json:"currency" binding:"required"`
+	Mart       DocumentMart      `json:"mart"`
+	Products   []DocumentProduct `json:"products" binding:"required,min=1,dive"`
+	Discount   int               `json:"discount" binding:"min=0"`
+	TotalPrice int               `json:"totalPrice"`
+	Tax        *TaxInfo          `json:"tax"`
+	Payment    *PaymentInfo      `json:"payment"`
+}
+
+type DocumentMart struct {
+	MartName    string `json:"martName" binding:"max=100"`
+	MartAddress string `json:"martAddress" binding:"max=200"`
+	Tel         string `json:"tel" binding:"max=30"`
+	BizNum      string `json:"bizNum" binding:"max=20"`
+}
+
+type DocumentProduct struct {
+	Pname    string `json:"pname" binding:"required,max=100"`
+	Price    int    `json:"price"`
+	Amount   int    `json:"amount" binding:"gte=1"`
+	Discount int    `json:"discount" binding:"min=0"`
+	Category string `json:"category" binding:"max=50"`
+}
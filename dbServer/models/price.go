@@ -0,0 +1,53 @@
+package models
+
+// PricePoint is one purchase of a product
+type PricePoint struct {
+	// Product is the normalized name the purchase was grouped by
+	Product   string       `json:"-" bson:"product,omitempty"`
+	Rid       string       `json:"rid" bson:"rid"`
+	TimeStamp PurchaseTime `json:"timeStamp" bson:"timeStamp"`
+	MartName  string       `json:"martName" bson:"martName"`
+	Pname     string       `json:"pname" bson:"pname"`
+	UnitPrice int          `json:"unitPrice" bson:"unitPrice"`
+	Amount    int          `json:"amount" bson:"amount"`
+}
+
+// PriceStats summarizes unit prices
+type PriceStats struct {
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Avg   float64 `json:"avg"`
+	Count int     `json:"count"`
+	Sum   int     `json:"-"`
+}
+
+// MartPriceStats summarizes the unit prices paid at one mart
+type MartPriceStats struct {
+	MartName string `json:"martName"`
+	PriceStats
+	Last int `json:"last"`
+}
+
+// PriceHistory is every purchase of one normalized product
+type PriceHistory struct {
+	Product      string           `json:"product"`
+	Currency     string           `json:"currency"`
+	Names        []string         `json:"names"`
+	Purchases    []PricePoint     `json:"purchases"`
+	Stats        PriceStats       `json:"stats"`
+	ByMart       []MartPriceStats `json:"byMart"`
+	CheapestMart string           `json:"cheapestMart,omitempty"`
+}
+
+// Add includes one unit price in the stats
+func (s *PriceStats) Add(price int) {
+	if s.Count == 0 || price < s.Min {
+		s.Min = price
+	}
+	if s.Count == 0 || price > s.Max {
+		s.Max = price
+	}
+	s.Count++
+	s.Sum += price
+	s.Avg = float64(s.Sum) / float64(s.Count)
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"dbserver/dates"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// PurchaseTime is the moment a receipt was issued. At is stored in UTC; TimeZone is the
+// zone the purchase happened in and HasTime is false when only the date is known
+// (At is then midnight in TimeZone).
+type PurchaseTime struct {
+	At       time.Time `bson:"at"`
+	TimeZone string    `bson:"timeZone"`
+	HasTime  bool      `bson:"hasTime"`
+}
+
+// NewPurchaseTime builds a PurchaseTime from a time carrying its location
+func NewPurchaseTime(t time.Time, hasTime bool) PurchaseTime {
+	return PurchaseTime{
+		At:       t.UTC(),
+		TimeZone: t.Location().String(),
+		HasTime:  hasTime,
+	}
+}
+
+// ParsePurchaseTime reads a receipt date string in loc
+func ParsePurchaseTime(value string, loc *time.Location) (PurchaseTime, error) {
+	t, hasTime, err := dates.Parse(value, loc)
+	if err != nil {
+		return PurchaseTime{}, err
+	}
+	return NewPurchaseTime(t, hasTime), nil
+}
+
+// Local returns the purchase time in the zone it happened in
+func (p PurchaseTime) Local() time.Time {
+	return p.At.In(dates.LoadLocation(p.TimeZone))
+}
+
+// Date returns the purchase date as YYYY-MM-DD
+func (p PurchaseTime) Date() string {
+	if p.At.IsZero() {
+		return ""
+	}
+	return p.Local().Format(dates.DateLayout)
+}
+
+func (p PurchaseTime) MarshalJSON() ([]byte, error) {
+	if p.At.IsZero() {
+		return []byte("null"), nil
+	}
+
+	local := p.Local()
+	out := struct {
+		At       string  `json:"at"`
+		Date     string  `json:"date"`
+		Time     *string `json:"time"`
+		TimeZone string  `json:"timeZone"`
+	}{
+		At:       local.Format(time.RFC3339),
+		Date:     local.Format(dates.DateLayout),
+		TimeZone: p.TimeZone,
+	}
+	if p.HasTime {
+		clock := local.Format("15:04:05")
+		out.Time = &clock
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalBSONValue also accepts the legacy "year-month-day" strings and plain dates
+// so documents that have not been migrated yet can still be read.
+func (p *PurchaseTime) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+
+	switch t {
+	case bsontype.EmbeddedDocument:
+		type plain PurchaseTime
+		var decoded plain
+		if err := raw.Unmarshal(&decoded); err != nil {
+			return err
+		}
+		*p = PurchaseTime(decoded)
+	case bsontype.String:
+		parsed, err := ParsePurchaseTime(raw.StringValue(), dates.LoadLocation(""))
+		if err != nil {
+			// 해석할 수 없는 값은 비워 두고 마이그레이션에서 보고
+			*p = PurchaseTime{}
+			return nil
+		}
+		*p = parsed
+	case bsontype.DateTime:
+		*p = PurchaseTime{At: raw.Time().UTC(), TimeZone: dates.DefaultTimeZone}
+	case bsontype.Null, bsontype.Undefined:
+		*p = PurchaseTime{}
+	default:
+		return fmt.Errorf("cannot decode %v into PurchaseTime", t)
+	}
+	return nil
+}
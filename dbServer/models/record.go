@@ -2,35 +2,88 @@ package models
 
 type RecordInput struct {
 	Uid        string      `bson:"uid"`
+	GroupId    string      `bson:"groupId,omitempty"`
 	Record     DBRecord    `bson:"record"`
 	Mart       DBMart      `bson:"mart"`
 	Product    []DBProduct `bson:"product"`
 	TotalPrice int         `bson:"totalPrice"`
+	// Currency is an ISO 4217 code, won when missing. Prices and totals are in its minor units.
+	Currency string `bson:"currency,omitempty"`
+	// Discount is taken off the whole receipt, on top of the line item discounts
+	Discount int          `bson:"discount,omitempty"`
+	Tax      *TaxInfo     `bson:"tax,omitempty"`
+	Payment  *PaymentInfo `bson:"payment,omitempty"`
+	Split    *RecordSplit `bson:"split,omitempty"`
+	// Image is the receipt photo of records read by OCR
+	Image *ReceiptImage `bson:"image,omitempty"`
+	// Issues are the reconciliation problems found on the receipt
+	Issues []RecordIssue `bson:"issues,omitempty"`
+}
+
+// TaxInfo is the VAT breakdown printed on a receipt
+type TaxInfo struct {
+	Supply  int `json:"supply" bson:"supply"`
+	Vat     int `json:"vat" bson:"vat"`
+	TaxFree int `json:"taxFree,omitempty" bson:"taxFree,omitempty"`
+}
+
+const (
+	PaymentMethodCard = "card"
+	PaymentMethodCash = "cash"
+)
+
+// PaymentInfo is how a receipt was paid. CardNumber is always stored masked.
+type PaymentInfo struct {
+	Method         string `json:"method" bson:"method"`
+	CardCompany    string `json:"cardCompany,omitempty" bson:"cardCompany,omitempty"`
+	CardNumber     string `json:"cardNumber,omitempty" bson:"cardNumber,omitempty"`
+	ApprovalNumber string `json:"approvalNumber,omitempty" bson:"approvalNumber,omitempty"`
 }
 
 type DBRecord struct {
-	Rid       string `bson:"rid"`
-	Rname     string `bson:"rname"`
-	TimeStamp string `bson:"timeStamp"`
+	Rid           string       `bson:"rid"`
+	Rname         string       `bson:"rname"`
+	TimeStamp     PurchaseTime `bson:"timeStamp"`
+	Tags          []string     `bson:"tags,omitempty"`
+	Note          string       `bson:"note,omitempty"`
+	ImportBatchId string       `bson:"importBatchId,omitempty"`
+	Fingerprint   string       `bson:"fingerprint,omitempty"`
+	DuplicateOf   string       `bson:"duplicateOf,omitempty"`
 }
 
 type DBProduct struct {
-	Pname  string `bson:"pname"`
-	Price  int    `bson:"price"`
-	Amount int    `bson:"amount"`
+	Pname          string `bson:"pname"`
+	NormName       string `bson:"normName,omitempty"`
+	Price          int    `bson:"price"`
+	Amount         int    `bson:"amount"`
+	Category       string `bson:"category,omitempty"`
+	CategorySource string `bson:"categorySource,omitempty"`
+	// Discount is taken off this line, e.g. a "1+1" or member discount
+	Discount int `bson:"discount,omitempty"`
+}
+
+// LineTotal is what was paid for the line: price times amount less its discount
+func (p DBProduct) LineTotal() int {
+	return p.Price*p.Amount - p.Discount
 }
 
 type DBMart struct {
+	// MartId points to the mart directory entry of the record's owner
+	MartId      string `bson:"martId,omitempty"`
 	MartAddress string `bson:"martAddress"`
 	MartName    string `bson:"martName"`
 	Tel         string `bson:"tel"`
+	BizNum      string `bson:"bizNum,omitempty"`
 }
 
 type RecordList struct {
-	Uid        string     `bson:"uid"`
-	Record     DBRecord   `bson:"record"`
-	Mart       SimpleMart `bson:"mart"`
-	TotalPrice int        `bson:"totalPrice"`
+	Uid        string        `bson:"uid"`
+	GroupId    string        `bson:"groupId,omitempty"`
+	Record     DBRecord      `bson:"record"`
+	Mart       SimpleMart    `bson:"mart"`
+	Currency   string        `bson:"currency,omitempty"`
+	TotalPrice int           `bson:"totalPrice"`
+	Issues     []RecordIssue `bson:"issues,omitempty"`
 }
 
 type SimpleMart struct {
@@ -55,10 +108,20 @@ type GetProductInfoRequest struct {
 }
 
 type UpdateProductRequest struct {
-	Rid       string `json:"rid" binding:"required"`
-	Pname     string `json:"pname" binding:"required"`
-	NewPrice  int    `json:"price" binding:"required"`
-	NewAmount int    `json:"amount" binding:"required"`
+	Rid         string `json:"rid" binding:"required"`
+	Pname       string `json:"pname" binding:"required"`
+	NewPrice    int    `json:"price" binding:"required"`
+	NewAmount   int    `json:"amount" binding:"required"`
+	NewDiscount *int   `json:"discount" binding:"omitempty,min=0"`
+}
+
+// UpdatePaymentRequest edits the receipt-level discount, tax and payment method.
+// Fields that are not sent are left unchanged.
+type UpdatePaymentRequest struct {
+	Rid      string       `json:"rid" binding:"required"`
+	Discount *int         `json:"discount" binding:"omitempty,min=0"`
+	Tax      *TaxInfo     `json:"tax"`
+	Payment  *PaymentInfo `json:"payment"`
 }
 
 type UpdateMartRequest struct {
@@ -69,7 +132,52 @@ type UpdateMartRequest struct {
 }
 
 type UpdateRecordRequest struct {
-	Rid      string `json:"rid" binding:"required"`
-	NewRname string `json:"newRname" binding:"required"`
-	NewTime  string `json:"newTime" binding:"required"`
+	Rid         string `json:"rid" binding:"required"`
+	NewRname    string `json:"newRname" binding:"required"`
+	NewTime     string `json:"newTime" binding:"required"`
+	NewTimeZone string `json:"newTimeZone"`
+	NewCurrency string `json:"newCurrency"`
+}
+
+type UpdateTimeZoneRequest struct {
+	TimeZone string `json:"timeZone" binding:"required"`
+}
+
+// CreateRecordRequest is a receipt entered by hand
+type CreateRecordRequest struct {
+	Rname      string                 `json:"rname"`
+	Time       string                 `json:"time" binding:"required"`
+	TimeZone   string                 `json:"timeZone"`
+	Currency   string                 `json:"currency"`
+	Mart       CreateMartRequest      `json:"mart" binding:"required"`
+	Products   []CreateProductRequest `json:"products" binding:"required,min=1,dive"`
+	TotalPrice *int                   `json:"totalPrice"`
+	Discount   int                    `json:"discount" binding:"min=0"`
+	Tax        *TaxInfo               `json:"tax"`
+	Payment    *PaymentInfo           `json:"payment"`
+	// GroupId saves the record to a household instead of the personal ledger
+	GroupId string `json:"groupId"`
+}
+
+type CreateMartRequest struct {
+	MartName    string `json:"martName" binding:"required"`
+	MartAddress string `json:"martAddress"`
+	Tel         string `json:"tel"`
+	BizNum      string `json:"bizNum"`
+}
+
+type CreateProductRequest struct {
+	Pname    string `json:"pname" binding:"required"`
+	Price    int    `json:"price"`
+	Amount   int    `json:"amount" binding:"gte=1"`
+	Discount int    `json:"discount" binding:"min=0"`
+}
+
+type RecordTagsRequest struct {
+	Rids []string `json:"rids" binding:"required,min=1,max=500"`
+	Tags []string `json:"tags" binding:"required,min=1,max=20"`
+}
+
+type UpdateNoteRequest struct {
+	Note string `json:"note" binding:"max=2000"`
 }
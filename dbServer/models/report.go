@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// MonthlyReport holds the figures of a monthly spending report, in the home currency
+type MonthlyReport struct {
+	Month    string         `json:"month"`
+	Period   Period         `json:"period"`
+	Currency string         `json:"currency"`
+	Totals   SpendingTotals `json:"totals"`
+	// PreviousTotal is the spend of the month before, for comparison
+	PreviousTotal int              `json:"previousTotal"`
+	Change        *float64         `json:"change,omitempty"`
+	Categories    []SpendingBucket `json:"categories"`
+	Marts         []SpendingBucket `json:"marts"`
+	Products      []ProductStat    `json:"products"`
+	// Days has a bucket for every day of the month, empty days included
+	Days        []SpendingBucket `json:"days"`
+	GeneratedAt time.Time        `json:"generatedAt"`
+}
+
+type UpdateReportRequest struct {
+	Monthly *bool `json:"monthly" binding:"required"`
+}
+
+// ReportFile is a rendered report. It is served again until the hash of the records
+// it was built from changes.
+type ReportFile struct {
+	Uid       string    `bson:"uid"`
+	Group     string    `bson:"group"`
+	Month     string    `bson:"month"`
+	Hash      string    `bson:"hash"`
+	Pdf       []byte    `bson:"pdf"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
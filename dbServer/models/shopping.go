@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+const (
+	ShoppingSourceManual    = "manual"
+	ShoppingSourceRecurring = "recurring"
+)
+
+// ShoppingItem is an entry of a user's shopping list
+type ShoppingItem struct {
+	ItemId    string     `json:"itemId" bson:"itemId"`
+	Uid       string     `json:"uid" bson:"uid"`
+	Name      string     `json:"name" bson:"name"`
+	NormName  string     `json:"normName" bson:"normName"`
+	Quantity  int        `json:"quantity" bson:"quantity"`
+	Note      string     `json:"note,omitempty" bson:"note,omitempty"`
+	Source    string     `json:"source" bson:"source"`
+	Checked   bool       `json:"checked" bson:"checked"`
+	CheckedAt *time.Time `json:"checkedAt,omitempty" bson:"checkedAt,omitempty"`
+	// BoughtRid is the receipt that ticked the item off, empty when ticked by hand
+	BoughtRid string    `json:"boughtRid,omitempty" bson:"boughtRid,omitempty"`
+	AddedAt   time.Time `json:"addedAt" bson:"addedAt"`
+}
+
+// RecurringItem is a product bought at a regular interval
+type RecurringItem struct {
+	NormName      string    `json:"normName"`
+	Pname         string    `json:"pname"`
+	Purchases     int       `json:"purchases"`
+	IntervalDays  float64   `json:"intervalDays"`
+	LastPurchased time.Time `json:"lastPurchased"`
+	NextDue       time.Time `json:"nextDue"`
+	// DaysUntilDue is negative when the item is overdue
+	DaysUntilDue int `json:"daysUntilDue"`
+	// Regularity is 1 for a perfectly steady interval and falls towards 0 as it varies
+	Regularity float64 `json:"regularity"`
+	OnList     bool    `json:"onList"`
+}
+
+type ShoppingItemRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Quantity int    `json:"quantity" binding:"omitempty,min=1,max=999"`
+	Note     string `json:"note" binding:"max=500"`
+}
+
+type UpdateShoppingItemRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Quantity *int    `json:"quantity" binding:"omitempty,min=1,max=999"`
+	Note     *string `json:"note" binding:"omitempty,max=500"`
+	Checked  *bool   `json:"checked"`
+}
+
+// AddSuggestionsRequest adds recurring items due within Within days to the list.
+// NormNames limits it to the given products.
+type AddSuggestionsRequest struct {
+	Within    int      `json:"within" binding:"min=0,max=60"`
+	NormNames []string `json:"normNames"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// Person is someone a receipt can be split with. People belong to the personal ledger
+// of the user who added them or to a household.
+type Person struct {
+	PersonId  string    `json:"personId" bson:"personId"`
+	Uid       string    `json:"uid" bson:"uid"`
+	GroupId   string    `json:"groupId,omitempty" bson:"groupId,omitempty"`
+	Name      string    `json:"name" bson:"name"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// SplitShare gives a person Weight parts of a line item
+type SplitShare struct {
+	PersonId string `json:"personId" bson:"personId" binding:"required"`
+	Weight   int    `json:"weight" bson:"weight" binding:"min=0"`
+}
+
+// ItemSplit assigns the product at Index of the record to one or more people
+type ItemSplit struct {
+	Index  int          `json:"index" bson:"index" binding:"min=0"`
+	Shares []SplitShare `json:"shares" bson:"shares" binding:"required,min=1,dive"`
+}
+
+// RecordSplit describes who paid a receipt and who shares each line item. Items
+// without an assignment are shared by Default, or belong to the payer if it is empty.
+type RecordSplit struct {
+	PaidBy    string       `json:"paidBy" bson:"paidBy"`
+	Items     []ItemSplit  `json:"items" bson:"items"`
+	Default   []SplitShare `json:"default,omitempty" bson:"default,omitempty"`
+	UpdatedAt time.Time    `json:"updatedAt" bson:"updatedAt"`
+}
+
+// PersonTotal is what one person owes for a receipt
+type PersonTotal struct {
+	PersonId string `json:"personId"`
+	Name     string `json:"name"`
+	Amount   int    `json:"amount"`
+}
+
+// Settlement is a payment between two people that settles part of their balance
+type Settlement struct {
+	SettlementId string    `json:"settlementId" bson:"settlementId"`
+	Uid          string    `json:"uid" bson:"uid"`
+	GroupId      string    `json:"groupId,omitempty" bson:"groupId,omitempty"`
+	From         string    `json:"from" bson:"from"`
+	To           string    `json:"to" bson:"to"`
+	Amount       int       `json:"amount" bson:"amount"`
+	Note         string    `json:"note,omitempty" bson:"note,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Balance is a person's position across receipts and settlements. A positive Net
+// means the person is owed money.
+type Balance struct {
+	PersonId string `json:"personId"`
+	Name     string `json:"name"`
+	Paid     int    `json:"paid"`
+	Owed     int    `json:"owed"`
+	Sent     int    `json:"sent"`
+	Received int    `json:"received"`
+	Net      int    `json:"net"`
+}
+
+// Payment is one transfer of a settle-up plan
+type Payment struct {
+	From     string `json:"from"`
+	FromName string `json:"fromName"`
+	To       string `json:"to"`
+	ToName   string `json:"toName"`
+	Amount   int    `json:"amount"`
+}
+
+type PersonRequest struct {
+	Name    string `json:"name" binding:"required"`
+	GroupId string `json:"groupId"`
+}
+
+type SplitRequest struct {
+	PaidBy  string       `json:"paidBy" binding:"required"`
+	Items   []ItemSplit  `json:"items" binding:"dive"`
+	Default []SplitShare `json:"default" binding:"dive"`
+}
+
+type SettlementRequest struct {
+	From    string `json:"from" binding:"required"`
+	To      string `json:"to" binding:"required,nefield=From"`
+	Amount  int    `json:"amount" binding:"required,min=1"`
+	GroupId string `json:"groupId"`
+	Note    string `json:"note"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Tag is an entry of a user's tag catalogue
+type Tag struct {
+	Uid       string    `json:"uid" bson:"uid"`
+	Name      string    `json:"name" bson:"name"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// TagCount is a catalogue tag with the number of records carrying it
+type TagCount struct {
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type RenameTagRequest struct {
+	NewName string `json:"newName" binding:"required"`
+}
+
+type MergeTagsRequest struct {
+	Sources []string `json:"sources" binding:"required,min=1"`
+	Target  string   `json:"target" binding:"required"`
+}
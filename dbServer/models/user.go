@@ -1,13 +1,20 @@
 package models
 
 type DBRequest struct {
-	Uid      string      `bson:"uid", omitempty`
-	Nickname string      `bson:"nickname"`
-	Email    string      `bson:"email"`
-	Pw       string      `bson:"pw"`
-	Record   DBRecord    `bson:"record"`
-	Mart     DBMart      `bson:"mart"`
-	Product  []DBProduct `bson:"product"`
+	Uid      string       `bson:"uid,omitempty"`
+	Nickname string       `bson:"nickname"`
+	Email    string       `bson:"email"`
+	Pw       string       `bson:"pw"`
+	Record   DBRecord     `bson:"record"`
+	Mart     DBMart       `bson:"mart"`
+	Product  []DBProduct  `bson:"product"`
+	Currency string       `bson:"currency,omitempty"`
+	Discount int          `bson:"discount,omitempty"`
+	Tax      *TaxInfo     `bson:"tax,omitempty"`
+	Payment  *PaymentInfo `bson:"payment,omitempty"`
+	// Image는 GET /records/:rid/image로 받음
+	Image  *ReceiptImage `bson:"image,omitempty"`
+	Issues []RecordIssue `bson:"issues,omitempty"`
 }
 
 type LoginRequest struct {
@@ -21,6 +28,11 @@ type User struct {
 	Nickname string `bson:"nickname"`
 	Email    string `bson:"email"`
 	Pw       string `bson:"pw"`
+	TimeZone string `bson:"timeZone,omitempty"`
+	// HomeCurrency is the currency analytics are converted to
+	HomeCurrency string `bson:"homeCurrency,omitempty"`
+	// MonthlyReport has the report of the past month rendered on the first of every month
+	MonthlyReport bool `bson:"monthlyReport,omitempty"`
 }
 
 type SignupRequest struct {
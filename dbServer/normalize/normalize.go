@@ -0,0 +1,95 @@
+package normalize
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ProductName reduces a product name to a key shared by the different spellings
+// that appear on receipts: case, spaces and punctuation are dropped, e.g.
+// "서울우유 1L", "서울 우유1l" and "*서울우유(1L)" all become "서울우유1l".
+func ProductName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// 태그 최대 길이 (문자 수)
+const maxTagLength = 30
+
+// Tag trims and lower-cases a tag and joins its words with "-", so "Trip Jeju" and
+// "trip-jeju" are the same tag. It returns "" for tags that are empty or too long.
+func Tag(tag string) string {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if tag == "" || len([]rune(tag)) > maxTagLength {
+		return ""
+	}
+	return tag
+}
+
+// CardNumber masks a card number so only the first and last four digits remain,
+// e.g. "5365-1234-5678-9012" and "5365********9012" both become
+// "5365-****-****-9012". Digits the receipt already masked stay masked.
+func CardNumber(number string) string {
+	var chars []rune
+	for _, r := range number {
+		if unicode.IsDigit(r) || r == '*' {
+			chars = append(chars, r)
+		}
+	}
+	n := len(chars)
+	if n <= 4 {
+		return string(chars)
+	}
+
+	var b strings.Builder
+	for i, r := range chars {
+		if i > 0 && i%4 == 0 {
+			b.WriteRune('-')
+		}
+		// 12자리 미만이면 마지막 네 자리만 남김
+		if (i < 4 && n >= 12) || i >= n-4 {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('*')
+		}
+	}
+	return b.String()
+}
+
+// Digits keeps only the digits of s, e.g. for phone and business registration numbers
+func Digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// 시·도 이름은 영수증마다 줄여 쓰거나 풀어 씀
+var regionSuffixes = strings.NewReplacer("특별자치시", "", "특별자치도", "", "특별시", "", "광역시", "")
+
+// minAddressLength is the shortest normalized address used to tell stores apart
+const minAddressLength = 6
+
+// Address reduces a street address to a comparable key: the parenthesized district
+// note and the long forms of city names are dropped, then spaces and punctuation,
+// so "서울특별시 강남구 테헤란로 123 (역삼동)" and "서울 강남구 테헤란로123" match.
+// It returns "" for addresses too short to identify a store.
+func Address(address string) string {
+	if i := strings.Index(address, "("); i >= 0 {
+		address = address[:i]
+	}
+	address = ProductName(regionSuffixes.Replace(address))
+	if len([]rune(address)) < minAddressLength {
+		return ""
+	}
+	return address
+}
@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+	"dbserver/currency"
+	"dbserver/models"
+	"strconv"
+)
+
+// BudgetNotifier sends budget alerts as notifications, see budget.SetNotifier
+type BudgetNotifier struct{}
+
+func (BudgetNotifier) Notify(ctx context.Context, alert models.BudgetAlert) error {
+	code := currency.Normalize(alert.Currency)
+	_, err := Send(ctx, alert.Uid, models.NotificationBudgetAlert, map[string]string{
+		"aid":       alert.Aid,
+		"bid":       alert.Bid,
+		"budget":    alert.BudgetName,
+		"threshold": strconv.Itoa(alert.Threshold),
+		"exceeded":  strconv.FormatBool(alert.Threshold >= 100),
+		"spent":     Amount(alert.Spent, code),
+		"amount":    Amount(alert.Amount, code),
+		"currency":  code,
+	})
+	return err
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"context"
+	"dbserver/models"
+	"sync"
+)
+
+// FakeTransport records messages instead of sending them. Tests register it with Use in
+// place of a real transport and read what was sent with Sent.
+type FakeTransport struct {
+	channel string
+
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+func NewFakeTransport(channel string) *FakeTransport {
+	return &FakeTransport{channel: channel}
+}
+
+func (t *FakeTransport) Channel() string {
+	return t.channel
+}
+
+func (t *FakeTransport) Send(ctx context.Context, msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	t.messages = append(t.messages, msg)
+	return nil
+}
+
+// Fail makes the following sends return err, or succeed again when err is nil
+func (t *FakeTransport) Fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+}
+
+// Sent returns the messages sent so far
+func (t *FakeTransport) Sent() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message{}, t.messages...)
+}
+
+// Reset forgets the messages sent so far
+func (t *FakeTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
+
+// FakePush is a push recorded by FakeGateway
+type FakePush struct {
+	Devices []models.PushDevice
+	Push    MobilePush
+}
+
+// FakeGateway records mobile pushes instead of handing them to FCM or APNs. Tokens
+// added with Invalidate are reported back as no longer valid.
+type FakeGateway struct {
+	mu      sync.Mutex
+	pushes  []FakePush
+	invalid map[string]bool
+	err     error
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{invalid: map[string]bool{}}
+}
+
+func (g *FakeGateway) Push(ctx context.Context, devices []models.PushDevice, push MobilePush) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var invalid []string
+	var valid []models.PushDevice
+	for _, device := range devices {
+		if g.invalid[device.Token] {
+			invalid = append(invalid, device.Token)
+		} else {
+			valid = append(valid, device)
+		}
+	}
+	if len(valid) > 0 {
+		g.pushes = append(g.pushes, FakePush{Devices: valid, Push: push})
+	}
+	return invalid, nil
+}
+
+// Invalidate makes the gateway reject token from now on
+func (g *FakeGateway) Invalidate(token string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.invalid[token] = true
+}
+
+// Fail makes the following pushes return err, or succeed again when err is nil
+func (g *FakeGateway) Fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+}
+
+// Pushes returns the pushes made so far
+func (g *FakeGateway) Pushes() []FakePush {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]FakePush{}, g.pushes...)
+}
@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// InboxTransport puts notifications in the in-app inbox, GET /notifications
+type InboxTransport struct{}
+
+func (InboxTransport) Channel() string {
+	return models.ChannelInbox
+}
+
+func (InboxTransport) Send(ctx context.Context, msg Message) error {
+	_, err := db.NotificationCollection.UpdateOne(ctx,
+		bson.M{"nid": msg.Nid},
+		bson.M{"$set": bson.M{"inbox": true, "title": msg.Title, "body": msg.Body}},
+	)
+	return err
+}
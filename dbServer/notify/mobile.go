@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MobilePush is a notification for the mobile apps
+type MobilePush struct {
+	Nid   string            `json:"nid"`
+	Kind  string            `json:"kind"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// MobileGateway hands pushes to the platform push services, FCM and APNs. It returns
+// the tokens the platforms no longer accept so they can be dropped.
+type MobileGateway interface {
+	Push(ctx context.Context, devices []models.PushDevice, push MobilePush) (invalid []string, err error)
+}
+
+// MobileTransport sends notifications to the devices the user registered through a
+// MobileGateway
+type MobileTransport struct {
+	gateway MobileGateway
+}
+
+func NewMobileTransport(gateway MobileGateway) *MobileTransport {
+	return &MobileTransport{gateway: gateway}
+}
+
+func (t *MobileTransport) Channel() string {
+	return models.ChannelMobile
+}
+
+func (t *MobileTransport) Send(ctx context.Context, msg Message) error {
+	cursor, err := db.PushDeviceCollection.Find(ctx, bson.M{"uid": msg.Uid})
+	if err != nil {
+		return err
+	}
+	var devices []models.PushDevice
+	if err := cursor.All(ctx, &devices); err != nil {
+		return err
+	}
+
+	invalid, err := t.push(ctx, devices, msg)
+	if len(invalid) > 0 {
+		if _, err := db.PushDeviceCollection.DeleteMany(ctx, bson.M{"token": bson.M{"$in": invalid}}); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// push hands msg to the gateway for devices. It returns the tokens the gateway no
+// longer accepts, and ErrNoRecipient when no device is left to receive it.
+func (t *MobileTransport) push(ctx context.Context, devices []models.PushDevice, msg Message) ([]string, error) {
+	if len(devices) == 0 {
+		return nil, ErrNoRecipient
+	}
+	invalid, err := t.gateway.Push(ctx, devices, MobilePush{Nid: msg.Nid, Kind: msg.Kind, Title: msg.Title, Body: msg.Body, Data: msg.Data})
+	if err != nil {
+		return invalid, err
+	}
+	if len(invalid) == len(devices) {
+		return invalid, ErrNoRecipient
+	}
+	return invalid, nil
+}
+
+// HTTPGateway posts pushes as JSON to a push relay, with the secret as a bearer token:
+//
+//	{"devices": [{"token", "platform"}], "push": {"nid", "kind", "title", "body", "data"}}
+//
+// The relay answers 2xx with {"invalidTokens": [...]}.
+type HTTPGateway struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewHTTPGateway(url string, secret string) *HTTPGateway {
+	return &HTTPGateway{url: url, secret: secret, client: &http.Client{}}
+}
+
+type gatewayRequest struct {
+	Devices []models.PushDevice `json:"devices"`
+	Push    MobilePush          `json:"push"`
+}
+
+type gatewayResponse struct {
+	InvalidTokens []string `json:"invalidTokens"`
+}
+
+func (g *HTTPGateway) Push(ctx context.Context, devices []models.PushDevice, push MobilePush) ([]string, error) {
+	body, err := json.Marshal(gatewayRequest{Devices: devices, Push: push})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+g.secret)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("push gateway returned %s", resp.Status)
+	}
+
+	var result gatewayResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return result.InvalidTokens, nil
+}
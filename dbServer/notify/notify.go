@@ -0,0 +1,283 @@
+// Package notify delivers notifications to users. Any server queues a notification in
+// Mongo with Send; the dispatcher of dbServer renders it in the user's language, picks
+// the channels from the user's preferences, holds push and email back during quiet
+// hours and retries the channels that failed. Channels are served by transports, see
+// Transport.
+package notify
+
+import (
+	"context"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// pollInterval is how often the queue is checked for notifications sent by other
+	// servers or due again
+	pollInterval = 5 * time.Second
+	// lease is how long a dispatcher holds a notification before others may take it
+	lease = time.Minute
+	// sendTimeout bounds the delivery of one notification on every channel
+	sendTimeout = 30 * time.Second
+	// maxAttempts is how often a failing channel is tried, retryDelay times the
+	// attempt number apart
+	maxAttempts = 3
+	retryDelay  = time.Minute
+)
+
+// urgent kinds are sent during quiet hours too
+var urgent = map[string]bool{
+	models.NotificationNewDeviceLogin: true,
+}
+
+var (
+	wake      = make(chan struct{}, 1)
+	startOnce sync.Once
+)
+
+// Send queues a notification of kind for uid. Data fills the template of the kind.
+func Send(ctx context.Context, uid string, kind string, data map[string]string) (*models.Notification, error) {
+	if _, ok := templates[kind]; !ok {
+		return nil, fmt.Errorf("unknown notification kind %q", kind)
+	}
+
+	now := time.Now()
+	n := &models.Notification{
+		Nid:          uuid.NewString(),
+		Uid:          uid,
+		Kind:         kind,
+		Data:         data,
+		Status:       models.NotificationPending,
+		DeliverAfter: now,
+		CreatedAt:    now,
+	}
+	if _, err := db.NotificationCollection.InsertOne(ctx, n); err != nil {
+		return nil, err
+	}
+	Wake()
+	return n, nil
+}
+
+// Wake makes the dispatcher look at the queue now instead of at the next poll
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start starts the dispatcher. Every replica may run one; a notification is claimed by
+// one of them at a time.
+func Start() {
+	startOnce.Do(func() {
+		go loop()
+	})
+}
+
+func loop() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		dispatchDue()
+		select {
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// dispatchDue delivers the due notifications until none is left
+func dispatchDue() {
+	for {
+		n, err := claim()
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			log.Printf("Notification error: %v\n", err)
+			return
+		}
+		if err := deliver(n); err != nil {
+			// 잠금이 풀리면 다시 시도됨
+			log.Printf("Notification error for %s: %v\n", n.Nid, err)
+		}
+	}
+}
+
+// claim locks the oldest due notification
+func claim() (*models.Notification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"status":       models.NotificationPending,
+		"deliverAfter": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "deliverAfter", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var n models.Notification
+	err := db.NotificationCollection.FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{"lockedUntil": now.Add(lease)}}, opts,
+	).Decode(&n)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// deliver sends n on the channels still due and stores the outcome
+func deliver(n *models.Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	user, err := loadUser(ctx, n.Uid)
+	if err != nil {
+		return err
+	}
+	prefs, err := LoadPreferences(ctx, n.Uid)
+	if err != nil {
+		return err
+	}
+	loc := dates.LoadLocation(user.TimeZone)
+	now := time.Now()
+
+	msg := Message{
+		Nid:      n.Nid,
+		Uid:      n.Uid,
+		Kind:     n.Kind,
+		Language: prefs.Language,
+		Email:    user.Email,
+		Data:     n.Data,
+	}
+	msg.Title, msg.Body, err = Render(n.Kind, prefs.Language, n.Data, loc)
+	if err != nil {
+		// 템플릿이 없으면 다시 시도해도 같으므로 실패로 끝냄
+		return finish(ctx, n, msg, nil, 0, now, []models.Delivery{{Status: models.DeliveryFailed, Error: err.Error(), At: now}})
+	}
+
+	o := send(ctx, n, msg, prefs, now, loc)
+	return finish(ctx, n, msg, o.remaining, o.attempts, o.deliverAfter, o.deliveries)
+}
+
+// outcome is what one delivery attempt of a notification did
+type outcome struct {
+	deliveries []models.Delivery
+	// remaining are the channels to send again after deliverAfter
+	remaining    []string
+	attempts     int
+	deliverAfter time.Time
+}
+
+// send hands msg to the transports of the channels of n still due at now. During the
+// quiet hours of prefs in loc only the inbox is sent; push and email wait until they
+// end. Failed channels are tried again later, up to maxAttempts times.
+func send(ctx context.Context, n *models.Notification, msg Message, prefs models.NotificationPreferences, now time.Time, loc *time.Location) outcome {
+	// 처음 보낼 때 사용자 설정으로 채널을 정함
+	channels := n.Channels
+	if channels == nil {
+		channels = append([]string{}, prefs.Channels[n.Kind]...)
+	}
+
+	var deferred []string
+	deliverAfter := now
+	if until, quiet := QuietUntil(prefs.QuietHours, now, loc); quiet && !urgent[n.Kind] {
+		// 인박스는 바로 넣고 푸시와 메일은 방해 금지 시간이 끝난 뒤 보냄
+		var immediate []string
+		for _, channel := range channels {
+			if channel == models.ChannelInbox {
+				immediate = append(immediate, channel)
+			} else {
+				deferred = append(deferred, channel)
+			}
+		}
+		channels = immediate
+		deliverAfter = until
+	}
+
+	var failed []string
+	deliveries := []models.Delivery{}
+	for _, channel := range channels {
+		delivery := models.Delivery{Channel: channel, Status: models.DeliverySent}
+		transport, ok := lookup(channel)
+		if !ok {
+			delivery.Status = models.DeliverySkipped
+			delivery.Error = "transport is not configured"
+		} else if err := transport.Send(ctx, msg); err == ErrNoRecipient {
+			delivery.Status = models.DeliverySkipped
+			delivery.Error = err.Error()
+		} else if err != nil {
+			delivery.Status = models.DeliveryFailed
+			delivery.Error = err.Error()
+			failed = append(failed, channel)
+		}
+		delivery.At = time.Now()
+		deliveries = append(deliveries, delivery)
+	}
+
+	attempts := n.Attempts
+	if len(failed) > 0 {
+		attempts++
+		if attempts < maxAttempts {
+			deferred = append(deferred, failed...)
+			if retry := now.Add(retryDelay * time.Duration(attempts)); retry.After(deliverAfter) {
+				deliverAfter = retry
+			}
+		}
+	}
+	return outcome{deliveries: deliveries, remaining: deferred, attempts: attempts, deliverAfter: deliverAfter}
+}
+
+// finish stores the deliveries of n and releases it. Remaining channels are sent again
+// after deliverAfter; without them n is done.
+func finish(ctx context.Context, n *models.Notification, msg Message, remaining []string, attempts int, deliverAfter time.Time, deliveries []models.Delivery) error {
+	status := models.NotificationDelivered
+	if len(remaining) > 0 {
+		status = models.NotificationPending
+	}
+	if remaining == nil {
+		remaining = []string{}
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"title":        msg.Title,
+			"body":         msg.Body,
+			"channels":     remaining,
+			"status":       status,
+			"attempts":     attempts,
+			"deliverAfter": deliverAfter,
+		},
+		"$unset": bson.M{"lockedUntil": ""},
+	}
+	if len(deliveries) > 0 {
+		update["$push"] = bson.M{"deliveries": bson.M{"$each": deliveries}}
+	}
+	_, err := db.NotificationCollection.UpdateOne(ctx, bson.M{"nid": n.Nid}, update)
+	return err
+}
+
+func loadUser(ctx context.Context, uid string) (models.User, error) {
+	var user models.User
+	err := db.Collection.FindOne(ctx, bson.M{"uid": uid, "record": bson.M{"$exists": false}}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, nil
+	}
+	return user, err
+}
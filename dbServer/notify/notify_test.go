@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"context"
+	"dbserver/models"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var kst = time.FixedZone("KST", 9*60*60)
+
+func TestQuietUntil(t *testing.T) {
+	at := func(d, h, m int) time.Time {
+		return time.Date(2026, 3, d, h, m, 0, 0, kst)
+	}
+	sameDay := &models.QuietHours{Start: "13:00", End: "14:00"}
+	overnight := &models.QuietHours{Start: "22:00", End: "07:00"}
+
+	tests := []struct {
+		name      string
+		q         *models.QuietHours
+		at        time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"off", nil, at(14, 13, 30), false, time.Time{}},
+		{"same day, inside", sameDay, at(14, 13, 30), true, at(14, 14, 0)},
+		{"same day, at the start", sameDay, at(14, 13, 0), true, at(14, 14, 0)},
+		{"same day, at the end", sameDay, at(14, 14, 0), false, at(14, 14, 0)},
+		{"same day, before", sameDay, at(14, 12, 59), false, at(14, 14, 0)},
+		{"overnight, before midnight", overnight, at(14, 23, 0), true, at(15, 7, 0)},
+		{"overnight, after midnight", overnight, at(15, 2, 0), true, at(15, 7, 0)},
+		{"overnight, at the end", overnight, at(15, 7, 0), false, at(15, 7, 0)},
+		{"overnight, evening", overnight, at(14, 21, 59), false, at(14, 7, 0)},
+		{"overnight, month end", overnight, time.Date(2026, 3, 31, 22, 30, 0, 0, kst), true, time.Date(2026, 4, 1, 7, 0, 0, 0, kst)},
+		{"in another zone", overnight, time.Date(2026, 3, 14, 14, 0, 0, 0, time.UTC), true, at(15, 7, 0)},
+		{"invalid clock", &models.QuietHours{Start: "25:00", End: "07:00"}, at(14, 23, 0), false, time.Time{}},
+		{"empty range", &models.QuietHours{Start: "22:00", End: "22:00"}, at(14, 22, 0), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := QuietUntil(tt.q, tt.at, kst)
+			if quiet != tt.wantQuiet || !until.Equal(tt.wantUntil) {
+				t.Errorf("QuietUntil() = %s, %v; want %s, %v", until, quiet, tt.wantUntil, tt.wantQuiet)
+			}
+		})
+	}
+}
+
+var allChannels = []string{models.ChannelInbox, models.ChannelEmail, models.ChannelWebPush, models.ChannelMobile}
+
+// useFakes puts a fake transport on every channel for the length of the test
+func useFakes(t *testing.T) map[string]*FakeTransport {
+	fakes := map[string]*FakeTransport{}
+	for _, channel := range allChannels {
+		fake := NewFakeTransport(channel)
+		Use(fake)
+		fakes[channel] = fake
+		t.Cleanup(func() { Remove(channel) })
+	}
+	return fakes
+}
+
+func testPreferences(quiet *models.QuietHours) models.NotificationPreferences {
+	return models.NotificationPreferences{
+		Language: "ko",
+		Channels: map[string][]string{
+			models.NotificationBudgetAlert:    allChannels,
+			models.NotificationNewDeviceLogin: allChannels,
+		},
+		QuietHours: quiet,
+	}
+}
+
+func testMessage(n *models.Notification) Message {
+	return Message{Nid: n.Nid, Uid: n.Uid, Kind: n.Kind, Title: "예산", Body: "예산의 80%를 사용했습니다"}
+}
+
+// sentCounts is how many messages each fake has sent
+func sentCounts(fakes map[string]*FakeTransport) map[string]int {
+	counts := map[string]int{}
+	for channel, fake := range fakes {
+		counts[channel] = len(fake.Sent())
+	}
+	return counts
+}
+
+func TestSendHoldsPushBackDuringQuietHours(t *testing.T) {
+	fakes := useFakes(t)
+	prefs := testPreferences(&models.QuietHours{Start: "22:00", End: "07:00"})
+	n := &models.Notification{Nid: "n1", Uid: "u1", Kind: models.NotificationBudgetAlert}
+	night := time.Date(2026, 3, 14, 23, 0, 0, 0, kst)
+
+	o := send(context.Background(), n, testMessage(n), prefs, night, kst)
+
+	want := map[string]int{models.ChannelInbox: 1, models.ChannelEmail: 0, models.ChannelWebPush: 0, models.ChannelMobile: 0}
+	if got := sentCounts(fakes); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent during quiet hours = %v, want %v", got, want)
+	}
+	if want := []string{models.ChannelEmail, models.ChannelWebPush, models.ChannelMobile}; !reflect.DeepEqual(o.remaining, want) {
+		t.Errorf("remaining = %v, want %v", o.remaining, want)
+	}
+	morning := time.Date(2026, 3, 15, 7, 0, 0, 0, kst)
+	if !o.deliverAfter.Equal(morning) {
+		t.Errorf("deliverAfter = %s, want %s", o.deliverAfter, morning)
+	}
+	if len(o.deliveries) != 1 || o.deliveries[0].Channel != models.ChannelInbox || o.deliveries[0].Status != models.DeliverySent {
+		t.Errorf("deliveries = %+v, want the inbox only", o.deliveries)
+	}
+
+	// 방해 금지 시간이 끝나면 남은 채널만 보냄
+	n.Channels, n.Attempts = o.remaining, o.attempts
+	o = send(context.Background(), n, testMessage(n), prefs, morning, kst)
+
+	want = map[string]int{models.ChannelInbox: 1, models.ChannelEmail: 1, models.ChannelWebPush: 1, models.ChannelMobile: 1}
+	if got := sentCounts(fakes); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent after quiet hours = %v, want %v", got, want)
+	}
+	if len(o.remaining) != 0 {
+		t.Errorf("remaining = %v, want none", o.remaining)
+	}
+}
+
+func TestSendUrgentDuringQuietHours(t *testing.T) {
+	fakes := useFakes(t)
+	prefs := testPreferences(&models.QuietHours{Start: "22:00", End: "07:00"})
+	n := &models.Notification{Nid: "n1", Uid: "u1", Kind: models.NotificationNewDeviceLogin}
+	night := time.Date(2026, 3, 14, 23, 0, 0, 0, kst)
+
+	o := send(context.Background(), n, testMessage(n), prefs, night, kst)
+
+	for channel, count := range sentCounts(fakes) {
+		if count != 1 {
+			t.Errorf("%s sent %d messages, want 1", channel, count)
+		}
+	}
+	if len(o.remaining) != 0 || !o.deliverAfter.Equal(night) {
+		t.Errorf("remaining = %v after %s, want none", o.remaining, o.deliverAfter)
+	}
+}
+
+func TestSendRetriesFailedChannels(t *testing.T) {
+	fakes := useFakes(t)
+	prefs := testPreferences(nil)
+	n := &models.Notification{Nid: "n1", Uid: "u1", Kind: models.NotificationBudgetAlert}
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, kst)
+
+	fakes[models.ChannelEmail].Fail(errors.New("smtp is down"))
+	o := send(context.Background(), n, testMessage(n), prefs, now, kst)
+	if !reflect.DeepEqual(o.remaining, []string{models.ChannelEmail}) || o.attempts != 1 {
+		t.Fatalf("remaining = %v after %d attempts, want the email after 1", o.remaining, o.attempts)
+	}
+	if want := now.Add(retryDelay); !o.deliverAfter.Equal(want) {
+		t.Errorf("deliverAfter = %s, want %s", o.deliverAfter, want)
+	}
+	for _, d := range o.deliveries {
+		if d.Channel == models.ChannelEmail && (d.Status != models.DeliveryFailed || d.Error == "") {
+			t.Errorf("email delivery = %+v, want failed with the error", d)
+		}
+	}
+
+	// 두 번째 실패는 더 오래 기다림
+	now = o.deliverAfter
+	n.Channels, n.Attempts = o.remaining, o.attempts
+	o = send(context.Background(), n, testMessage(n), prefs, now, kst)
+	if o.attempts != 2 || !o.deliverAfter.Equal(now.Add(2*retryDelay)) {
+		t.Errorf("attempt %d retries at %s, want attempt 2 at %s", o.attempts, o.deliverAfter, now.Add(2*retryDelay))
+	}
+
+	fakes[models.ChannelEmail].Fail(nil)
+	n.Channels, n.Attempts = o.remaining, o.attempts
+	o = send(context.Background(), n, testMessage(n), prefs, o.deliverAfter, kst)
+	if len(o.remaining) != 0 {
+		t.Errorf("remaining = %v after the email recovered, want none", o.remaining)
+	}
+
+	// 다른 채널은 한 번씩만 보냄
+	want := map[string]int{models.ChannelInbox: 1, models.ChannelEmail: 1, models.ChannelWebPush: 1, models.ChannelMobile: 1}
+	if got := sentCounts(fakes); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent = %v, want %v", got, want)
+	}
+}
+
+func TestSendGivesUp(t *testing.T) {
+	fakes := useFakes(t)
+	fakes[models.ChannelWebPush].Fail(errors.New("push service unavailable"))
+	prefs := testPreferences(nil)
+	n := &models.Notification{Nid: "n1", Uid: "u1", Kind: models.NotificationBudgetAlert}
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, kst)
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		o := send(context.Background(), n, testMessage(n), prefs, now, kst)
+		if o.attempts != attempt {
+			t.Fatalf("attempts = %d, want %d", o.attempts, attempt)
+		}
+		n.Channels, n.Attempts = o.remaining, o.attempts
+		now = o.deliverAfter
+	}
+	if len(n.Channels) != 0 {
+		t.Errorf("remaining = %v after %d attempts, want none", n.Channels, maxAttempts)
+	}
+}
+
+func TestSendSkipsChannelsWithoutRecipient(t *testing.T) {
+	fakes := useFakes(t)
+	fakes[models.ChannelMobile].Fail(ErrNoRecipient)
+	Remove(models.ChannelWebPush)
+	prefs := testPreferences(nil)
+	n := &models.Notification{Nid: "n1", Uid: "u1", Kind: models.NotificationBudgetAlert}
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, kst)
+
+	o := send(context.Background(), n, testMessage(n), prefs, now, kst)
+	if len(o.remaining) != 0 || o.attempts != 0 {
+		t.Errorf("remaining = %v after %d attempts, want skipped channels dropped", o.remaining, o.attempts)
+	}
+	for _, d := range o.deliveries {
+		skipped := d.Channel == models.ChannelMobile || d.Channel == models.ChannelWebPush
+		if skipped && d.Status != models.DeliverySkipped {
+			t.Errorf("%s delivery = %+v, want skipped", d.Channel, d)
+		}
+	}
+}
+
+func TestMobilePush(t *testing.T) {
+	gateway := NewFakeGateway()
+	transport := NewMobileTransport(gateway)
+	devices := []models.PushDevice{{Token: "a", Platform: "ios"}, {Token: "b", Platform: "android"}}
+	msg := Message{Nid: "n1", Kind: models.NotificationBudgetAlert, Title: "예산", Body: "본문"}
+	ctx := context.Background()
+
+	if _, err := transport.push(ctx, nil, msg); err != ErrNoRecipient {
+		t.Errorf("push() without devices = %v, want ErrNoRecipient", err)
+	}
+
+	gateway.Invalidate("b")
+	invalid, err := transport.push(ctx, devices, msg)
+	if err != nil || !reflect.DeepEqual(invalid, []string{"b"}) {
+		t.Errorf("push() = %v, %v; want [b], nil", invalid, err)
+	}
+	pushes := gateway.Pushes()
+	if len(pushes) != 1 || len(pushes[0].Devices) != 1 || pushes[0].Devices[0].Token != "a" || pushes[0].Push.Title != "예산" {
+		t.Errorf("pushes = %+v, want one to device a", pushes)
+	}
+
+	gateway.Invalidate("a")
+	if _, err := transport.push(ctx, devices, msg); err != ErrNoRecipient {
+		t.Errorf("push() to invalid devices = %v, want ErrNoRecipient", err)
+	}
+
+	gateway.Fail(errors.New("gateway is down"))
+	if _, err := transport.push(ctx, devices, msg); err == nil {
+		t.Errorf("push() succeeded while the gateway fails")
+	}
+}
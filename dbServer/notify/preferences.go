@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultChannels are the channels of each kind until the user picks their own
+var defaultChannels = map[string][]string{
+	models.NotificationBudgetAlert:    {models.ChannelInbox, models.ChannelWebPush, models.ChannelMobile},
+	models.NotificationOCRFailed:      {models.ChannelInbox, models.ChannelWebPush, models.ChannelMobile},
+	models.NotificationNewDeviceLogin: {models.ChannelInbox, models.ChannelEmail},
+	models.NotificationMonthlyReport:  {models.ChannelInbox, models.ChannelEmail},
+}
+
+var channels = map[string]bool{
+	models.ChannelInbox:   true,
+	models.ChannelEmail:   true,
+	models.ChannelWebPush: true,
+	models.ChannelMobile:  true,
+}
+
+// LoadPreferences returns the settings of uid with the defaults filled in for what the
+// user never set
+func LoadPreferences(ctx context.Context, uid string) (models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{Uid: uid}
+	err := db.NotificationPrefCollection.FindOne(ctx, bson.M{"uid": uid}).Decode(&prefs)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return prefs, err
+	}
+
+	if prefs.Language == "" {
+		prefs.Language = LanguageKorean
+	}
+	if prefs.Channels == nil {
+		prefs.Channels = map[string][]string{}
+	}
+	for _, kind := range Kinds() {
+		if _, ok := prefs.Channels[kind]; !ok {
+			prefs.Channels[kind] = append([]string{}, defaultChannels[kind]...)
+		}
+	}
+	return prefs, nil
+}
+
+// ValidatePreferences checks the kinds, channels and quiet hours of req
+func ValidatePreferences(req models.NotificationPreferencesRequest) error {
+	for kind, list := range req.Channels {
+		if _, ok := templates[kind]; !ok {
+			return fmt.Errorf("unknown notification kind %q", kind)
+		}
+		for _, channel := range list {
+			if !channels[channel] {
+				return fmt.Errorf("unknown channel %q for %s", channel, kind)
+			}
+		}
+	}
+	if q := req.QuietHours; q != nil {
+		if _, err := clock(q.Start); err != nil {
+			return fmt.Errorf("quietHours.start: %v", err)
+		}
+		if _, err := clock(q.End); err != nil {
+			return fmt.Errorf("quietHours.end: %v", err)
+		}
+	}
+	return nil
+}
+
+// SavePreferences applies req, already validated, to the settings of uid. Kinds missing
+// from req.Channels keep their channels.
+func SavePreferences(ctx context.Context, uid string, req models.NotificationPreferencesRequest) (models.NotificationPreferences, error) {
+	set := bson.M{"uid": uid}
+	if req.Language != nil {
+		set["language"] = *req.Language
+	}
+	for kind, list := range req.Channels {
+		seen := map[string]bool{}
+		unique := []string{}
+		for _, channel := range list {
+			if !seen[channel] {
+				seen[channel] = true
+				unique = append(unique, channel)
+			}
+		}
+		set["channels."+kind] = unique
+	}
+
+	update := bson.M{"$set": set}
+	if req.ClearQuietHours {
+		update["$unset"] = bson.M{"quietHours": ""}
+	} else if req.QuietHours != nil {
+		set["quietHours"] = req.QuietHours
+	}
+
+	_, err := db.NotificationPrefCollection.UpdateOne(ctx, bson.M{"uid": uid}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	return LoadPreferences(ctx, uid)
+}
+
+// QuietUntil reports whether at falls in the quiet hours q in loc, and when they end
+func QuietUntil(q *models.QuietHours, at time.Time, loc *time.Location) (time.Time, bool) {
+	if q == nil {
+		return time.Time{}, false
+	}
+	start, err := clock(q.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := clock(q.End)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	at = at.In(loc)
+	midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc)
+	now := at.Sub(midnight)
+	endToday := midnight.Add(end)
+
+	if start < end {
+		// 같은 날 안의 구간, 예: 13:00-14:00
+		return endToday, now >= start && now < end
+	}
+	// 자정을 넘는 구간, 예: 22:00-07:00
+	if now >= start {
+		return time.Date(at.Year(), at.Month(), at.Day()+1, 0, 0, 0, 0, loc).Add(end), true
+	}
+	return endToday, now < end
+}
+
+// clock reads "HH:MM" as the time since midnight
+func clock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q must be HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"dbserver/currency"
+	"dbserver/models"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	LanguageKorean  = "ko"
+	LanguageEnglish = "en"
+)
+
+// messageTemplate is the title and body of a kind in one language
+type messageTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// texts are the title and body of every kind, by language. Data of the notification
+// fills them; "at" is an RFC 3339 time shown in the user's time zone.
+var texts = map[string]map[string][2]string{
+	models.NotificationBudgetAlert: {
+		LanguageKorean: {
+			`{{if eq .exceeded "true"}}예산 초과: {{.budget}}{{else}}예산 {{.threshold}}% 사용: {{.budget}}{{end}}`,
+			`{{if eq .exceeded "true"}}'{{.budget}}' 예산을 초과했습니다{{else}}'{{.budget}}' 예산의 {{.threshold}}%를 사용했습니다{{end}} ({{.spent}} / {{.amount}} {{.currency}})`,
+		},
+		LanguageEnglish: {
+			`{{if eq .exceeded "true"}}Budget exceeded: {{.budget}}{{else}}{{.threshold}}% of budget used: {{.budget}}{{end}}`,
+			`{{if eq .exceeded "true"}}You went over your '{{.budget}}' budget{{else}}You used {{.threshold}}% of your '{{.budget}}' budget{{end}} ({{.spent}} / {{.amount}} {{.currency}})`,
+		},
+	},
+	models.NotificationOCRFailed: {
+		LanguageKorean: {
+			`영수증 인식 실패`,
+			`{{.at}}에 올린 영수증을 읽지 못했습니다. 밝은 곳에서 영수증 전체가 나오게 다시 찍어 주세요.`,
+		},
+		LanguageEnglish: {
+			`Receipt could not be read`,
+			`The receipt you uploaded at {{.at}} could not be read. Please take the photo again in good light with the whole receipt in view.`,
+		},
+	},
+	models.NotificationNewDeviceLogin: {
+		LanguageKorean: {
+			`새 기기에서 로그인`,
+			`{{.at}}에 새 기기에서 로그인했습니다.
+기기: {{.device}}
+IP: {{.ip}}
+본인이 아니라면 바로 비밀번호를 바꿔 주세요.`,
+		},
+		LanguageEnglish: {
+			`New sign-in to your account`,
+			`Your account was signed in from a new device at {{.at}}.
+Device: {{.device}}
+IP: {{.ip}}
+If this was not you, change your password now.`,
+		},
+	},
+	models.NotificationMonthlyReport: {
+		LanguageKorean: {
+			`{{.month}} 지출 보고서`,
+			`{{.month}} 지출 보고서가 준비되었습니다. 앱의 보고서 화면에서 받아 보세요.`,
+		},
+		LanguageEnglish: {
+			`Spending report for {{.month}}`,
+			`Your spending report for {{.month}} is ready. Download it from the reports screen of the app.`,
+		},
+	},
+}
+
+var templates = parseTemplates()
+
+func parseTemplates() map[string]map[string]messageTemplate {
+	parsed := map[string]map[string]messageTemplate{}
+	for kind, languages := range texts {
+		parsed[kind] = map[string]messageTemplate{}
+		for language, text := range languages {
+			name := kind + "." + language
+			parsed[kind][language] = messageTemplate{
+				title: template.Must(template.New(name + ".title").Option("missingkey=zero").Parse(text[0])),
+				body:  template.Must(template.New(name + ".body").Option("missingkey=zero").Parse(text[1])),
+			}
+		}
+	}
+	return parsed
+}
+
+// Kinds returns the kinds of notification
+func Kinds() []string {
+	return []string{
+		models.NotificationBudgetAlert,
+		models.NotificationOCRFailed,
+		models.NotificationNewDeviceLogin,
+		models.NotificationMonthlyReport,
+	}
+}
+
+// Render returns the title and body of a notification of kind in language, falling
+// back to Korean
+func Render(kind string, language string, data map[string]string, loc *time.Location) (string, string, error) {
+	languages, ok := templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown notification kind %q", kind)
+	}
+	tmpl, ok := languages[language]
+	if !ok {
+		tmpl = languages[LanguageKorean]
+	}
+
+	values := map[string]string{}
+	for k, v := range data {
+		values[k] = v
+	}
+	if at, err := time.Parse(time.RFC3339, values["at"]); err == nil {
+		values["at"] = at.In(loc).Format("2006-01-02 15:04")
+	}
+	if month, err := time.Parse("2006-01", values["month"]); err == nil {
+		values["month"] = monthName(month, language)
+	}
+
+	var title, body strings.Builder
+	if err := tmpl.title.Execute(&title, values); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, values); err != nil {
+		return "", "", err
+	}
+	return title.String(), body.String(), nil
+}
+
+func monthName(month time.Time, language string) string {
+	if language == LanguageEnglish {
+		return month.Format("January 2006")
+	}
+	return fmt.Sprintf("%d년 %d월", month.Year(), int(month.Month()))
+}
+
+// Amount formats an amount in minor units of code with digit grouping for the data of a
+// notification
+func Amount(amount int, code string) string {
+	digits := currency.Format(amount, code)
+	sign, fraction := "", ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+		digits, fraction = digits[:dot], digits[dot:]
+	}
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + fraction
+}
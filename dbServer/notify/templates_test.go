@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"dbserver/models"
+	"testing"
+	"time"
+)
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		amount int
+		code   string
+		want   string
+	}{
+		{1234567, "KRW", "1,234,567"},
+		{-1500, "KRW", "-1,500"},
+		{999, "KRW", "999"},
+		{123456, "USD", "1,234.56"},
+		{-5, "USD", "-0.05"},
+		{1234567, "JPY", "1,234,567"},
+	}
+	for _, tt := range tests {
+		if got := Amount(tt.amount, tt.code); got != tt.want {
+			t.Errorf("Amount(%d, %s) = %q, want %q", tt.amount, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestRenderBudgetAlert(t *testing.T) {
+	data := map[string]string{
+		"budget":    "식비",
+		"threshold": "80",
+		"exceeded":  "false",
+		"spent":     Amount(40000, "USD"),
+		"amount":    Amount(50000, "USD"),
+		"currency":  "USD",
+	}
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{LanguageKorean, "'식비' 예산의 80%를 사용했습니다 (400.00 / 500.00 USD)"},
+		{LanguageEnglish, "You used 80% of your '식비' budget (400.00 / 500.00 USD)"},
+	}
+	for _, tt := range tests {
+		_, body, err := Render(models.NotificationBudgetAlert, tt.language, data, time.UTC)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if body != tt.want {
+			t.Errorf("Render(%s) body = %q, want %q", tt.language, body, tt.want)
+		}
+	}
+}
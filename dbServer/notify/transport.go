@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"dbserver/config"
+	"errors"
+	"log"
+	"sync"
+)
+
+// ErrNoRecipient is returned by a transport when the user has nowhere to receive on its
+// channel, e.g. no subscribed browser. The delivery is skipped instead of retried.
+var ErrNoRecipient = errors.New("user has no recipient on this channel")
+
+// Message is a rendered notification handed to the transports
+type Message struct {
+	Nid      string
+	Uid      string
+	Kind     string
+	Language string
+	Title    string
+	Body     string
+	Data     map[string]string
+	// Email is the address of the user
+	Email string
+}
+
+// Transport delivers messages on one channel
+type Transport interface {
+	Channel() string
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	transportsMu sync.RWMutex
+	transports   = map[string]Transport{}
+)
+
+// Use makes t the transport of its channel, replacing the previous one
+func Use(t Transport) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports[t.Channel()] = t
+}
+
+// Remove turns a channel off; its deliveries are skipped
+func Remove(channel string) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	delete(transports, channel)
+}
+
+func lookup(channel string) (Transport, bool) {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+	t, ok := transports[channel]
+	return t, ok
+}
+
+// Configure sets up the inbox and the transports cfg has settings for
+func Configure(cfg config.NotifyConfig) {
+	Use(InboxTransport{})
+
+	if cfg.SMTPHost != "" && cfg.SMTPFrom != "" {
+		Use(NewSMTPTransport(cfg))
+	} else {
+		log.Println("SMTP is not configured, email notifications are skipped")
+	}
+
+	if cfg.VAPIDPublicKey != "" && cfg.VAPIDPrivateKey != "" {
+		Use(NewWebPushTransport(cfg))
+	} else {
+		log.Println("VAPID keys are not configured, web push notifications are skipped")
+	}
+
+	if cfg.GatewayURL != "" {
+		Use(NewMobileTransport(NewHTTPGateway(cfg.GatewayURL, cfg.GatewaySecret)))
+	} else {
+		log.Println("Push gateway is not configured, mobile notifications are skipped")
+	}
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"dbserver/config"
+	"dbserver/db"
+	"dbserver/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// webPushTTL is how long, in seconds, the push service keeps a message for a browser
+// that is offline
+const webPushTTL = 24 * 60 * 60
+
+// WebPushTransport sends notifications to the browsers the user subscribed with the
+// Push API, signed with the VAPID keys of the server
+type WebPushTransport struct {
+	publicKey  string
+	privateKey string
+	subject    string
+	client     *http.Client
+}
+
+func NewWebPushTransport(cfg config.NotifyConfig) *WebPushTransport {
+	return &WebPushTransport{
+		publicKey:  cfg.VAPIDPublicKey,
+		privateKey: cfg.VAPIDPrivateKey,
+		subject:    cfg.VAPIDSubject,
+		client:     &http.Client{},
+	}
+}
+
+func (t *WebPushTransport) Channel() string {
+	return models.ChannelWebPush
+}
+
+// PublicKey is the application server key browsers subscribe with
+func (t *WebPushTransport) PublicKey() string {
+	return t.publicKey
+}
+
+// webPushPayload is what the service worker receives in the push event
+type webPushPayload struct {
+	Nid   string            `json:"nid"`
+	Kind  string            `json:"kind"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+func (t *WebPushTransport) Send(ctx context.Context, msg Message) error {
+	cursor, err := db.PushSubscriptionCollection.Find(ctx, bson.M{"uid": msg.Uid})
+	if err != nil {
+		return err
+	}
+	var subscriptions []models.PushSubscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return ErrNoRecipient
+	}
+
+	payload, err := json.Marshal(webPushPayload{Nid: msg.Nid, Kind: msg.Kind, Title: msg.Title, Body: msg.Body, Data: msg.Data})
+	if err != nil {
+		return err
+	}
+	urgency := webpush.UrgencyNormal
+	if urgent[msg.Kind] {
+		urgency = webpush.UrgencyHigh
+	}
+
+	sent := 0
+	var lastErr error
+	for _, subscription := range subscriptions {
+		resp, err := webpush.SendNotificationWithContext(ctx, payload, &webpush.Subscription{
+			Endpoint: subscription.Endpoint,
+			Keys:     webpush.Keys{Auth: subscription.Keys.Auth, P256dh: subscription.Keys.P256dh},
+		}, &webpush.Options{
+			HTTPClient:      t.client,
+			Subscriber:      t.subject,
+			VAPIDPublicKey:  t.publicKey,
+			VAPIDPrivateKey: t.privateKey,
+			TTL:             webPushTTL,
+			Urgency:         urgency,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			// 브라우저가 구독을 해지했으므로 지움
+			if _, err := db.PushSubscriptionCollection.DeleteOne(ctx, bson.M{"endpoint": subscription.Endpoint}); err != nil {
+				lastErr = err
+			}
+		case resp.StatusCode >= 300:
+			lastErr = fmt.Errorf("push service returned %s", resp.Status)
+		default:
+			sent++
+		}
+	}
+
+	// 한 브라우저라도 받았으면 성공으로 봄
+	if sent > 0 {
+		return nil
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return ErrNoRecipient
+}
+
+// WebPushKey returns the public VAPID key, or false when web push is not configured
+func WebPushKey() (string, bool) {
+	t, ok := lookup(models.ChannelWebPush)
+	if !ok {
+		return "", false
+	}
+	keyed, ok := t.(interface{ PublicKey() string })
+	if !ok {
+		return "", false
+	}
+	return keyed.PublicKey(), true
+}
@@ -0,0 +1,40 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Merge applies an RFC 7396 JSON Merge Patch to doc. Members of the patch replace
+// those of doc, objects are merged recursively and null removes a member. Arrays are
+// replaced as a whole.
+func Merge(doc []byte, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target interface{}, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = merge(object[key], value)
+	}
+	return object
+}
@@ -0,0 +1,217 @@
+package reconcile
+
+import (
+	"dbserver/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func codes(issues []models.RecordIssue) []string {
+	out := []string{}
+	for _, issue := range issues {
+		out = append(out, issue.Code)
+	}
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		record   models.RecordInput
+		want     []string
+		expected int
+		actual   int
+	}{
+		{
+			name: "balanced",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 2}, {Pname: "식빵", Price: 3200, Amount: 1, Discount: 200}},
+				Discount:   1000,
+				TotalPrice: 7000,
+			},
+			want: []string{},
+		},
+		{
+			name: "sum mismatch",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 2}},
+				TotalPrice: 4000,
+			},
+			want:     []string{models.IssueSumMismatch},
+			expected: 5000,
+			actual:   4000,
+		},
+		{
+			name: "zero total",
+			record: models.RecordInput{
+				Product: []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 1}},
+			},
+			want:     []string{models.IssueZeroTotal},
+			expected: 2500,
+		},
+		{
+			name: "zero price",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 1}, {Pname: "봉투", Price: 0, Amount: 1}},
+				TotalPrice: 2500,
+			},
+			want: []string{models.IssueBadPrice},
+		},
+		{
+			name: "negative price on a purchase",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 1}, {Pname: "쿠폰", Price: -500, Amount: 1}},
+				TotalPrice: 2000,
+			},
+			want:   []string{models.IssueBadPrice},
+			actual: -500,
+		},
+		{
+			// 환불 영수증은 음수 가격이 정상
+			name: "negative price on a refund",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: -2500, Amount: 1}},
+				TotalPrice: -2500,
+			},
+			want: []string{},
+		},
+		{
+			name: "implausible amount",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "껌", Price: 1, Amount: 1000}},
+				TotalPrice: 1000,
+			},
+			want:     []string{models.IssueBadAmount},
+			expected: 1,
+			actual:   1000,
+		},
+		{
+			name: "zero amount",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 0}, {Pname: "식빵", Price: 3200, Amount: 1}},
+				TotalPrice: 3200,
+			},
+			want:     []string{models.IssueBadAmount},
+			expected: 1,
+		},
+		{
+			name: "discount larger than the line",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 1, Discount: 3000}, {Pname: "식빵", Price: 3200, Amount: 1}},
+				TotalPrice: 2700,
+			},
+			want:     []string{models.IssueDiscountTooLarge},
+			expected: 2500,
+			actual:   3000,
+		},
+		{
+			name:   "no products",
+			record: models.RecordInput{TotalPrice: 5000},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Check(tt.record, nil, now)
+			if got := codes(issues); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Check() = %v, want %v", got, tt.want)
+			}
+			if len(issues) == 0 {
+				return
+			}
+			if issues[0].Expected != tt.expected || issues[0].Actual != tt.actual {
+				t.Errorf("expected, actual = %d, %d, want %d, %d", issues[0].Expected, issues[0].Actual, tt.expected, tt.actual)
+			}
+			if !issues[0].DetectedAt.Equal(now) || issues[0].Resolved {
+				t.Errorf("issue = %+v", issues[0])
+			}
+		})
+	}
+}
+
+func TestCheckIssueIds(t *testing.T) {
+	record := models.RecordInput{
+		Product: []models.DBProduct{
+			{Pname: "우유", Price: 0, Amount: 1},
+			{Pname: "식빵", Price: 0, Amount: 1},
+		},
+		TotalPrice: 1000,
+	}
+	issues := Check(record, nil, time.Now())
+	if len(issues) != 3 {
+		t.Fatalf("Check() = %v, want two bad prices and a sum mismatch", codes(issues))
+	}
+	// 품목별 문제는 품목 이름으로 구분되고, 다시 검사해도 같은 id
+	if issues[0].IssueId == issues[1].IssueId || issues[0].Pname != "우유" || issues[1].Pname != "식빵" {
+		t.Errorf("line issues = %+v", issues[:2])
+	}
+	if issues[2].IssueId != models.IssueSumMismatch {
+		t.Errorf("sum issue id = %q", issues[2].IssueId)
+	}
+	if again := Check(record, nil, time.Now()); again[0].IssueId != issues[0].IssueId {
+		t.Errorf("issue id changed from %q to %q", issues[0].IssueId, again[0].IssueId)
+	}
+}
+
+func TestCheckCarriesOverResolution(t *testing.T) {
+	detected := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	resolvedAt := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	record := models.RecordInput{
+		Product:    []models.DBProduct{{Pname: "우유", Price: 2500, Amount: 2}},
+		TotalPrice: 4000,
+	}
+	previous := []models.RecordIssue{{
+		IssueId:    models.IssueSumMismatch,
+		Code:       models.IssueSumMismatch,
+		Expected:   5000,
+		Actual:     4000,
+		Resolved:   true,
+		ResolvedBy: "u1",
+		ResolvedAt: &resolvedAt,
+		DetectedAt: detected,
+	}}
+
+	issues := Check(record, previous, now)
+	if !Equal(issues, previous) || issues[0].ResolvedBy != "u1" || Open(issues) != 0 {
+		t.Errorf("Check() = %+v, want the resolved issue kept", issues)
+	}
+
+	// 값이 바뀌면 새 문제로 보고 다시 연다
+	record.TotalPrice = 4500
+	issues = Check(record, previous, now)
+	if Equal(issues, previous) || issues[0].Resolved || !issues[0].DetectedAt.Equal(now) || Open(issues) != 1 {
+		t.Errorf("Check() = %+v, want a new open issue", issues)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	at := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	base := []models.RecordIssue{{IssueId: "sum_mismatch", Expected: 5000, Actual: 4000, DetectedAt: at}}
+	change := func(f func(issue *models.RecordIssue)) []models.RecordIssue {
+		changed := append([]models.RecordIssue{}, base...)
+		f(&changed[0])
+		return changed
+	}
+
+	if !Equal(base, change(func(issue *models.RecordIssue) {})) {
+		t.Errorf("Equal() = false for the same issues")
+	}
+	if !Equal(nil, []models.RecordIssue{}) {
+		t.Errorf("Equal(nil, empty) = false")
+	}
+	for name, other := range map[string][]models.RecordIssue{
+		"longer":   append(change(func(issue *models.RecordIssue) {}), base...),
+		"id":       change(func(issue *models.RecordIssue) { issue.IssueId = "zero_total" }),
+		"expected": change(func(issue *models.RecordIssue) { issue.Expected = 5100 }),
+		"actual":   change(func(issue *models.RecordIssue) { issue.Actual = 4100 }),
+		"resolved": change(func(issue *models.RecordIssue) { issue.Resolved = true }),
+		"detected": change(func(issue *models.RecordIssue) { issue.DetectedAt = at.Add(time.Hour) }),
+	} {
+		if Equal(base, other) {
+			t.Errorf("Equal() = true with a different %s", name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package recurring
+
+import (
+	"context"
+	"dbserver/db"
+	"dbserver/models"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// MinPurchases is the number of purchases on different days needed to see a pattern
+	MinPurchases = 3
+	// MaxVariation is the largest coefficient of variation of the intervals that still
+	// counts as regular, e.g. intervals of 4, 5 and 7 days are regular but 2, 9 and 20 are not
+	MaxVariation = 0.5
+	// 마지막 구매 후 주기의 몇 배가 지나면 더 이상 사지 않는 것으로 봄
+	staleIntervals = 3
+)
+
+type purchases struct {
+	NormName string      `bson:"_id"`
+	Names    []string    `bson:"names"`
+	Dates    []time.Time `bson:"dates"`
+}
+
+// Detect finds the products of the records matched by match that are bought at a
+// regular interval and predicts when each is due next. Purchase days are taken in
+// loc. The result is sorted by the next due date.
+func Detect(ctx context.Context, match bson.M, loc *time.Location, now time.Time) ([]models.RecurringItem, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.M{"record.timeStamp.at": 1}}},
+		{{Key: "$unwind", Value: "$product"}},
+		{{Key: "$match", Value: bson.M{"product.normName": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$product.normName",
+			"names": bson.M{"$push": "$product.pname"},
+			"dates": bson.M{"$push": "$record.timeStamp.at"},
+		}}},
+		{{Key: "$match", Value: bson.M{"dates." + strconv.Itoa(MinPurchases-1): bson.M{"$exists": true}}}},
+	}
+
+	cursor, err := db.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []purchases
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	today := day(now, loc)
+	items := []models.RecurringItem{}
+	for _, g := range groups {
+		item, ok := analyze(g, loc, today)
+		if ok {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].NextDue.Equal(items[j].NextDue) {
+			return items[i].NextDue.Before(items[j].NextDue)
+		}
+		return items[i].NormName < items[j].NormName
+	})
+	return items, nil
+}
+
+// analyze decides whether the purchases of one product are regular
+func analyze(g purchases, loc *time.Location, today time.Time) (models.RecurringItem, bool) {
+	// 같은 날 여러 번 산 것은 한 번으로 셈
+	var days []time.Time
+	for _, at := range g.Dates {
+		d := day(at, loc)
+		if len(days) == 0 || !d.Equal(days[len(days)-1]) {
+			days = append(days, d)
+		}
+	}
+	if len(days) < MinPurchases {
+		return models.RecurringItem{}, false
+	}
+
+	intervals := make([]float64, 0, len(days)-1)
+	for i := 1; i < len(days); i++ {
+		intervals = append(intervals, float64(daysBetween(days[i-1], days[i])))
+	}
+
+	mean := 0.0
+	for _, v := range intervals {
+		mean += v
+	}
+	mean /= float64(len(intervals))
+
+	variance := 0.0
+	for _, v := range intervals {
+		variance += (v - mean) * (v - mean)
+	}
+	variation := math.Sqrt(variance/float64(len(intervals))) / mean
+	if variation > MaxVariation {
+		return models.RecurringItem{}, false
+	}
+
+	interval := median(intervals)
+	last := days[len(days)-1]
+	if float64(daysBetween(last, today)) > interval*staleIntervals {
+		return models.RecurringItem{}, false
+	}
+
+	nextDue := last.AddDate(0, 0, int(math.Round(interval)))
+	return models.RecurringItem{
+		NormName:      g.NormName,
+		Pname:         g.Names[len(g.Names)-1],
+		Purchases:     len(days),
+		IntervalDays:  math.Round(interval*10) / 10,
+		LastPurchased: last,
+		NextDue:       nextDue,
+		DaysUntilDue:  daysBetween(today, nextDue),
+		Regularity:    math.Round((1-variation)*100) / 100,
+	}, true
+}
+
+// Due returns the items due within the given number of days, overdue ones included
+func Due(items []models.RecurringItem, within int) []models.RecurringItem {
+	due := []models.RecurringItem{}
+	for _, item := range items {
+		if item.DaysUntilDue <= within {
+			due = append(due, item)
+		}
+	}
+	return due
+}
+
+func day(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
+
+// daysBetween counts calendar days, so a daylight saving change does not shift it
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
@@ -0,0 +1,40 @@
+package reports
+
+import (
+	"dbserver/config"
+	"errors"
+	"log"
+	"os"
+	"sync"
+)
+
+// ErrNoFont is returned when the configured report font cannot be read
+var ErrNoFont = errors.New("report font is not available")
+
+var (
+	fontOnce    sync.Once
+	regularFont []byte
+	boldFont    []byte
+	fontErr     error
+)
+
+// fonts reads the report fonts once
+func fonts() ([]byte, []byte, error) {
+	fontOnce.Do(func() {
+		cfg := config.AppConfig.Report
+		var err error
+		regularFont, err = os.ReadFile(cfg.Font)
+		if err != nil {
+			log.Printf("Report font error: %v\n", err)
+			fontErr = ErrNoFont
+			return
+		}
+		// 굵은 글꼴이 없으면 보통 글꼴로 대신함
+		boldFont, err = os.ReadFile(cfg.BoldFont)
+		if err != nil {
+			log.Printf("Report font error: %v\n", err)
+			boldFont = regularFont
+		}
+	})
+	return regularFont, boldFont, fontErr
+}
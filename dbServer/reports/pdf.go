@@ -0,0 +1,358 @@
+package reports
+
+import (
+	"bytes"
+	"dbserver/analytics"
+	"dbserver/category"
+	"dbserver/currency"
+	"dbserver/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	fontFamily = "report"
+
+	pageMargin = 15.0
+	rowHeight  = 7.0
+	chartH     = 50.0
+)
+
+// 표와 차트에 쓰는 색
+var (
+	accent = [3]int{46, 110, 200}
+	muted  = [3]int{120, 120, 120}
+	light  = [3]int{242, 244, 247}
+)
+
+// page wraps the document with the helpers of the report layout
+type page struct {
+	*fpdf.Fpdf
+	currency string
+}
+
+// Render writes report as an A4 PDF in Korean
+func Render(report *models.MonthlyReport) ([]byte, error) {
+	regular, bold, err := fonts()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.AddUTF8FontFromBytes(fontFamily, "", regular)
+	doc.AddUTF8FontFromBytes(fontFamily, "B", bold)
+	doc.SetMargins(pageMargin, pageMargin, pageMargin)
+	doc.SetAutoPageBreak(true, pageMargin)
+	doc.SetTitle(monthTitle(report.Period.From)+" 지출 보고서", true)
+	doc.SetCreator("dbServer", true)
+	doc.AliasNbPages("")
+
+	p := &page{Fpdf: doc, currency: report.Currency}
+	generated := report.GeneratedAt.In(report.Period.From.Location()).Format("2006-01-02 15:04")
+	doc.SetFooterFunc(func() {
+		doc.SetY(-pageMargin + 3)
+		p.font("", 8, muted)
+		doc.CellFormat(0, 5, fmt.Sprintf("생성 %s · %d/{nb}", generated, doc.PageNo()), "", 0, "R", false, 0, "")
+	})
+	doc.AddPage()
+
+	p.header(report)
+	p.summary(report)
+	if report.Totals.Receipts == 0 {
+		p.font("", 11, muted)
+		doc.Ln(6)
+		doc.CellFormat(0, 10, "이 달에는 기록된 영수증이 없습니다.", "", 1, "C", false, 0, "")
+	} else {
+		p.dailyChart(report.Days)
+		p.categories(report.Categories, report.Totals.Total)
+		p.marts(report.Marts)
+		p.products(report.Products)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *page) header(report *models.MonthlyReport) {
+	p.font("B", 20, [3]int{})
+	p.CellFormat(0, 11, monthTitle(report.Period.From)+" 지출 보고서", "", 1, "L", false, 0, "")
+
+	last := report.Period.To.AddDate(0, 0, -1)
+	p.font("", 10, muted)
+	p.CellFormat(0, 6, fmt.Sprintf("%s ~ %s · 통화 %s", report.Period.From.Format("2006-01-02"), last.Format("2006-01-02"), report.Currency), "", 1, "L", false, 0, "")
+	p.Ln(4)
+}
+
+// summary draws the boxes with the totals of the month
+func (p *page) summary(report *models.MonthlyReport) {
+	change := "-"
+	if report.Change != nil {
+		change = fmt.Sprintf("%+.1f%%", *report.Change*100)
+	}
+	boxes := [][2]string{
+		{"총 지출", p.money(report.Totals.Total)},
+		{"영수증", strconv.Itoa(report.Totals.Receipts) + "건"},
+		{"평균 결제액", p.money(int(report.Totals.AvgBasket + 0.5))},
+		{"전월 대비", change},
+	}
+
+	width, _ := p.GetPageSize()
+	gap := 4.0
+	boxW := (width - 2*pageMargin - gap*float64(len(boxes)-1)) / float64(len(boxes))
+	boxH := 20.0
+	y := p.GetY()
+	for i, box := range boxes {
+		x := pageMargin + float64(i)*(boxW+gap)
+		p.SetFillColor(light[0], light[1], light[2])
+		p.Rect(x, y, boxW, boxH, "F")
+
+		p.SetXY(x+3, y+3)
+		p.font("", 9, muted)
+		p.CellFormat(boxW-6, 5, box[0], "", 0, "L", false, 0, "")
+		p.SetXY(x+3, y+10)
+		p.font("B", 13, [3]int{})
+		p.CellFormat(boxW-6, 7, p.fit(box[1], boxW-6), "", 0, "L", false, 0, "")
+	}
+	p.SetXY(pageMargin, y+boxH+3)
+
+	if report.Totals.Unconverted > 0 {
+		p.font("", 8, muted)
+		p.CellFormat(0, 5, fmt.Sprintf("환율이 없어 %s로 바꿀 수 없는 영수증 %d건은 빠져 있습니다.", report.Currency, report.Totals.Unconverted), "", 1, "L", false, 0, "")
+	}
+	p.Ln(4)
+}
+
+// dailyChart draws the spend of every day as a bar
+func (p *page) dailyChart(days []models.SpendingBucket) {
+	p.section("일별 지출", chartH+14)
+
+	max := 0
+	for _, day := range days {
+		if day.Total > max {
+			max = day.Total
+		}
+	}
+
+	width, _ := p.GetPageSize()
+	labelW := 22.0
+	x0 := pageMargin + labelW
+	chartW := width - 2*pageMargin - labelW
+	y0 := p.GetY() + 2
+	base := y0 + chartH
+
+	p.SetDrawColor(200, 200, 200)
+	p.Line(x0, base, x0+chartW, base)
+	p.Line(x0, y0, x0+chartW, y0)
+	p.font("", 7, muted)
+	p.SetXY(pageMargin, y0-2)
+	p.CellFormat(labelW-2, 4, p.fit(p.money(max), labelW-2), "", 0, "R", false, 0, "")
+	p.SetXY(pageMargin, base-2)
+	p.CellFormat(labelW-2, 4, "0", "", 0, "R", false, 0, "")
+
+	step := chartW / float64(len(days))
+	p.SetFillColor(accent[0], accent[1], accent[2])
+	for i, day := range days {
+		x := x0 + float64(i)*step
+		if max > 0 && day.Total > 0 {
+			h := chartH * float64(day.Total) / float64(max)
+			p.Rect(x+step*0.15, base-h, step*0.7, h, "F")
+		}
+		// 날짜는 1일과 5일 간격으로 표시
+		if n := i + 1; n == 1 || n%5 == 0 {
+			p.SetXY(x, base+1)
+			p.CellFormat(step, 4, strconv.Itoa(n), "", 0, "C", false, 0, "")
+		}
+	}
+	p.SetXY(pageMargin, base+8)
+}
+
+// categories lists the spend of every category with its share of the month
+func (p *page) categories(categories []models.SpendingBucket, total int) {
+	if len(categories) == 0 {
+		return
+	}
+	p.section("카테고리별 지출", 2*rowHeight)
+
+	width, _ := p.GetPageSize()
+	nameW, amountW, shareW := 40.0, 35.0, 18.0
+	barW := width - 2*pageMargin - nameW - amountW - shareW - 4
+
+	max := categories[0].Total
+	for _, bucket := range categories {
+		p.ensure(rowHeight)
+		y := p.GetY()
+
+		p.font("", 9, [3]int{})
+		p.CellFormat(nameW, rowHeight, p.fit(categoryLabel(bucket.Key), nameW), "", 0, "L", false, 0, "")
+		if max > 0 && bucket.Total > 0 {
+			p.SetFillColor(accent[0], accent[1], accent[2])
+			p.Rect(pageMargin+nameW, y+2, barW*float64(bucket.Total)/float64(max), rowHeight-4, "F")
+		}
+		p.SetX(pageMargin + nameW + barW + 4)
+		p.CellFormat(amountW, rowHeight, p.money(bucket.Total), "", 0, "R", false, 0, "")
+		share := 0.0
+		if total > 0 {
+			share = float64(bucket.Total) / float64(total) * 100
+		}
+		p.font("", 9, muted)
+		p.CellFormat(shareW, rowHeight, fmt.Sprintf("%.1f%%", share), "", 1, "R", false, 0, "")
+	}
+	p.Ln(4)
+}
+
+// marts lists the marts with the highest spend
+func (p *page) marts(marts []models.SpendingBucket) {
+	if len(marts) == 0 {
+		return
+	}
+	rows := make([][]string, len(marts))
+	for i, mart := range marts {
+		name := mart.Key
+		if name == "" {
+			name = "(매장 정보 없음)"
+		}
+		rows[i] = []string{strconv.Itoa(i + 1), name, strconv.Itoa(mart.Count) + "회", p.money(mart.Total)}
+	}
+	p.table("자주 간 매장", []string{"", "매장", "방문", "지출"}, []float64{8, 0, 25, 40}, rows)
+}
+
+// products lists the products with the highest spend
+func (p *page) products(products []models.ProductStat) {
+	if len(products) == 0 {
+		return
+	}
+	rows := make([][]string, len(products))
+	for i, product := range products {
+		rows[i] = []string{
+			strconv.Itoa(i + 1),
+			product.Pname,
+			strconv.Itoa(product.Quantity) + "개",
+			strconv.Itoa(product.Purchases) + "회",
+			p.money(product.Spend),
+		}
+	}
+	p.table("많이 산 품목", []string{"", "품목", "수량", "구매", "지출"}, []float64{8, 0, 20, 20, 40}, rows)
+}
+
+// table draws rows under a heading. A zero width takes the space the others leave.
+// The first column is centered, the second left aligned and the others right aligned.
+func (p *page) table(title string, heads []string, widths []float64, rows [][]string) {
+	p.section(title, 2*rowHeight)
+
+	width, _ := p.GetPageSize()
+	rest := width - 2*pageMargin
+	for _, w := range widths {
+		rest -= w
+	}
+	for i := range widths {
+		if widths[i] == 0 {
+			widths[i] = rest
+		}
+	}
+	align := func(i int) string {
+		switch i {
+		case 0:
+			return "C"
+		case 1:
+			return "L"
+		}
+		return "R"
+	}
+
+	p.SetFillColor(light[0], light[1], light[2])
+	p.font("B", 9, muted)
+	for i, head := range heads {
+		p.CellFormat(widths[i], rowHeight, head, "", 0, align(i), true, 0, "")
+	}
+	p.Ln(-1)
+
+	p.SetDrawColor(225, 225, 225)
+	for _, row := range rows {
+		p.ensure(rowHeight)
+		p.font("", 9, [3]int{})
+		for i, value := range row {
+			p.CellFormat(widths[i], rowHeight, p.fit(value, widths[i]-1), "B", 0, align(i), false, 0, "")
+		}
+		p.Ln(-1)
+	}
+	p.Ln(4)
+}
+
+// section writes a heading, starting a new page unless it and the first need mm fit
+func (p *page) section(title string, need float64) {
+	p.ensure(10 + need)
+	p.font("B", 12, [3]int{})
+	p.CellFormat(0, 10, title, "", 1, "L", false, 0, "")
+}
+
+// ensure starts a new page unless h mm are left
+func (p *page) ensure(h float64) {
+	_, height := p.GetPageSize()
+	if p.GetY()+h > height-pageMargin-5 {
+		p.AddPage()
+	}
+}
+
+func (p *page) font(style string, size float64, color [3]int) {
+	p.SetFont(fontFamily, style, size)
+	p.SetTextColor(color[0], color[1], color[2])
+}
+
+// fit shortens s until it fits in w mm in the current font
+func (p *page) fit(s string, w float64) string {
+	if p.GetStringWidth(s) <= w {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && p.GetStringWidth(string(runes)+"..") > w {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + ".."
+}
+
+// money formats an amount in minor units of the report currency with digit grouping
+func (p *page) money(amount int) string {
+	value := currency.Format(amount, p.currency)
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+	whole, fraction, _ := strings.Cut(value, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	if fraction != "" {
+		grouped.WriteString("." + fraction)
+	}
+
+	if p.currency == currency.Default {
+		return sign + grouped.String() + "원"
+	}
+	return sign + grouped.String() + " " + p.currency
+}
+
+func monthTitle(month time.Time) string {
+	return fmt.Sprintf("%d년 %d월", month.Year(), int(month.Month()))
+}
+
+func categoryLabel(key string) string {
+	if key == analytics.Uncategorized {
+		return "미분류"
+	}
+	if label, ok := category.Labels[key]; ok {
+		return label
+	}
+	return key
+}
@@ -0,0 +1,262 @@
+// Package reports builds the monthly spending reports of a user and renders them as
+// PDF. A rendered report is kept with a hash of the records and exchange rates it was
+// built from and is only rendered again once that hash changes.
+package reports
+
+import (
+	"context"
+	"crypto/sha256"
+	"dbserver/analytics"
+	"dbserver/currency"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/household"
+	"dbserver/models"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// layoutVersion is part of the hash so cached reports are rendered again when the
+// layout changes
+const layoutVersion = "1"
+
+const (
+	// topMarts and topProducts are how many marts and products a report lists
+	topMarts    = 5
+	topProducts = 10
+)
+
+// Request selects the report of a user
+type Request struct {
+	Access *household.Access
+	// Group is "personal", a household id, or "" for every record the user can read
+	Group string
+	// Month is YYYY-MM in Loc
+	Month string
+	Home  string
+	Loc   *time.Location
+}
+
+// ParseMonth reads a YYYY-MM month into its period in loc
+func ParseMonth(month string, loc *time.Location) (models.Period, error) {
+	from, err := time.ParseInLocation("2006-01", month, loc)
+	if err != nil {
+		return models.Period{}, fmt.Errorf("month must be YYYY-MM")
+	}
+	return models.Period{From: from, To: from.AddDate(0, 1, 0)}, nil
+}
+
+// Generate returns the PDF report of req and the hash identifying its contents. The
+// stored copy is returned while the records of the month, and of the month before it
+// is compared with, and the exchange rates they are converted with are unchanged.
+func Generate(ctx context.Context, req Request) ([]byte, string, error) {
+	period, err := ParseMonth(req.Month, req.Loc)
+	if err != nil {
+		return nil, "", err
+	}
+	scope, err := req.Access.Scope(req.Group)
+	if err != nil {
+		return nil, "", err
+	}
+	base := bson.M{"$or": scope, "record.rid": bson.M{"$exists": true}}
+
+	hash, err := fingerprint(ctx, base, period.From.AddDate(0, -1, 0), period.To, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := bson.M{"uid": req.Access.Uid, "group": req.Group, "month": req.Month}
+	var cached models.ReportFile
+	err = db.ReportCollection.FindOne(ctx, key).Decode(&cached)
+	if err == nil && cached.Hash == hash {
+		return cached.Pdf, hash, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, "", err
+	}
+
+	report, err := Build(ctx, base, req.Month, period, req.Home, req.Loc)
+	if err != nil {
+		return nil, "", err
+	}
+	pdf, err := Render(report)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file := models.ReportFile{
+		Uid:       req.Access.Uid,
+		Group:     req.Group,
+		Month:     req.Month,
+		Hash:      hash,
+		Pdf:       pdf,
+		CreatedAt: report.GeneratedAt,
+	}
+	if _, err := db.ReportCollection.ReplaceOne(ctx, key, file, options.Replace().SetUpsert(true)); err != nil {
+		return nil, "", err
+	}
+	return pdf, hash, nil
+}
+
+// Build collects the figures of the report of period from the records matched by base
+func Build(ctx context.Context, base bson.M, month string, period models.Period, home string, loc *time.Location) (*models.MonthlyReport, error) {
+	current := inPeriod(base, period.From, period.To)
+	previous := inPeriod(base, period.From.AddDate(0, -1, 0), period.From)
+
+	report := &models.MonthlyReport{
+		Month:       month,
+		Period:      period,
+		Currency:    home,
+		GeneratedAt: time.Now(),
+	}
+
+	var err error
+	if report.Totals, err = analytics.Totals(ctx, current, home, loc); err != nil {
+		return nil, err
+	}
+	previousTotals, err := analytics.Totals(ctx, previous, home, loc)
+	if err != nil {
+		return nil, err
+	}
+	report.PreviousTotal = previousTotals.Total
+	report.Change = analytics.Change(float64(report.Totals.Total), float64(previousTotals.Total))
+
+	if report.Categories, err = analytics.Spending(ctx, current, analytics.GroupByCategory, home, loc); err != nil {
+		return nil, err
+	}
+	if report.Marts, err = analytics.Spending(ctx, current, analytics.GroupByMart, home, loc); err != nil {
+		return nil, err
+	}
+	if len(report.Marts) > topMarts {
+		report.Marts = report.Marts[:topMarts]
+	}
+	if report.Products, err = analytics.TopProducts(ctx, current, analytics.TopBySpend, topProducts, home, loc); err != nil {
+		return nil, err
+	}
+
+	days, err := analytics.Spending(ctx, current, analytics.GroupByDay, home, loc)
+	if err != nil {
+		return nil, err
+	}
+	report.Days = fillDays(days, period)
+
+	return report, nil
+}
+
+// fillDays returns a bucket for every day of period, with zero for days without spend
+func fillDays(days []models.SpendingBucket, period models.Period) []models.SpendingBucket {
+	byDay := make(map[string]models.SpendingBucket, len(days))
+	for _, day := range days {
+		byDay[day.Key] = day
+	}
+
+	filled := []models.SpendingBucket{}
+	for day := period.From; day.Before(period.To); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		bucket, ok := byDay[key]
+		if !ok {
+			bucket = models.SpendingBucket{Key: key}
+		}
+		filled = append(filled, bucket)
+	}
+	return filled
+}
+
+func inPeriod(base bson.M, from time.Time, to time.Time) bson.M {
+	match := bson.M{}
+	for k, v := range base {
+		match[k] = v
+	}
+	match["record.timeStamp.at"] = bson.M{"$gte": from, "$lt": to}
+	return match
+}
+
+// fingerprint hashes the records of base purchased between from and to, and the
+// exchange rates they are converted with, together with the settings the report
+// depends on
+func fingerprint(ctx context.Context, base bson.M, from time.Time, to time.Time, req Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00", layoutVersion, req.Month, req.Group, req.Home, req.Loc.String())
+
+	// 레코드 문서를 그대로 해시하므로 어떤 필드가 바뀌어도 새로 만듦
+	match := inPeriod(base, from, to)
+	cursor, err := db.Collection.Find(ctx, match, options.Find().SetSort(bson.D{{Key: "record.rid", Value: 1}}))
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		h.Write(cursor.Current)
+	}
+	if err := cursor.Err(); err != nil {
+		return "", err
+	}
+
+	if err := hashRates(ctx, h, match, from.In(req.Loc).Format(dates.DateLayout), to.In(req.Loc).Format(dates.DateLayout), req.Home); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashRates writes to h the rates the records matched by match are converted to home
+// with: for the home currency and each currency of the records, the rates dated from
+// from up to to and the last rate before from, which covers the first days
+func hashRates(ctx context.Context, h io.Writer, match bson.M, from string, to string, home string) error {
+	values, err := db.Collection.Distinct(ctx, "currency", match)
+	if err != nil {
+		return err
+	}
+	codes := map[string]bool{currency.Normalize(home): true}
+	for _, value := range values {
+		if code, ok := value.(string); ok {
+			codes[currency.Normalize(code)] = true
+		}
+	}
+	// 원화는 환율표를 쓰지 않음
+	delete(codes, currency.Default)
+
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Strings(sorted)
+
+	for _, code := range sorted {
+		var rates []models.ExchangeRate
+		var before models.ExchangeRate
+		err := db.RateCollection.FindOne(ctx,
+			bson.M{"currency": code, "date": bson.M{"$lt": from}},
+			options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}}),
+		).Decode(&before)
+		if err == nil {
+			rates = append(rates, before)
+		} else if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		cursor, err := db.RateCollection.Find(ctx,
+			bson.M{"currency": code, "date": bson.M{"$gte": from, "$lt": to}},
+			options.Find().SetSort(bson.D{{Key: "date", Value: 1}}),
+		)
+		if err != nil {
+			return err
+		}
+		var window []models.ExchangeRate
+		if err := cursor.All(ctx, &window); err != nil {
+			return err
+		}
+
+		for _, rate := range append(rates, window...) {
+			fmt.Fprintf(h, "%s\x00%s\x00%v\x00", code, rate.Date, rate.Rate)
+		}
+	}
+	return nil
+}
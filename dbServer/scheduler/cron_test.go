@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// 2026-03-14 is a Saturday
+	at := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+	}
+	saturday := at(2026, 3, 14, 10, 5)
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"hourly", "@hourly", saturday, at(2026, 3, 14, 11, 0)},
+		{"daily", "@daily", saturday, at(2026, 3, 15, 0, 0)},
+		{"weekly", "@weekly", saturday, at(2026, 3, 15, 0, 0)},
+		{"monthly", "@monthly", saturday, at(2026, 4, 1, 0, 0)},
+		{"yearly", "@yearly", saturday, at(2027, 1, 1, 0, 0)},
+		{"descriptor with spaces", " @daily ", saturday, at(2026, 3, 15, 0, 0)},
+		{"strictly after", "5 10 * * *", saturday, at(2026, 3, 15, 10, 5)},
+		{"minute step", "*/15 * * * *", saturday, at(2026, 3, 14, 10, 15)},
+		{"range step", "0 8-18/2 * * *", saturday, at(2026, 3, 14, 12, 0)},
+		{"range step wraps to the next day", "0 8-18/2 * * *", at(2026, 3, 14, 18, 30), at(2026, 3, 15, 8, 0)},
+		{"value step", "0 5/6 * * *", saturday, at(2026, 3, 14, 11, 0)},
+		{"list", "0,30 * * * *", saturday, at(2026, 3, 14, 10, 30)},
+		{"weekdays", "30 9 * * 1-5", saturday, at(2026, 3, 16, 9, 30)},
+		{"sunday as 7", "0 9 * * 7", saturday, at(2026, 3, 15, 9, 0)},
+		{"sunday as 0", "0 9 * * 0", saturday, at(2026, 3, 15, 9, 0)},
+		{"range ending on 7", "0 9 * * 6-7", at(2026, 3, 14, 9, 30), at(2026, 3, 15, 9, 0)},
+		{"day of month or week, week first", "0 0 13 * 5", saturday, at(2026, 3, 20, 0, 0)},
+		{"day of month or week, month first", "0 0 13 * 5", at(2026, 5, 10, 0, 0), at(2026, 5, 13, 0, 0)},
+		{"day of month step and week", "0 0 */2 * 1", saturday, at(2026, 3, 23, 0, 0)},
+		{"day of month and week step", "0 0 1 * */2", saturday, at(2026, 8, 1, 0, 0)},
+		{"month without the day", "0 0 31 * *", at(2026, 4, 1, 0, 0), at(2026, 5, 31, 0, 0)},
+		{"month list", "0 0 1 1,7 *", saturday, at(2026, 7, 1, 0, 0)},
+		{"year end", "0 0 * * *", at(2026, 12, 31, 23, 59), at(2027, 1, 1, 0, 0)},
+		{"leap day", "0 0 29 2 *", saturday, at(2028, 2, 29, 0, 0)},
+		{"never", "0 0 30 2 *", saturday, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCron(tt.expr, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	kst := time.FixedZone("KST", 9*60*60)
+	s, err := ParseCron("0 9 * * *", kst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 09:30 KST
+	from := time.Date(2026, 3, 14, 0, 30, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 15, 9, 0, 0, 0, kst)
+	if got := s.Next(from); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@often",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	} {
+		if _, err := ParseCron(expr, time.UTC); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
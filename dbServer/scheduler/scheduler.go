@@ -0,0 +1,348 @@
+// Package scheduler runs background jobs on cron schedules. Every replica runs the
+// scheduler; the state of each job in Mongo doubles as a lock so a scheduled run is
+// claimed by one replica only. Runs are retried on failure and kept as history.
+package scheduler
+
+import (
+	"context"
+	"dbserver/dates"
+	"dbserver/db"
+	"dbserver/models"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pollInterval is how often the replicas look for due jobs
+const pollInterval = 30 * time.Second
+
+var (
+	// ErrUnknownJob is returned for a name no job is registered under
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrRunning is returned when the job is already running on some replica
+	ErrRunning = errors.New("job is already running")
+)
+
+// Job is a task run on a schedule
+type Job struct {
+	Name        string
+	Description string
+	// Schedule is a cron expression in the default time zone, see ParseCron
+	Schedule string
+	// Timeout bounds one attempt
+	Timeout time.Duration
+	// Retries is how many more attempts a failing run gets, RetryDelay times the
+	// attempt number apart
+	Retries    int
+	RetryDelay time.Duration
+	// Run does the work and returns a summary for the run history
+	Run func(ctx context.Context) (string, error)
+
+	schedule *Schedule
+}
+
+// lease is how long a replica may hold the job before others consider it dead
+func (j *Job) lease() time.Duration {
+	attempts := time.Duration(j.Retries + 1)
+	delays := j.RetryDelay * time.Duration(j.Retries*(j.Retries+1)/2)
+	return j.Timeout*attempts + delays + time.Minute
+}
+
+var (
+	mu        sync.Mutex
+	jobs      = map[string]*Job{}
+	startOnce sync.Once
+	// instance names this replica in locks and run history
+	instance = instanceName()
+)
+
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// Register adds a job. It panics on an invalid schedule or a duplicate name since both
+// are programming errors.
+func Register(job Job) {
+	schedule, err := ParseCron(job.Schedule, dates.LoadLocation(""))
+	if err != nil {
+		panic(fmt.Sprintf("job %s: %v", job.Name, err))
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		panic(fmt.Sprintf("job %s: schedule %q never runs", job.Name, job.Schedule))
+	}
+	job.schedule = schedule
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := jobs[job.Name]; ok {
+		panic("job registered twice: " + job.Name)
+	}
+	jobs[job.Name] = &job
+}
+
+// registered returns the jobs by name
+func registered() []*Job {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func lookup(name string) (*Job, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	job, ok := jobs[name]
+	return job, ok
+}
+
+// Start stores the schedules of the registered jobs and starts running them
+func Start() {
+	startOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, job := range registered() {
+			if err := initState(ctx, job); err != nil {
+				log.Printf("Job error: %v\n", err)
+			}
+		}
+		go loop()
+	})
+}
+
+// initState creates the state of job, or moves its next run when the schedule changed
+func initState(ctx context.Context, job *Job) error {
+	_, err := db.JobCollection.UpdateOne(ctx,
+		bson.M{"_id": job.Name, "schedule": bson.M{"$ne": job.Schedule}},
+		bson.M{"$set": bson.M{"schedule": job.Schedule, "nextRun": job.schedule.Next(time.Now())}},
+		options.Update().SetUpsert(true),
+	)
+	// 같은 일정으로 이미 있으면 upsert가 키 중복으로 실패하므로 무시
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func loop() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for _, job := range registered() {
+			run, err := claim(job, models.JobTriggerSchedule)
+			if err != nil {
+				if err != ErrRunning {
+					log.Printf("Job error: %v\n", err)
+				}
+				continue
+			}
+			go execute(job, run)
+		}
+		<-ticker.C
+	}
+}
+
+// Trigger runs a job now, outside its schedule, and returns the started run
+func Trigger(name string) (*models.JobRun, error) {
+	job, ok := lookup(name)
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	run, err := claim(job, models.JobTriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	go execute(job, run)
+	return run, nil
+}
+
+// claim takes the lock of job and records the start of a run. A scheduled claim only
+// succeeds once the next run is due and moves it to the following occurrence, so each
+// occurrence runs once whichever replica sees it first.
+func claim(job *Job, trigger string) (*models.JobRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": job.Name,
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	set := bson.M{"lockedBy": instance, "lockedUntil": now.Add(job.lease())}
+	if trigger == models.JobTriggerSchedule {
+		filter["nextRun"] = bson.M{"$lte": now}
+		set["nextRun"] = job.schedule.Next(now)
+	}
+
+	result, err := db.JobCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrRunning
+	}
+	abandon(ctx, job, now)
+
+	run := &models.JobRun{
+		RunId:     uuid.NewString(),
+		Job:       job.Name,
+		Trigger:   trigger,
+		Instance:  instance,
+		Status:    models.JobRunRunning,
+		StartedAt: now,
+	}
+	if _, err := db.JobRunCollection.InsertOne(ctx, run); err != nil {
+		release(ctx, job)
+		return nil, err
+	}
+	return run, nil
+}
+
+// abandon marks the runs of job that are still running as failed. The lock is only
+// free again once a run finished or its lease expired, so a run still running after
+// a new claim was left behind by a replica that stopped. A run that only overran its
+// lease stores its real outcome when it finishes.
+func abandon(ctx context.Context, job *Job, now time.Time) {
+	_, err := db.JobRunCollection.UpdateMany(ctx,
+		bson.M{"job": job.Name, "status": models.JobRunRunning, "startedAt": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{
+			"status":     models.JobRunFailed,
+			"error":      "abandoned: the lease expired before the run finished",
+			"finishedAt": now,
+		}},
+	)
+	if err != nil {
+		log.Printf("Job error: %v\n", err)
+	}
+}
+
+// execute runs job, retrying failed attempts, and stores the outcome of run
+func execute(job *Job, run *models.JobRun) {
+	var message string
+	var err error
+	for attempt := 1; attempt <= job.Retries+1; attempt++ {
+		if attempt > 1 {
+			time.Sleep(job.RetryDelay * time.Duration(attempt-1))
+		}
+		run.Attempts = attempt
+		message, err = attemptRun(job)
+		if err == nil {
+			break
+		}
+		log.Printf("Job %s attempt %d error: %v\n", job.Name, attempt, err)
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Message = message
+	run.Status = models.JobRunSucceeded
+	if err != nil {
+		run.Status = models.JobRunFailed
+		run.Error = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := db.JobRunCollection.ReplaceOne(ctx, bson.M{"runId": run.RunId}, run); err != nil {
+		log.Printf("Job error: %v\n", err)
+	}
+	release(ctx, job)
+}
+
+// attemptRun runs job once, turning a panic into an error
+func attemptRun(job *Job) (message string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), job.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func release(ctx context.Context, job *Job) {
+	_, err := db.JobCollection.UpdateOne(ctx,
+		bson.M{"_id": job.Name, "lockedBy": instance},
+		bson.M{"$unset": bson.M{"lockedBy": "", "lockedUntil": ""}},
+	)
+	if err != nil {
+		log.Printf("Job error: %v\n", err)
+	}
+}
+
+// List returns every registered job with its state and latest run
+func List(ctx context.Context) ([]models.JobInfo, error) {
+	cursor, err := db.JobCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var states []models.JobState
+	if err := cursor.All(ctx, &states); err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.JobState, len(states))
+	for _, state := range states {
+		byName[state.Name] = state
+	}
+
+	now := time.Now()
+	infos := []models.JobInfo{}
+	for _, job := range registered() {
+		info := models.JobInfo{Name: job.Name, Description: job.Description, Schedule: job.Schedule}
+		if state, ok := byName[job.Name]; ok {
+			next := state.NextRun
+			info.NextRun = &next
+			info.Running = state.LockedUntil != nil && state.LockedUntil.After(now)
+		}
+
+		runs, err := Runs(ctx, job.Name, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			info.LastRun = &runs[0]
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Runs returns the latest runs of a job, newest first
+func Runs(ctx context.Context, name string, limit int64) ([]models.JobRun, error) {
+	if _, ok := lookup(name); !ok {
+		return nil, ErrUnknownJob
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetLimit(limit)
+	cursor, err := db.JobRunCollection.Find(ctx, bson.M{"job": name}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	runs := []models.JobRun{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"dbserver/budget"
 	"dbserver/config"
 	"dbserver/db"
+	"dbserver/events"
 	login "dbserver/handlers"
 	"dbserver/handlers/middleware"
+	"dbserver/jobs"
+	"dbserver/notify"
+	"dbserver/scheduler"
+	"dbserver/storage"
+	"log"
 	"net/http"
 	"time"
 
@@ -22,13 +29,22 @@ type User struct {
 func Setup() *gin.Engine {
 	config.Init()
 	db.DBInit()
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Failed to open receipt image storage: %v", err)
+	}
+	events.Start()
+	notify.Configure(config.AppConfig.Notify)
+	budget.SetNotifier(notify.BudgetNotifier{})
+	notify.Start()
+	jobs.Register()
+	scheduler.Start()
 
 	r := gin.Default()
 
 	config := cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Last-Event-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -39,12 +55,147 @@ func Setup() *gin.Engine {
 	protected.Use(middleware.AuthMiddleware())
 	{
 		protected.GET("/records", login.SearchByUid)
+		protected.GET("/records/export", login.ExportRecords)
 		protected.GET("/records/:rid", login.GetRecordInfo)
 		protected.GET("/records/product/:pid", login.GetProductInfo)
+		protected.POST("/records", login.CreateRecord)
+		protected.PATCH("/records/:rid", login.PatchRecord)
+		protected.POST("/records/bulk", login.BulkRecords)
 
 		protected.PUT("/records/update/product", login.UpdateProduct)
 		protected.PUT("/records/update/mart", login.UpdateMart)
+		protected.PUT("/records/update/payment", login.UpdatePayment)
 		protected.PUT("/records/update/record", login.UpdateRecord)
+		protected.PUT("/records/update/category", login.UpdateProductCategory)
+		protected.PUT("/records/:rid/note", login.UpdateRecordNote)
+		protected.POST("/records/tags/add", login.AddRecordTags)
+		protected.POST("/records/tags/remove", login.RemoveRecordTags)
+
+		protected.GET("/records/:rid/image", login.GetRecordImage)
+		protected.GET("/records/:rid/history", login.GetRecordHistory)
+		protected.POST("/records/:rid/revert/:version", login.RevertRecord)
+
+		protected.GET("/records/duplicates", login.ListDuplicates)
+		protected.GET("/records/issues", login.ListRecordIssues)
+		protected.PUT("/records/:rid/issues/:issueId", login.ResolveRecordIssue)
+		protected.POST("/records/:rid/duplicate/dismiss", login.DismissDuplicate)
+		protected.POST("/records/:rid/duplicate/merge", login.MergeDuplicate)
+		protected.PUT("/records/:rid/group", login.MoveRecord)
+		protected.GET("/records/:rid/split", login.GetRecordSplit)
+		protected.PUT("/records/:rid/split", login.UpdateRecordSplit)
+		protected.DELETE("/records/:rid/split", login.DeleteRecordSplit)
+
+		protected.PUT("/users/timezone", login.UpdateTimeZone)
+		protected.PUT("/users/currency", login.UpdateHomeCurrency)
+		protected.PUT("/users/reports", login.UpdateReportSubscription)
+
+		protected.GET("/analytics/spending", login.GetSpending)
+		protected.GET("/analytics/summary", login.GetSpendingSummary)
+		protected.GET("/analytics/top-products", login.GetTopProducts)
+
+		protected.GET("/budgets", login.ListBudgets)
+		protected.POST("/budgets", login.CreateBudget)
+		protected.PUT("/budgets/:bid", login.UpdateBudget)
+		protected.DELETE("/budgets/:bid", login.DeleteBudget)
+		protected.GET("/alerts", login.ListAlerts)
+		protected.PUT("/alerts/:aid/read", login.MarkAlertRead)
+
+		protected.GET("/notifications", login.ListNotifications)
+		protected.PUT("/notifications/read", login.MarkAllNotificationsRead)
+		protected.PUT("/notifications/:nid/read", login.MarkNotificationRead)
+		protected.PUT("/notifications/:nid/unread", login.MarkNotificationUnread)
+		protected.GET("/notifications/preferences", login.GetNotificationPreferences)
+		protected.PUT("/notifications/preferences", login.UpdateNotificationPreferences)
+		protected.GET("/notifications/webpush/key", login.GetWebPushKey)
+		protected.POST("/notifications/webpush/subscriptions", login.SubscribeWebPush)
+		protected.DELETE("/notifications/webpush/subscriptions", login.UnsubscribeWebPush)
+		protected.POST("/notifications/devices", login.RegisterPushDevice)
+		protected.DELETE("/notifications/devices/:token", login.DeletePushDevice)
+
+		protected.GET("/products/:name/prices", login.GetProductPrices)
+
+		protected.GET("/reports/:month", login.GetMonthlyReport)
+
+		protected.GET("/categories", login.ListCategories)
+		protected.GET("/categories/rules", login.ListCategoryRules)
+		protected.POST("/categories/rules", login.CreateCategoryRule)
+		protected.DELETE("/categories/rules/:ruleId", login.DeleteCategoryRule)
+		protected.POST("/categories/recategorize", login.StartRecategorize)
+		protected.GET("/categories/jobs/:jobId", login.GetCategoryJob)
+
+		protected.GET("/tags", login.ListTags)
+		protected.POST("/tags", login.CreateTag)
+		protected.POST("/tags/merge", login.MergeTags)
+		protected.PUT("/tags/:name", login.RenameTag)
+		protected.DELETE("/tags/:name", login.DeleteTag)
+
+		protected.GET("/imports/presets", login.ListImportPresets)
+		protected.GET("/imports", login.ListImports)
+		protected.POST("/imports", login.CreateImport)
+		protected.GET("/imports/:batchId", login.GetImport)
+		protected.PUT("/imports/:batchId/mapping", login.UpdateImportMapping)
+		protected.POST("/imports/:batchId/commit", login.CommitImport)
+		protected.DELETE("/imports/:batchId", login.DeleteImport)
+
+		protected.GET("/households", login.ListHouseholds)
+		protected.POST("/households", login.CreateHousehold)
+		protected.GET("/households/:groupId", login.GetHousehold)
+		protected.PUT("/households/:groupId", login.UpdateHousehold)
+		protected.DELETE("/households/:groupId", login.DeleteHousehold)
+		protected.GET("/households/:groupId/invitations", login.ListInvitations)
+		protected.POST("/households/:groupId/invitations", login.CreateInvitation)
+		protected.DELETE("/households/:groupId/invitations/:inviteId", login.RevokeInvitation)
+		protected.PUT("/households/:groupId/members/:uid", login.UpdateMember)
+		protected.DELETE("/households/:groupId/members/:uid", login.RemoveMember)
+		protected.POST("/invitations/accept", login.AcceptInvitation)
+
+		protected.GET("/people", login.ListPeople)
+		protected.POST("/people", login.CreatePerson)
+		protected.DELETE("/people/:personId", login.DeletePerson)
+		protected.GET("/settlements", login.ListSettlements)
+		protected.POST("/settlements", login.CreateSettlement)
+		protected.GET("/settlements/balances", login.GetBalances)
+		protected.DELETE("/settlements/:settlementId", login.DeleteSettlement)
+
+		protected.GET("/currencies", login.GetCurrencies)
+		protected.GET("/exchange-rates", login.GetExchangeRates)
+		protected.GET("/exchange-rates/convert", login.ConvertAmount)
+
+		protected.GET("/recurring", login.GetRecurringPurchases)
+		protected.GET("/shopping-list", login.GetShoppingList)
+		protected.POST("/shopping-list", login.AddShoppingItem)
+		protected.DELETE("/shopping-list", login.ClearCheckedShoppingItems)
+		protected.POST("/shopping-list/suggestions", login.AddShoppingSuggestions)
+		protected.PUT("/shopping-list/:itemId", login.UpdateShoppingItem)
+		protected.DELETE("/shopping-list/:itemId", login.DeleteShoppingItem)
+
+		protected.GET("/marts", login.ListMarts)
+		protected.GET("/marts/:martId", login.GetMart)
+		protected.PUT("/marts/:martId", login.EditMart)
+		protected.POST("/marts/:martId/merge", login.MergeMarts)
+
+		protected.GET("/graphql", login.GraphQL)
+		protected.POST("/graphql", login.GraphQL)
+	}
+
+	// EventSource는 헤더를 못 붙여서 access_token 쿼리로도 인증함
+	stream := r.Group("/events")
+	stream.Use(middleware.QueryToken(), middleware.AuthMiddleware())
+	{
+		stream.GET("/records", login.StreamRecordEvents)
+	}
+
+	// 관리자 전용 API
+	admin := r.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+	{
+		admin.PUT("/exchange-rates", login.PutExchangeRates)
+		admin.POST("/exchange-rates/import", login.ImportExchangeRates)
+		admin.DELETE("/exchange-rates/:currency/:date", login.DeleteExchangeRate)
+
+		admin.GET("/jobs", login.ListJobs)
+		admin.POST("/jobs/:name/run", login.RunJob)
+		admin.GET("/jobs/:name/runs", login.ListJobRuns)
 	}
 
 	r.GET("/ping", func(c *gin.Context) {
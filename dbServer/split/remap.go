@@ -0,0 +1,60 @@
+package split
+
+import (
+	"dbserver/models"
+	"errors"
+)
+
+// ErrItemRemoved is returned by Remap when the split assigns a product the record no
+// longer has
+var ErrItemRemoved = errors.New("bill split assigns a product that was removed")
+
+// MatchProducts pairs the products of a record before an edit with those after it. A
+// product whose name is unchanged at its position stays there; otherwise it moves to
+// the first unpaired product of the same name. The result holds the new index of each
+// old product, or -1 when it was removed.
+func MatchProducts(old []models.DBProduct, next []models.DBProduct) []int {
+	moved := make([]int, len(old))
+	used := make([]bool, len(next))
+	for i := range old {
+		moved[i] = -1
+		if i < len(next) && next[i].Pname == old[i].Pname {
+			moved[i] = i
+			used[i] = true
+		}
+	}
+	for i := range old {
+		if moved[i] >= 0 {
+			continue
+		}
+		for j := range next {
+			if !used[j] && next[j].Pname == old[i].Pname {
+				moved[i] = j
+				used[j] = true
+				break
+			}
+		}
+	}
+	return moved
+}
+
+// Remap moves the line items of s from the products of a record before an edit to the
+// same products after it. It fails with ErrItemRemoved when an assigned product is
+// gone, since its shares can not be moved to another line.
+func Remap(s *models.RecordSplit, old []models.DBProduct, next []models.DBProduct) (*models.RecordSplit, error) {
+	if s == nil {
+		return nil, nil
+	}
+	moved := MatchProducts(old, next)
+
+	remapped := *s
+	remapped.Items = make([]models.ItemSplit, 0, len(s.Items))
+	for _, item := range s.Items {
+		if item.Index < 0 || item.Index >= len(moved) || moved[item.Index] < 0 {
+			return nil, ErrItemRemoved
+		}
+		item.Index = moved[item.Index]
+		remapped.Items = append(remapped.Items, item)
+	}
+	return &remapped, nil
+}
@@ -0,0 +1,76 @@
+package split
+
+import (
+	"dbserver/models"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func names(pnames ...string) []models.DBProduct {
+	products := make([]models.DBProduct, len(pnames))
+	for i, pname := range pnames {
+		products[i] = models.DBProduct{Pname: pname}
+	}
+	return products
+}
+
+func TestMatchProducts(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, next []models.DBProduct
+		want      []int
+	}{
+		{"unchanged", names("a", "b", "c"), names("a", "b", "c"), []int{0, 1, 2}},
+		{"reordered", names("a", "b", "c"), names("c", "a", "b"), []int{1, 2, 0}},
+		{"inserted at the front", names("a", "b"), names("x", "a", "b"), []int{1, 2}},
+		{"removed", names("a", "b", "c"), names("a", "c"), []int{0, -1, 1}},
+		{"renamed", names("a", "b"), names("a", "B"), []int{0, -1}},
+		{"same name twice", names("a", "b", "a"), names("b", "a", "a"), []int{1, 0, 2}},
+		{"duplicate removed", names("a", "a"), names("a"), []int{0, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchProducts(tt.old, tt.next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchProducts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemap(t *testing.T) {
+	s := &models.RecordSplit{
+		PaidBy: "a",
+		Items: []models.ItemSplit{
+			{Index: 0, Shares: []models.SplitShare{share("a", 1)}},
+			{Index: 2, Shares: []models.SplitShare{share("b", 1)}},
+		},
+		Default: []models.SplitShare{share("a", 1), share("b", 1)},
+	}
+	old := names("맥주", "안주", "생수")
+
+	remapped, err := Remap(s, old, names("생수", "맥주", "안주", "얼음"))
+	if err != nil {
+		t.Fatalf("Remap() error = %v", err)
+	}
+	if got := []int{remapped.Items[0].Index, remapped.Items[1].Index}; !reflect.DeepEqual(got, []int{1, 0}) {
+		t.Errorf("indexes = %v, want [1 0]", got)
+	}
+	if remapped.Items[1].Shares[0].PersonId != "b" || !reflect.DeepEqual(remapped.Default, s.Default) {
+		t.Errorf("shares changed: %+v", remapped)
+	}
+	if s.Items[0].Index != 0 || s.Items[1].Index != 2 {
+		t.Errorf("Remap() changed the original split: %+v", s.Items)
+	}
+
+	// 나누지 않은 품목은 지워도 됨
+	if _, err := Remap(s, old, names("맥주", "생수")); err != nil {
+		t.Errorf("Remap() error = %v after removing an unassigned product", err)
+	}
+	if _, err := Remap(s, old, names("안주", "생수")); !errors.Is(err, ErrItemRemoved) {
+		t.Errorf("Remap() error = %v, want ErrItemRemoved", err)
+	}
+	if remapped, err := Remap(nil, old, old); remapped != nil || err != nil {
+		t.Errorf("Remap(nil) = %v, %v", remapped, err)
+	}
+}
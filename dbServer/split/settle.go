@@ -0,0 +1,121 @@
+package split
+
+import (
+	"dbserver/currency"
+	"dbserver/models"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrCurrency is returned for a receipt that is not in the currency of the ledger
+var ErrCurrency = errors.New("receipt is not in the currency of the ledger")
+
+// Ledger accumulates balances over receipts and settlements in one currency
+type Ledger struct {
+	home     string
+	balances map[string]*models.Balance
+}
+
+// NewLedger returns an empty ledger kept in home. Receipts in other currencies must
+// be converted before they are added; settlements are taken to be in home.
+func NewLedger(home string) *Ledger {
+	return &Ledger{home: currency.Normalize(home), balances: map[string]*models.Balance{}}
+}
+
+func (l *Ledger) get(person string) *models.Balance {
+	b, ok := l.balances[person]
+	if !ok {
+		b = &models.Balance{PersonId: person}
+		l.balances[person] = b
+	}
+	return b
+}
+
+// AddRecord counts the receipt as paid by the payer and owed by everyone sharing it
+func (l *Ledger) AddRecord(record models.RecordInput) error {
+	if record.Split == nil {
+		return nil
+	}
+	if code := currency.Normalize(record.Currency); code != l.home {
+		return fmt.Errorf("%w: %s in %s", ErrCurrency, record.Record.Rid, code)
+	}
+	l.get(record.Split.PaidBy).Paid += record.TotalPrice
+	for person, amount := range Totals(record) {
+		l.get(person).Owed += amount
+	}
+	return nil
+}
+
+// AddSettlement counts a payment from one person to another
+func (l *Ledger) AddSettlement(s models.Settlement) {
+	l.get(s.From).Sent += s.Amount
+	l.get(s.To).Received += s.Amount
+}
+
+// Balances returns every person's balance, named with names and sorted by name
+func (l *Ledger) Balances(names map[string]string) []models.Balance {
+	balances := make([]models.Balance, 0, len(l.balances))
+	for _, b := range l.balances {
+		b.Name = names[b.PersonId]
+		b.Net = b.Paid - b.Owed + b.Sent - b.Received
+		balances = append(balances, *b)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Name != balances[j].Name {
+			return balances[i].Name < balances[j].Name
+		}
+		return balances[i].PersonId < balances[j].PersonId
+	})
+	return balances
+}
+
+// Simplify turns balances into a short list of payments that settles everyone. The
+// largest debtor always pays the largest creditor, which needs at most one payment
+// fewer than the number of people with a non-zero balance.
+func Simplify(balances []models.Balance) []models.Payment {
+	type position struct {
+		person string
+		name   string
+		amount int
+	}
+
+	var creditors, debtors []position
+	for _, b := range balances {
+		switch {
+		case b.Net > 0:
+			creditors = append(creditors, position{b.PersonId, b.Name, b.Net})
+		case b.Net < 0:
+			debtors = append(debtors, position{b.PersonId, b.Name, -b.Net})
+		}
+	}
+
+	payments := []models.Payment{}
+	for len(creditors) > 0 && len(debtors) > 0 {
+		sort.SliceStable(creditors, func(i, j int) bool { return creditors[i].amount > creditors[j].amount })
+		sort.SliceStable(debtors, func(i, j int) bool { return debtors[i].amount > debtors[j].amount })
+
+		c, d := &creditors[0], &debtors[0]
+		amount := c.amount
+		if d.amount < amount {
+			amount = d.amount
+		}
+		payments = append(payments, models.Payment{
+			From:     d.person,
+			FromName: d.name,
+			To:       c.person,
+			ToName:   c.name,
+			Amount:   amount,
+		})
+
+		c.amount -= amount
+		d.amount -= amount
+		if c.amount == 0 {
+			creditors = creditors[1:]
+		}
+		if d.amount == 0 {
+			debtors = debtors[1:]
+		}
+	}
+	return payments
+}
@@ -0,0 +1,138 @@
+package split
+
+import (
+	"dbserver/models"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Validate checks that a split only refers to existing line items and known people
+func Validate(s models.RecordSplit, products int, people map[string]bool) error {
+	if !people[s.PaidBy] {
+		return fmt.Errorf("unknown person %s", s.PaidBy)
+	}
+
+	seen := map[int]bool{}
+	for _, item := range s.Items {
+		if item.Index < 0 || item.Index >= products {
+			return fmt.Errorf("record has no line item %d", item.Index)
+		}
+		if seen[item.Index] {
+			return fmt.Errorf("line item %d is assigned twice", item.Index)
+		}
+		seen[item.Index] = true
+
+		if err := validateShares(item.Shares, people); err != nil {
+			return err
+		}
+	}
+	return validateShares(s.Default, people)
+}
+
+func validateShares(shares []models.SplitShare, people map[string]bool) error {
+	for _, share := range shares {
+		if !people[share.PersonId] {
+			return fmt.Errorf("unknown person %s", share.PersonId)
+		}
+	}
+	return nil
+}
+
+// Totals returns what each person owes for a receipt. Line items are divided by
+// weight and then scaled so the amounts add up to the record's total, which spreads
+// receipt-level discounts over the items in proportion to their price. Amounts are
+// rounded with the largest remainder method so they always sum to the total exactly.
+func Totals(record models.RecordInput) map[string]int {
+	s := record.Split
+	if s == nil {
+		return nil
+	}
+
+	payer := []models.SplitShare{{PersonId: s.PaidBy, Weight: 1}}
+	fallback := s.Default
+	if len(fallback) == 0 {
+		fallback = payer
+	}
+
+	assigned := map[int][]models.SplitShare{}
+	for _, item := range s.Items {
+		assigned[item.Index] = item.Shares
+	}
+
+	raw := map[string]float64{}
+	lines := 0
+	for i, p := range record.Product {
+		shares, ok := assigned[i]
+		if !ok {
+			shares = fallback
+		}
+		line := p.LineTotal()
+		lines += line
+		divide(raw, float64(line), shares)
+	}
+
+	if lines == 0 {
+		// 품목 금액이 없으면 합계를 기본 분담 비율로 나눔
+		raw = map[string]float64{}
+		divide(raw, float64(record.TotalPrice), fallback)
+	} else if lines != record.TotalPrice {
+		scale := float64(record.TotalPrice) / float64(lines)
+		for person := range raw {
+			raw[person] *= scale
+		}
+	}
+
+	return round(raw, record.TotalPrice)
+}
+
+func divide(raw map[string]float64, amount float64, shares []models.SplitShare) {
+	weights := 0
+	for _, share := range shares {
+		weights += weight(share)
+	}
+	if weights == 0 {
+		return
+	}
+	for _, share := range shares {
+		raw[share.PersonId] += amount * float64(weight(share)) / float64(weights)
+	}
+}
+
+// weight treats a missing weight as an equal share
+func weight(share models.SplitShare) int {
+	if share.Weight <= 0 {
+		return 1
+	}
+	return share.Weight
+}
+
+func round(raw map[string]float64, total int) map[string]int {
+	type part struct {
+		person    string
+		remainder float64
+	}
+
+	amounts := map[string]int{}
+	parts := make([]part, 0, len(raw))
+	sum := 0
+	for person, value := range raw {
+		floor := math.Floor(value)
+		amounts[person] = int(floor)
+		sum += int(floor)
+		parts = append(parts, part{person, value - floor})
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		if parts[i].remainder != parts[j].remainder {
+			return parts[i].remainder > parts[j].remainder
+		}
+		return parts[i].person < parts[j].person
+	})
+	for i := 0; sum < total && len(parts) > 0; i = (i + 1) % len(parts) {
+		amounts[parts[i].person]++
+		sum++
+	}
+
+	return amounts
+}
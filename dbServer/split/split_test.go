@@ -0,0 +1,243 @@
+package split
+
+import (
+	"dbserver/currency"
+	"dbserver/models"
+	"errors"
+	"testing"
+)
+
+func share(person string, weight int) models.SplitShare {
+	return models.SplitShare{PersonId: person, Weight: weight}
+}
+
+func product(name string, price, amount, discount int) models.DBProduct {
+	return models.DBProduct{Pname: name, Price: price, Amount: amount, Discount: discount}
+}
+
+func TestTotals(t *testing.T) {
+	tests := []struct {
+		name   string
+		record models.RecordInput
+		want   map[string]int
+	}{
+		{
+			name: "three ways with a remainder",
+			record: models.RecordInput{
+				Product:    []models.DBProduct{product("피자", 10000, 1, 0)},
+				TotalPrice: 10000,
+				Split: &models.RecordSplit{
+					PaidBy:  "a",
+					Default: []models.SplitShare{share("a", 1), share("b", 1), share("c", 1)},
+				},
+			},
+			want: map[string]int{"a": 3334, "b": 3333, "c": 3333},
+		},
+		{
+			name: "items assigned by weight",
+			record: models.RecordInput{
+				Product: []models.DBProduct{
+					product("맥주", 3000, 2, 0),
+					product("안주", 9000, 1, 0),
+				},
+				TotalPrice: 15000,
+				Split: &models.RecordSplit{
+					PaidBy: "a",
+					Items: []models.ItemSplit{
+						{Index: 0, Shares: []models.SplitShare{share("b", 1)}},
+						{Index: 1, Shares: []models.SplitShare{share("a", 2), share("b", 1)}},
+					},
+				},
+			},
+			want: map[string]int{"a": 6000, "b": 9000},
+		},
+		{
+			name: "unassigned items fall back to the payer",
+			record: models.RecordInput{
+				Product: []models.DBProduct{
+					product("우유", 2500, 1, 0),
+					product("빵", 4000, 1, 0),
+				},
+				TotalPrice: 6500,
+				Split: &models.RecordSplit{
+					PaidBy: "a",
+					Items:  []models.ItemSplit{{Index: 1, Shares: []models.SplitShare{share("b", 1)}}},
+				},
+			},
+			want: map[string]int{"a": 2500, "b": 4000},
+		},
+		{
+			name: "receipt discount spread by price",
+			record: models.RecordInput{
+				Product: []models.DBProduct{
+					product("과자", 1000, 1, 0),
+					product("라면", 2000, 1, 0),
+				},
+				TotalPrice: 2000,
+				Split: &models.RecordSplit{
+					PaidBy: "a",
+					Items: []models.ItemSplit{
+						{Index: 0, Shares: []models.SplitShare{share("a", 1)}},
+						{Index: 1, Shares: []models.SplitShare{share("b", 1)}},
+					},
+				},
+			},
+			want: map[string]int{"a": 667, "b": 1333},
+		},
+		{
+			name: "no line prices",
+			record: models.RecordInput{
+				TotalPrice: 100,
+				Split: &models.RecordSplit{
+					PaidBy:  "a",
+					Default: []models.SplitShare{share("a", 0), share("b", 0), share("c", 0)},
+				},
+			},
+			want: map[string]int{"a": 34, "b": 33, "c": 33},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Totals(tt.record)
+			sum := 0
+			for _, amount := range got {
+				sum += amount
+			}
+			if sum != tt.record.TotalPrice {
+				t.Errorf("shares add up to %d, want %d", sum, tt.record.TotalPrice)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Totals() = %v, want %v", got, tt.want)
+			}
+			for person, amount := range tt.want {
+				if got[person] != amount {
+					t.Errorf("Totals() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestTotalsAddUp(t *testing.T) {
+	people := []models.SplitShare{share("a", 1), share("b", 2), share("c", 3), share("d", 0), share("e", 7)}
+	for total := 1; total <= 2000; total += 37 {
+		for n := 1; n <= len(people); n++ {
+			record := models.RecordInput{
+				Product: []models.DBProduct{
+					product("가", 333, 3, 10),
+					product("나", 1001, 1, 0),
+					product("다", 7, 11, 0),
+				},
+				TotalPrice: total,
+				Split: &models.RecordSplit{
+					PaidBy:  "a",
+					Items:   []models.ItemSplit{{Index: 1, Shares: people[:n]}},
+					Default: people[len(people)-n:],
+				},
+			}
+			sum := 0
+			for _, amount := range Totals(record) {
+				sum += amount
+			}
+			if sum != total {
+				t.Fatalf("total %d split %d ways: shares add up to %d", total, n, sum)
+			}
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	ledger := NewLedger(currency.Default)
+	records := []models.RecordInput{
+		{
+			Product:    []models.DBProduct{product("장보기", 30000, 1, 0)},
+			TotalPrice: 30000,
+			Split: &models.RecordSplit{
+				PaidBy:  "a",
+				Default: []models.SplitShare{share("a", 1), share("b", 1), share("c", 1)},
+			},
+		},
+		{
+			Product:    []models.DBProduct{product("택시", 10001, 1, 0)},
+			TotalPrice: 10001,
+			Split: &models.RecordSplit{
+				PaidBy:  "b",
+				Default: []models.SplitShare{share("b", 1), share("c", 1), share("d", 1)},
+			},
+		},
+		{
+			Product:    []models.DBProduct{product("커피", 4500, 4, 0)},
+			TotalPrice: 18000,
+			Split: &models.RecordSplit{
+				PaidBy: "d",
+				Items:  []models.ItemSplit{{Index: 0, Shares: []models.SplitShare{share("a", 1), share("c", 3)}}},
+			},
+		},
+	}
+	for _, record := range records {
+		if err := ledger.AddRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ledger.AddSettlement(models.Settlement{From: "c", To: "a", Amount: 5000})
+
+	balances := ledger.Balances(map[string]string{"a": "A", "b": "B", "c": "C", "d": "D"})
+	net := map[string]int{}
+	sum, open := 0, 0
+	for _, b := range balances {
+		net[b.PersonId] = b.Net
+		sum += b.Net
+		if b.Net != 0 {
+			open++
+		}
+	}
+	if sum != 0 {
+		t.Fatalf("balances add up to %d, want 0", sum)
+	}
+
+	payments := Simplify(balances)
+	if len(payments) >= open {
+		t.Errorf("got %d payments for %d open balances", len(payments), open)
+	}
+	for _, p := range payments {
+		if p.Amount <= 0 {
+			t.Errorf("payment %+v is not positive", p)
+		}
+		net[p.From] += p.Amount
+		net[p.To] -= p.Amount
+	}
+	for person, amount := range net {
+		if amount != 0 {
+			t.Errorf("%s is left with %d after the payments", person, amount)
+		}
+	}
+}
+
+func TestLedgerRejectsOtherCurrencies(t *testing.T) {
+	ledger := NewLedger("krw")
+	record := models.RecordInput{
+		Record:     models.DBRecord{Rid: "r1"},
+		Currency:   "USD",
+		TotalPrice: 1250,
+		Split:      &models.RecordSplit{PaidBy: "a", Default: []models.SplitShare{share("b", 1)}},
+	}
+	if err := ledger.AddRecord(record); !errors.Is(err, ErrCurrency) {
+		t.Fatalf("AddRecord() = %v, want ErrCurrency", err)
+	}
+	if balances := ledger.Balances(nil); len(balances) != 0 {
+		t.Errorf("rejected receipt left balances %v", balances)
+	}
+
+	record.Currency = ""
+	if err := ledger.AddRecord(record); err != nil {
+		t.Fatalf("AddRecord() = %v for a receipt in the default currency", err)
+	}
+}
+
+func TestSimplifySettled(t *testing.T) {
+	balances := []models.Balance{{PersonId: "a"}, {PersonId: "b"}}
+	if payments := Simplify(balances); len(payments) != 0 {
+		t.Errorf("Simplify() = %v, want no payments", payments)
+	}
+}
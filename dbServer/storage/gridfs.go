@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BucketName is the GridFS bucket of receipt images
+const BucketName = "ReceiptImage"
+
+// GridFS keeps images in the database, so both servers see them without a shared disk
+type GridFS struct {
+	database *mongo.Database
+}
+
+func NewGridFS(database *mongo.Database) *GridFS {
+	return &GridFS{database: database}
+}
+
+// bucket opens the bucket for one call. The driver takes deadlines per bucket
+// instead of a context, so buckets are not shared between requests.
+func (g *GridFS) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(g.database, options.GridFSBucket().SetName(BucketName))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		bucket.SetReadDeadline(deadline)
+		bucket.SetWriteDeadline(deadline)
+	}
+	return bucket, nil
+}
+
+func (g *GridFS) Put(ctx context.Context, key string, data []byte) error {
+	bucket, err := g.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	// 같은 키로 다시 저장하면 덮어씀
+	if err := bucket.DeleteContext(ctx, key); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return bucket.UploadFromStreamWithID(key, key, bytes.NewReader(data))
+}
+
+func (g *GridFS) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	bucket, err := g.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetReadDeadline(deadline)
+	}
+	return stream, nil
+}
+
+func (g *GridFS) Delete(ctx context.Context, key string) error {
+	bucket, err := g.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	if err := bucket.DeleteContext(ctx, key); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return nil
+}
@@ -7,9 +7,16 @@ import (
 var (
 	Client     *mongo.Client
 	Collection *mongo.Collection
+
+	DeviceCollection       *mongo.Collection
+	NotificationCollection *mongo.Collection
 )
 
 func DBInit() {
 	Client, _, _ = ConnectDB()
 	Collection = SelectTable(Client)
+	DeviceCollection = SelectCollection(Client, "LoginDevice")
+	NotificationCollection = SelectCollection(Client, "Notification")
+
+	EnsureIndexes()
 }
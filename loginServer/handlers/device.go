@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"loginserver/db"
+	"loginserver/models"
+	"loginserver/notify"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxUserAgent is how much of the user agent is kept and shown in the notification
+const maxUserAgent = 200
+
+// recordDevice remembers the device of a sign-in and tells the user when it is new.
+// The first device of an account is not reported. Failures are only logged so they
+// never block the sign-in.
+func recordDevice(c *gin.Context, uid string) {
+	if uid == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	sum := sha256.Sum256([]byte(userAgent))
+	device := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	result, err := db.DeviceCollection.UpdateOne(ctx,
+		bson.M{"uid": uid, "device": device},
+		bson.M{
+			"$set":         bson.M{"lastIp": c.ClientIP(), "lastSeen": now},
+			"$setOnInsert": bson.M{"userAgent": userAgent, "firstSeen": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Failed to record device: %v\n", err)
+		return
+	}
+	if result.UpsertedCount == 0 {
+		return
+	}
+
+	known, err := db.DeviceCollection.CountDocuments(ctx, bson.M{"uid": uid, "device": bson.M{"$ne": device}})
+	if err != nil {
+		log.Printf("Failed to count devices: %v\n", err)
+		return
+	}
+	if known == 0 {
+		return
+	}
+
+	if userAgent == "" {
+		userAgent = "unknown"
+	}
+	err = notify.Send(ctx, uid, models.NotificationNewDeviceLogin, map[string]string{
+		"device": userAgent,
+		"ip":     c.ClientIP(),
+		"at":     now.Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Failed to send notification: %v\n", err)
+	}
+}
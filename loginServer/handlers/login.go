@@ -75,6 +75,7 @@ func GoogleTokenVerifyHandler(c *gin.Context) {
 		Email:    existingUser.Email,
 		Nickname: existingUser.Nickname,
 	}
+	recordDevice(c, user.Uid)
 
 	c, err = jwt.SetAccount(c, &user)
 	if err != nil {
@@ -247,6 +248,7 @@ func HandleSignup(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
+	recordDevice(c, newUser.Uid)
 
 	c, err = jwt.SetAccount(c, &newUser)
 	if err != nil {
@@ -313,6 +315,7 @@ func HandleLogin(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
+	recordDevice(c, existingUser.Uid)
 
 	c, err = jwt.SetAccount(c, &existingUser)
 	if err != nil {
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// 알림 종류, dbServer와 같음
+const (
+	NotificationNewDeviceLogin = "login.new-device"
+
+	NotificationPending = "pending"
+)
+
+// Notification is queued here and delivered by dbServer, which renders it in the
+// user's language and sends it on the channels the user chose
+type Notification struct {
+	Nid          string            `bson:"nid"`
+	Uid          string            `bson:"uid"`
+	Kind         string            `bson:"kind"`
+	Data         map[string]string `bson:"data,omitempty"`
+	Inbox        bool              `bson:"inbox"`
+	Status       string            `bson:"status"`
+	Attempts     int               `bson:"attempts"`
+	DeliverAfter time.Time         `bson:"deliverAfter"`
+	CreatedAt    time.Time         `bson:"createdAt"`
+}
+
+// LoginDevice is a browser or app the user signed in from, told apart by its user agent
+type LoginDevice struct {
+	Uid       string    `bson:"uid"`
+	Device    string    `bson:"device"`
+	UserAgent string    `bson:"userAgent"`
+	LastIp    string    `bson:"lastIp"`
+	FirstSeen time.Time `bson:"firstSeen"`
+	LastSeen  time.Time `bson:"lastSeen"`
+}
@@ -0,0 +1,26 @@
+// Package notify queues notifications for dbServer to deliver
+package notify
+
+import (
+	"context"
+	"loginserver/db"
+	"loginserver/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Send queues a notification of kind for uid. Data fills the template of the kind.
+func Send(ctx context.Context, uid string, kind string, data map[string]string) error {
+	now := time.Now()
+	_, err := db.NotificationCollection.InsertOne(ctx, models.Notification{
+		Nid:          uuid.NewString(),
+		Uid:          uid,
+		Kind:         kind,
+		Data:         data,
+		Status:       models.NotificationPending,
+		DeliverAfter: now,
+		CreatedAt:    now,
+	})
+	return err
+}
@@ -0,0 +1,189 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	"ocrserver/db"
+	"ocrserver/models"
+	"ocrserver/normalize"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type compiledRule struct {
+	rule    models.CategoryRule
+	keyword string
+	regex   *regexp.Regexp
+}
+
+// Categorizer assigns categories to product names using, in order, the rules learned
+// from the user's corrections, the user's keyword and regex rules, and the built-in
+// dictionary.
+type Categorizer struct {
+	learned map[string]string
+	rules   []compiledRule
+}
+
+// New builds a categorizer from rules; invalid regex rules are skipped
+func New(rules []models.CategoryRule) *Categorizer {
+	c := &Categorizer{learned: map[string]string{}}
+	for _, r := range rules {
+		switch r.Type {
+		case models.CategoryRuleExact:
+			c.learned[normalize.ProductName(r.Pattern)] = r.Category
+		case models.CategoryRuleKeyword:
+			c.rules = append(c.rules, compiledRule{rule: r, keyword: normalize.ProductName(r.Pattern)})
+		case models.CategoryRuleRegex:
+			re, err := CompileRegex(r.Pattern)
+			if err != nil {
+				continue
+			}
+			c.rules = append(c.rules, compiledRule{rule: r, regex: re})
+		}
+	}
+
+	// 우선순위가 높은 규칙, 같으면 먼저 만든 규칙부터 적용
+	sort.SliceStable(c.rules, func(i, j int) bool {
+		return c.rules[i].rule.Priority > c.rules[j].rule.Priority
+	})
+	return c
+}
+
+// Load builds the categorizer of a user from the stored rules
+func Load(ctx context.Context, uid string) (*Categorizer, error) {
+	rules, err := Rules(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return New(rules), nil
+}
+
+// Rules returns the stored rules of a user, oldest first
+func Rules(ctx context.Context, uid string) ([]models.CategoryRule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := db.CategoryRuleCollection.Find(ctx, bson.M{"uid": uid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.CategoryRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CompileRegex compiles a user regex rule, matching case-insensitively
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %v", err)
+	}
+	return re, nil
+}
+
+// Categorize returns the category of a product name and how it was decided.
+// An empty category means nothing matched.
+func (c *Categorizer) Categorize(pname string) (string, string) {
+	name := normalize.ProductName(pname)
+	if name == "" {
+		return "", ""
+	}
+
+	if category, ok := c.learned[name]; ok {
+		return category, models.CategorySourceLearned
+	}
+
+	for _, r := range c.rules {
+		if r.regex != nil {
+			if r.regex.MatchString(pname) {
+				return r.rule.Category, models.CategorySourceRule
+			}
+			continue
+		}
+		if r.keyword != "" && strings.Contains(name, r.keyword) {
+			return r.rule.Category, models.CategorySourceRule
+		}
+	}
+
+	if category := lookupDictionary(name); category != "" {
+		return category, models.CategorySourceDictionary
+	}
+	return "", ""
+}
+
+// Apply categorizes every product that the user has not categorized by hand.
+// It reports whether any product changed.
+func (c *Categorizer) Apply(products []models.DBProduct) bool {
+	changed := false
+	for i := range products {
+		p := &products[i]
+		if p.CategorySource == models.CategorySourceManual {
+			continue
+		}
+
+		category, source := c.Categorize(p.Pname)
+		if p.Category != category || p.CategorySource != source {
+			p.Category = category
+			p.CategorySource = source
+			changed = true
+		}
+	}
+	return changed
+}
+
+// lookupDictionary returns the category of the longest keyword found in name.
+// On a tie the keyword ending later wins, since Korean item names end with the
+// head noun ("초코우유" is milk, not a snack).
+func lookupDictionary(name string) string {
+	bare := strings.TrimRightFunc(name, func(r rune) bool {
+		return unicode.IsDigit(r) || (r < unicode.MaxASCII && unicode.IsLetter(r))
+	})
+
+	best, bestLen, bestEnd := "", 0, -1
+	for category, keywords := range dictionary {
+		for _, keyword := range keywords {
+			length := utf8.RuneCountInString(keyword)
+			end := -1
+			if length == 1 {
+				if bare == keyword || strings.HasPrefix(name, keyword) && trailingUnit(name[len(keyword):]) {
+					end = len(keyword)
+				}
+			} else if i := strings.LastIndex(name, keyword); i >= 0 {
+				end = i + len(keyword)
+			}
+			if end < 0 {
+				continue
+			}
+
+			if length > bestLen || (length == bestLen && end > bestEnd) ||
+				(length == bestLen && end == bestEnd && category < best) {
+				best, bestLen, bestEnd = category, length, end
+			}
+		}
+	}
+	return best
+}
+
+// trailingUnit reports whether s is a quantity such as "3입", "1kg" or "2개"
+func trailingUnit(s string) bool {
+	if s == "" {
+		return true
+	}
+	if !unicode.IsDigit([]rune(s)[0]) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) && !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return utf8.RuneCountInString(strings.TrimLeftFunc(s, unicode.IsDigit)) <= 2
+}
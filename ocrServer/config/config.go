@@ -1,8 +1,26 @@
 package config
 
+import (
+	"os"
+	"strings"
+)
+
 type Config struct {
 	MongoDB MongoConfig
 	OCR     OCRConfig
+	Storage StorageConfig
+}
+
+const (
+	StorageGridFS = "gridfs"
+	StorageLocal  = "local"
+)
+
+// StorageConfig selects where receipt images are kept: RECEIPT_STORAGE is "gridfs"
+// (default) or "local", and RECEIPT_IMAGE_DIR is the directory of the local store.
+type StorageConfig struct {
+	Backend string
+	Dir     string
 }
 
 type MongoConfig struct {
@@ -37,6 +55,20 @@ func InitOCR() OCRConfig {
 	return ocrConfig
 }
 
+func InitStorage() StorageConfig {
+	storage := StorageConfig{
+		Backend: strings.ToLower(strings.TrimSpace(os.Getenv("RECEIPT_STORAGE"))),
+		Dir:     strings.TrimSpace(os.Getenv("RECEIPT_IMAGE_DIR")),
+	}
+	if storage.Backend == "" {
+		storage.Backend = StorageGridFS
+	}
+	if storage.Dir == "" {
+		storage.Dir = "receipts"
+	}
+	return storage
+}
+
 func Init() {
 	MongoConfig := InitDB()
 	ocr := InitOCR()
@@ -44,6 +76,7 @@ func Init() {
 	AppConfig = &Config{
 		MongoDB: MongoConfig,
 		OCR:     ocr,
+		Storage: InitStorage(),
 	}
 
 }
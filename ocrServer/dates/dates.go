@@ -0,0 +1,169 @@
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// DefaultTimeZone is used when a user has not chosen a timezone
+const DefaultTimeZone = "Asia/Seoul"
+
+const DateLayout = "2006-01-02"
+
+// 영수증에 찍히는 날짜와 시간 형식
+var (
+	datePattern = regexp.MustCompile(`^(\d{2}|\d{4})\s*[-./년]\s*(\d{1,2})\s*[-./월]\s*(\d{1,2})\s*일?`)
+	compactDate = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})`)
+	timePattern = regexp.MustCompile(`^(오전|오후|AM|PM|am|pm)?\s*(\d{1,2})\s*[:시]\s*(\d{1,2})\s*분?(?:\s*[:]\s*(\d{1,2})\s*초?)?\s*(AM|PM|am|pm)?$`)
+)
+
+// LoadLocation returns the named timezone, falling back to DefaultTimeZone
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		name = DefaultTimeZone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc, _ = time.LoadLocation(DefaultTimeZone)
+	}
+	return loc
+}
+
+// ValidTimeZone reports whether name is a known IANA timezone
+func ValidTimeZone(name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Parse reads a purchase date with an optional time of day in the formats printed on
+// receipts, e.g. "2024-11-30", "24.11.30 14:03", "2024/11/30 오후 2:03:11",
+// "2024년 11월 30일" or RFC 3339. hasTime reports whether a time of day was present.
+func Parse(value string, loc *time.Location) (t time.Time, hasTime bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("empty date")
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), true, nil
+	}
+
+	var year, month, day, rest string
+	if m := datePattern.FindStringSubmatch(value); m != nil {
+		year, month, day = m[1], m[2], m[3]
+		rest = value[len(m[0]):]
+	} else if m := compactDate.FindStringSubmatch(value); m != nil {
+		year, month, day = m[1], m[2], m[3]
+		rest = value[len(m[0]):]
+	} else {
+		return time.Time{}, false, fmt.Errorf("unrecognized date %q", value)
+	}
+
+	// 날짜와 시각 사이의 공백, T, 괄호 속 요일을 건너뜀
+	rest = strings.TrimLeft(strings.TrimSpace(rest), "T(")
+	rest = strings.TrimSpace(stripWeekday(rest))
+
+	hour, minute, second := "", "", ""
+	if rest != "" {
+		m := timePattern.FindStringSubmatch(rest)
+		if m == nil {
+			return time.Time{}, false, fmt.Errorf("unrecognized time %q", rest)
+		}
+
+		meridiem := m[1]
+		if meridiem == "" {
+			meridiem = m[5]
+		}
+		h, _ := strconv.Atoi(m[2])
+		switch strings.ToUpper(meridiem) {
+		case "오후", "PM":
+			if h < 12 {
+				h += 12
+			}
+		case "오전", "AM":
+			if h == 12 {
+				h = 0
+			}
+		}
+		hour, minute, second = strconv.Itoa(h), m[3], m[4]
+	}
+
+	return FromParts(year, month, day, hour, minute, second, loc)
+}
+
+// FromParts builds a purchase time from the separate fields read by OCR.
+// Two-digit years are read as 20xx. An empty hour means the time of day is unknown.
+func FromParts(year, month, day, hour, minute, second string, loc *time.Location) (time.Time, bool, error) {
+	y, err := strconv.Atoi(strings.TrimSpace(year))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid year %q", year)
+	}
+	if y < 100 {
+		y += 2000
+	}
+
+	mo, err := strconv.Atoi(strings.TrimSpace(month))
+	if err != nil || mo < 1 || mo > 12 {
+		return time.Time{}, false, fmt.Errorf("invalid month %q", month)
+	}
+
+	d, err := strconv.Atoi(strings.TrimSpace(day))
+	if err != nil || d < 1 || d > daysIn(time.Month(mo), y) {
+		return time.Time{}, false, fmt.Errorf("invalid day %q", day)
+	}
+
+	if strings.TrimSpace(hour) == "" {
+		return time.Date(y, time.Month(mo), d, 0, 0, 0, 0, loc), false, nil
+	}
+
+	h, err := strconv.Atoi(strings.TrimSpace(hour))
+	if err != nil || h < 0 || h > 23 {
+		return time.Time{}, false, fmt.Errorf("invalid hour %q", hour)
+	}
+
+	mi := 0
+	if strings.TrimSpace(minute) != "" {
+		mi, err = strconv.Atoi(strings.TrimSpace(minute))
+		if err != nil || mi < 0 || mi > 59 {
+			return time.Time{}, false, fmt.Errorf("invalid minute %q", minute)
+		}
+	}
+
+	s := 0
+	if strings.TrimSpace(second) != "" {
+		s, err = strconv.Atoi(strings.TrimSpace(second))
+		if err != nil || s < 0 || s > 59 {
+			return time.Time{}, false, fmt.Errorf("invalid second %q", second)
+		}
+	}
+
+	return time.Date(y, time.Month(mo), d, h, mi, s, 0, loc), true, nil
+}
+
+// StartOfDay returns midnight of the day containing t in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// 날짜 뒤에 붙는 요일 표기 "(토)" 등을 제거
+func stripWeekday(s string) string {
+	for _, w := range []string{"월)", "화)", "수)", "목)", "금)", "토)", "일)"} {
+		if strings.HasPrefix(s, w) {
+			return s[len(w):]
+		}
+	}
+	return s
+}
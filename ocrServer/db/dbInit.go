@@ -5,11 +5,36 @@ import (
 )
 
 var (
-	Client     *mongo.Client
-	Collection *mongo.Collection
+	Client            *mongo.Client
+	Collection        *mongo.Collection
+	HistoryCollection *mongo.Collection
+	BudgetCollection  *mongo.Collection
+	AlertCollection   *mongo.Collection
+
+	CategoryRuleCollection *mongo.Collection
+	HouseholdCollection    *mongo.Collection
+	RateCollection         *mongo.Collection
+	ShoppingCollection     *mongo.Collection
+	MartCollection         *mongo.Collection
+
+	EventCollection   *mongo.Collection
+	CounterCollection *mongo.Collection
+
+	NotificationCollection *mongo.Collection
 )
 
 func DBInit() {
 	Client, _, _ = ConnectDB()
 	Collection = SelectTable(Client)
+	HistoryCollection = SelectCollection(Client, "RecordHistory")
+	BudgetCollection = SelectCollection(Client, "Budget")
+	AlertCollection = SelectCollection(Client, "BudgetAlert")
+	CategoryRuleCollection = SelectCollection(Client, "CategoryRule")
+	HouseholdCollection = SelectCollection(Client, "Household")
+	RateCollection = SelectCollection(Client, "ExchangeRate")
+	ShoppingCollection = SelectCollection(Client, "ShoppingList")
+	MartCollection = SelectCollection(Client, "Mart")
+	EventCollection = SelectCollection(Client, "RecordEvent")
+	CounterCollection = SelectCollection(Client, "Counter")
+	NotificationCollection = SelectCollection(Client, "Notification")
 }
@@ -0,0 +1,66 @@
+// Package events stores record change events. dbServer reads them from the event
+// collection and pushes them to the clients listening for changes.
+package events
+
+import (
+	"context"
+	"ocrserver/db"
+	"ocrserver/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// counterId is the Counter document numbering the events, shared with dbServer
+const counterId = "recordEvent"
+
+// New builds an event about record
+func New(eventType string, actor string, record models.RecordInput) models.RecordEvent {
+	return models.RecordEvent{
+		Type:    eventType,
+		Rid:     record.Record.Rid,
+		Uid:     record.Uid,
+		GroupId: record.GroupId,
+		Actor:   actor,
+	}
+}
+
+// Publish numbers and stores events
+func Publish(ctx context.Context, events ...models.RecordEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	last, err := nextSeq(ctx, len(events))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		events[i].Seq = last - int64(len(events)-1-i)
+		if events[i].At.IsZero() {
+			events[i].At = now
+		}
+		docs[i] = events[i]
+	}
+	_, err = db.EventCollection.InsertMany(ctx, docs)
+	return err
+}
+
+// nextSeq reserves n sequence numbers and returns the last one
+func nextSeq(ctx context.Context, n int) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := db.CounterCollection.FindOneAndUpdate(ctx, bson.M{"_id": counterId}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(&counter)
+	// 처음 두 요청이 동시에 카운터를 만들면 한쪽은 키 중복으로 실패하므로 다시 시도
+	if mongo.IsDuplicateKeyError(err) {
+		err = db.CounterCollection.FindOneAndUpdate(ctx, bson.M{"_id": counterId}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(&counter)
+	}
+	return counter.Seq, err
+}
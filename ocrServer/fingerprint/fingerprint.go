@@ -0,0 +1,146 @@
+package fingerprint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"ocrserver/dates"
+	"ocrserver/db"
+	"ocrserver/models"
+	"ocrserver/normalize"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Of returns the fingerprint of a record: the business number (or mart name), the
+// purchase date and minute, the total and the line items. Two photos of the same
+// receipt produce the same fingerprint.
+func Of(record models.RecordInput) string {
+	items := make([]string, 0, len(record.Product))
+	for _, p := range record.Product {
+		name := p.NormName
+		if name == "" {
+			name = normalize.ProductName(p.Pname)
+		}
+		items = append(items, fmt.Sprintf("%s*%d@%d", name, p.Amount, p.Price))
+	}
+	sort.Strings(items)
+
+	parts := []string{
+		Merchant(record.Mart),
+		purchaseKey(record.Record.TimeStamp),
+		fmt.Sprint(record.TotalPrice),
+		strings.Join(items, ","),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Merchant identifies the store of a receipt, preferring the business number
+func Merchant(mart models.DBMart) string {
+	if digits := onlyDigits(mart.BizNum); digits != "" {
+		return "biz:" + digits
+	}
+	return "name:" + normalize.ProductName(mart.MartName)
+}
+
+// SameMerchant compares business numbers when both receipts have one, otherwise the
+// normalized mart names
+func SameMerchant(a, b models.DBMart) bool {
+	bizA, bizB := onlyDigits(a.BizNum), onlyDigits(b.BizNum)
+	if bizA != "" && bizB != "" {
+		return bizA == bizB
+	}
+	return normalize.ProductName(a.MartName) == normalize.ProductName(b.MartName)
+}
+
+func purchaseKey(t models.PurchaseTime) string {
+	if t.At.IsZero() {
+		return ""
+	}
+	if !t.HasTime {
+		return t.Date()
+	}
+	return t.Local().Format("2006-01-02T15:04")
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// FindDuplicate looks for another record in the same ledger that is likely the same
+// receipt as record. A record with the same fingerprint is an exact duplicate; one
+// from the same store on the same day with the same total is a likely duplicate.
+// It returns nil if there is none.
+func FindDuplicate(ctx context.Context, record models.RecordInput) (*models.RecordInput, bool, error) {
+	// 가구 레코드는 같은 가구 안에서, 개인 레코드는 개인 장부 안에서 비교
+	base := bson.M{
+		"uid":        record.Uid,
+		"groupId":    bson.M{"$exists": false},
+		"record.rid": bson.M{"$exists": true, "$ne": record.Record.Rid},
+	}
+	if record.GroupId != "" {
+		delete(base, "uid")
+		base["groupId"] = record.GroupId
+	}
+
+	if fp := record.Record.Fingerprint; fp != "" {
+		filter := bson.M{"record.fingerprint": fp}
+		for k, v := range base {
+			filter[k] = v
+		}
+
+		var existing models.RecordInput
+		opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: 1}})
+		err := db.Collection.FindOne(ctx, filter, opts).Decode(&existing)
+		if err == nil {
+			return &existing, true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+	}
+
+	t := record.Record.TimeStamp
+	if t.At.IsZero() {
+		return nil, false, nil
+	}
+	start := dates.StartOfDay(t.At, dates.LoadLocation(t.TimeZone))
+
+	filter := bson.M{
+		"record.timeStamp.at": bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 1)},
+		"totalPrice":          record.TotalPrice,
+	}
+	for k, v := range base {
+		filter[k] = v
+	}
+
+	cursor, err := db.Collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var existing models.RecordInput
+		if err := cursor.Decode(&existing); err != nil {
+			return nil, false, err
+		}
+		if SameMerchant(existing.Mart, record.Mart) {
+			return &existing, false, nil
+		}
+	}
+	return nil, false, cursor.Err()
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"ocrserver/db"
+	"ocrserver/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// canAddToHousehold reports whether the user is an owner or editor of the household
+func canAddToHousehold(ctx context.Context, uid string, groupId string) (bool, error) {
+	count, err := db.HouseholdCollection.CountDocuments(ctx, bson.M{
+		"groupId": groupId,
+		"members": bson.M{"$elemMatch": bson.M{
+			"uid":  uid,
+			"role": bson.M{"$in": bson.A{models.HouseholdRoleOwner, models.HouseholdRoleEditor}},
+		}},
+	})
+	return count > 0, err
+}
@@ -6,10 +6,21 @@ import (
 	"log"
 	"net/http"
 	jwt "ocrserver/auth"
+	"ocrserver/budget"
+	"ocrserver/category"
 	"ocrserver/config"
+	"ocrserver/dates"
 	"ocrserver/db"
+	"ocrserver/events"
+	"ocrserver/fingerprint"
 	"ocrserver/images"
+	"ocrserver/marts"
 	"ocrserver/models"
+	"ocrserver/normalize"
+	"ocrserver/notify"
+	"ocrserver/reconcile"
+	"ocrserver/shopping"
+	"ocrserver/storage"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +33,8 @@ import (
 type OCRResult struct {
 	Data  map[string]interface{}
 	Error error
+	// Image is the base64 receipt photo the result was read from
+	Image string
 }
 
 func processOCRRequest(ocrURL string, ocrSecretKey string, doc []byte) (*OCRResult, error) {
@@ -46,7 +59,46 @@ func processOCRRequest(ocrURL string, ocrSecretKey string, doc []byte) (*OCRResu
 
 func saveToDatabase(ctx context.Context, recordRequest models.RecordInput) error {
 	_, err := db.Collection.InsertOne(ctx, recordRequest)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// OCR이 읽은 원본 값을 첫 번째 버전으로 보관
+	_, err = db.HistoryCollection.InsertOne(ctx, models.RecordHistory{
+		Rid:       recordRequest.Record.Rid,
+		Uid:       recordRequest.Uid,
+		Version:   1,
+		Action:    models.HistoryActionCreate,
+		Actor:     recordRequest.Uid,
+		Changes:   []models.FieldChange{},
+		Snapshot:  recordRequest,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// dbServer의 이벤트 스트림으로 새 레코드를 알림, 실패해도 레코드는 저장된 상태
+	if err := events.Publish(ctx, events.New(models.RecordEventCreated, recordRequest.Uid, recordRequest)); err != nil {
+		log.Printf("Error publishing event of %s: %v", recordRequest.Record.Rid, err)
+	}
+	return nil
+}
+
+// saveImage keeps the receipt photo of a record. The record is saved without a
+// photo if it cannot be stored.
+func saveImage(ctx context.Context, rid string, photo string) *models.ReceiptImage {
+	data, err := images.Decode(photo)
+	if err != nil || len(data) == 0 {
+		log.Printf("Error decoding image of %s: %v", rid, err)
+		return nil
+	}
+	image, err := images.Save(ctx, rid, data)
+	if err != nil {
+		log.Printf("Error saving image of %s: %v", rid, err)
+		return nil
+	}
+	return image
 }
 
 func RequestOCR(c *gin.Context) {
@@ -91,6 +143,7 @@ func RequestOCR(c *gin.Context) {
 				errorChan <- err
 				return
 			}
+			result.Image = data
 			resultChan <- result
 		}(imageData)
 	}
@@ -113,6 +166,12 @@ func RequestOCR(c *gin.Context) {
 	case err := <-errorChan:
 		if err != nil {
 			log.Printf("Error in OCR processing: %v", err)
+			// 앱을 닫았어도 알 수 있게 알림으로도 보냄
+			if account, accountErr := jwt.GetAccount(c); accountErr == nil {
+				if notifyErr := notify.OCRFailed(ctx, account.Uid, time.Now()); notifyErr != nil {
+					log.Printf("Error sending notification: %v", notifyErr)
+				}
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -127,11 +186,85 @@ func RequestOCR(c *gin.Context) {
 		return
 	}
 
+	// groupId가 있으면 가구 장부에 저장
+	groupId := c.Query("groupId")
+	if groupId != "" {
+		allowed, err := canAddToHousehold(ctx, account.Uid, groupId)
+		if err != nil {
+			log.Printf("Error checking household: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch household"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only household editors can add records"})
+			return
+		}
+	}
+
 	// 결과 처리 및 데이터베이스 저장
+	loc := userLocation(ctx, account.Uid)
+	categorizer, err := category.Load(ctx, account.Uid)
+	if err != nil {
+		log.Printf("Error loading category rules: %v", err)
+		categorizer = category.New(nil)
+	}
+
+	// rejectDuplicates=true이면 이미 저장된 영수증과 완전히 같은 영수증은 저장하지 않음
+	rejectDuplicates := c.Query("rejectDuplicates") == "true"
+
 	var dbRequests []models.RecordInput
+	duplicates := []gin.H{}
+	rejected := []gin.H{}
+	seen := map[string]string{}
+	photos := map[string]string{}
 	for _, result := range results {
-		dbRequest := parseOCRResult(result.Data, account.Uid)
+		dbRequest := parseOCRResult(result.Data, account.Uid, loc, categorizer)
+		dbRequest.GroupId = groupId
+		// 잘못 읽은 가격 때문에 합계가 맞지 않는 영수증 표시
+		dbRequest.Issues = reconcile.Check(dbRequest, nil, time.Now())
+
+		duplicateOf, exact := "", false
+		if rid, ok := seen[dbRequest.Record.Fingerprint]; ok {
+			// 같은 요청 안에서 같은 영수증을 두 번 보낸 경우
+			duplicateOf, exact = rid, true
+		} else {
+			existing, isExact, err := fingerprint.FindDuplicate(ctx, dbRequest)
+			if err != nil {
+				log.Printf("Error checking duplicates: %v", err)
+			}
+			if existing != nil {
+				duplicateOf, exact = existing.Record.Rid, isExact
+			}
+		}
+
+		if duplicateOf != "" {
+			info := gin.H{"rid": dbRequest.Record.Rid, "rname": dbRequest.Record.Rname, "duplicateOf": duplicateOf, "exact": exact}
+			if exact && rejectDuplicates {
+				rejected = append(rejected, info)
+				continue
+			}
+			dbRequest.Record.DuplicateOf = duplicateOf
+			duplicates = append(duplicates, info)
+		}
+
+		seen[dbRequest.Record.Fingerprint] = dbRequest.Record.Rid
 		dbRequests = append(dbRequests, dbRequest)
+		photos[dbRequest.Record.Rid] = result.Image
+	}
+
+	if len(dbRequests) == 0 && len(rejected) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Receipt has already been saved",
+			"rejected": rejected,
+		})
+		return
+	}
+
+	// 매장 목록에 연결. 한 영수증의 레코드는 같은 매장이므로 저장 고루틴 전에 차례로 처리
+	for i := range dbRequests {
+		if err := marts.Link(ctx, &dbRequests[i]); err != nil {
+			log.Printf("Error linking mart: %v", err)
+		}
 	}
 
 	// 데이터베이스 저장을 위한 고루틴
@@ -140,7 +273,11 @@ func RequestOCR(c *gin.Context) {
 		wg.Add(1)
 		go func(req models.RecordInput) {
 			defer wg.Done()
+			req.Image = saveImage(ctx, req.Record.Rid, photos[req.Record.Rid])
 			if err := saveToDatabase(ctx, req); err != nil {
+				if err := storage.Remove(ctx, req.Image); err != nil {
+					log.Printf("Error removing image: %v", err)
+				}
 				errChan <- err
 			}
 		}(request)
@@ -159,10 +296,131 @@ func RequestOCR(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "data successfully processed and saved"})
+	// 확인이 필요한 영수증
+	issues := []gin.H{}
+	for _, request := range dbRequests {
+		if len(request.Issues) > 0 {
+			issues = append(issues, gin.H{"rid": request.Record.Rid, "rname": request.Record.Rname, "issues": request.Issues})
+		}
+	}
+
+	// 새 영수증이 반영된 예산 상태 재평가
+	alerts := []models.BudgetAlert{}
+	home := homeCurrency(ctx, account.Uid)
+	for _, request := range dbRequests {
+		at := request.Record.TimeStamp.At
+		if at.IsZero() {
+			at = time.Now()
+		}
+		raised, err := budget.Evaluate(ctx, account.Uid, at, home, loc)
+		if err != nil {
+			log.Printf("Error evaluating budgets: %v", err)
+		}
+		alerts = append(alerts, raised...)
+	}
+
+	// 장보기 목록에서 이번에 산 품목 체크
+	bought := []models.ShoppingItem{}
+	for _, request := range dbRequests {
+		items, err := shopping.MarkBought(ctx, account.Uid, request)
+		if err != nil {
+			log.Printf("Error updating shopping list: %v", err)
+		}
+		bought = append(bought, items...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "data successfully processed and saved",
+		"alerts":     alerts,
+		"bought":     bought,
+		"duplicates": duplicates,
+		"rejected":   rejected,
+		"issues":     issues,
+	})
+}
+
+// formattedInt reads field.formatted.value of an OCR price object as an int
+func formattedInt(node interface{}, field string) (int, bool) {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	value, ok := obj[field].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	formatted, ok := value["formatted"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	text, _ := formatted["value"].(string)
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// sumPrices adds up a list of OCR price lines such as subTotal.taxPrice.
+// Discounts are sometimes printed as negative numbers, so the absolute value is used.
+func sumPrices(node interface{}) int {
+	lines, _ := node.([]interface{})
+	total := 0
+	for _, line := range lines {
+		obj, ok := line.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		formatted, ok := obj["formatted"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		text, _ := formatted["value"].(string)
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			continue
+		}
+		if n < 0 {
+			n = -n
+		}
+		total += n
+	}
+	return total
+}
+
+// parsePayment reads the card details of paymentInfo. It returns nil when there
+// are none, as a cash receipt and a card slip that could not be read look the same.
+func parsePayment(paymentInfo map[string]interface{}) *models.PaymentInfo {
+	payment := &models.PaymentInfo{}
+	if cardInfo, ok := paymentInfo["cardInfo"].(map[string]interface{}); ok {
+		payment.CardCompany = ocrText(cardInfo["company"])
+		payment.CardNumber = normalize.CardNumber(ocrText(cardInfo["number"]))
+	}
+	payment.ApprovalNumber = ocrText(paymentInfo["confirmNum"])
+
+	if payment.CardCompany == "" && payment.CardNumber == "" && payment.ApprovalNumber == "" {
+		return nil
+	}
+	payment.Method = models.PaymentMethodCard
+	return payment
+}
+
+// ocrText returns formatted.value of an OCR field, falling back to its raw text
+func ocrText(node interface{}) string {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if formatted, ok := obj["formatted"].(map[string]interface{}); ok {
+		if value, _ := formatted["value"].(string); value != "" {
+			return value
+		}
+	}
+	text, _ := obj["text"].(string)
+	return text
 }
 
-func parseOCRResult(data map[string]interface{}, uid string) models.RecordInput {
+func parseOCRResult(data map[string]interface{}, uid string, loc *time.Location, categorizer *category.Categorizer) models.RecordInput {
 	resp := data["images"].([]interface{})[0].(map[string]interface{})
 	images := resp["receipt"].(map[string]interface{})
 	result := images["result"].(map[string]interface{})
@@ -173,17 +431,51 @@ func parseOCRResult(data map[string]interface{}, uid string) models.RecordInput
 	pDay := paymentDate["day"].(string)
 	pYear := paymentDate["year"].(string)
 
+	// 결제 시각은 영수증에 없을 수도 있음
+	var pHour, pMinute, pSecond string
+	if paymentTime, ok := paymentInfo["time"].(map[string]interface{}); ok {
+		if formatted, ok := paymentTime["formatted"].(map[string]interface{}); ok {
+			pHour, _ = formatted["hour"].(string)
+			pMinute, _ = formatted["minute"].(string)
+			pSecond, _ = formatted["second"].(string)
+		}
+	}
+
+	var timeStamp models.PurchaseTime
 	recordTime := pYear + "-" + pMonth + "-" + pDay
+	purchasedAt, hasTime, err := dates.FromParts(pYear, pMonth, pDay, pHour, pMinute, pSecond, loc)
+	if err != nil {
+		// 시각을 읽지 못했으면 날짜만이라도 사용
+		purchasedAt, hasTime, err = dates.FromParts(pYear, pMonth, pDay, "", "", "", loc)
+	}
+	if err != nil {
+		log.Printf("Error: invalid payment date %s: %v\n", recordTime, err)
+	} else {
+		timeStamp = models.NewPurchaseTime(purchasedAt, hasTime)
+		recordTime = purchasedAt.Format(dates.DateLayout)
+	}
 
 	martInfo := result["storeInfo"].(map[string]interface{})
 	martName := martInfo["name"].(map[string]interface{})["formatted"].(map[string]interface{})["value"].(string)
 	martAddress := martInfo["addresses"].([]interface{})[0].(map[string]interface{})["formatted"].(map[string]interface{})["value"].(string)
 	tel := martInfo["tel"].([]interface{})[0].(map[string]interface{})["text"].(string)
 
+	// 사업자등록번호는 중복 영수증 판별에 사용
+	var bizNum string
+	if info, ok := martInfo["bizNum"].(map[string]interface{}); ok {
+		if formatted, ok := info["formatted"].(map[string]interface{}); ok {
+			bizNum, _ = formatted["value"].(string)
+		}
+		if bizNum == "" {
+			bizNum, _ = info["text"].(string)
+		}
+	}
+
 	martInput := models.DBMart{
 		MartName:    martName,
 		MartAddress: martAddress,
 		Tel:         tel,
+		BizNum:      bizNum,
 	}
 
 	subres := result["subResults"].([]interface{})[0].(map[string]interface{})
@@ -203,13 +495,35 @@ func parseOCRResult(data map[string]interface{}, uid string) models.RecordInput
 		intamount, _ := strconv.Atoi(amount)
 		intprice, _ := strconv.Atoi(price)
 
+		// 금액이 음수인 줄은 바로 앞 품목의 할인으로 처리
+		if len(dbProducts) > 0 && intprice < 0 {
+			dbProducts[len(dbProducts)-1].Discount -= intprice
+			continue
+		}
+
+		// price는 줄 금액이므로 단가가 있으면 단가를 사용
+		var discount int
+		if unitPrice, ok := formattedInt(product["price"], "unitPrice"); ok {
+			intprice = unitPrice
+		} else if intamount > 1 {
+			// 나누어떨어지지 않으면 단가를 올림하고 차액을 할인으로 두어 줄 금액을 유지
+			lineTotal := intprice
+			intprice = (lineTotal + intamount - 1) / intamount
+			discount = intprice*intamount - lineTotal
+		}
+
 		dbProducts = append(dbProducts, models.DBProduct{
-			Pname:  productName,
-			Price:  intprice,
-			Amount: intamount,
+			Pname:    productName,
+			NormName: normalize.ProductName(productName),
+			Price:    intprice,
+			Amount:   intamount,
+			Discount: discount,
 		})
 	}
 
+	// 품목별 카테고리 자동 분류
+	categorizer.Apply(dbProducts)
+
 	totalPrice := result["totalPrice"].(map[string]interface{})["price"].(map[string]interface{})["formatted"].(map[string]interface{})["value"].(string)
 	intTotalPrice, _ := strconv.Atoi(totalPrice)
 
@@ -218,14 +532,28 @@ func parseOCRResult(data map[string]interface{}, uid string) models.RecordInput
 	record := models.DBRecord{
 		Rid:       uuid.NewString(),
 		Rname:     recordName,
-		TimeStamp: recordTime,
+		TimeStamp: timeStamp,
 	}
 
-	return models.RecordInput{
+	recordInput := models.RecordInput{
 		Uid:        uid,
 		Record:     record,
 		Mart:       martInput,
 		Product:    dbProducts,
 		TotalPrice: intTotalPrice,
+		Payment:    parsePayment(paymentInfo),
+	}
+
+	// 영수증 전체 할인과 부가세
+	if subTotals, ok := result["subTotal"].([]interface{}); ok && len(subTotals) > 0 {
+		if subTotal, ok := subTotals[0].(map[string]interface{}); ok {
+			recordInput.Discount = sumPrices(subTotal["discountPrice"])
+			if vat := sumPrices(subTotal["taxPrice"]); vat > 0 {
+				recordInput.Tax = &models.TaxInfo{Supply: intTotalPrice - vat, Vat: vat}
+			}
+		}
 	}
+	recordInput.Record.Fingerprint = fingerprint.Of(recordInput)
+
+	return recordInput
 }
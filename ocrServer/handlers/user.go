@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"ocrserver/currency"
+	"ocrserver/dates"
+	"ocrserver/db"
+	"ocrserver/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// userLocation returns the timezone the user has chosen, or the default one
+func userLocation(ctx context.Context, uid string) *time.Location {
+	var user models.User
+	err := db.Collection.FindOne(ctx, bson.M{"uid": uid, "record": bson.M{"$exists": false}}).Decode(&user)
+	if err != nil {
+		return dates.LoadLocation("")
+	}
+	return dates.LoadLocation(user.TimeZone)
+}
+
+// homeCurrency returns the currency the budgets of uid are kept in
+func homeCurrency(ctx context.Context, uid string) string {
+	var user models.User
+	err := db.Collection.FindOne(ctx, bson.M{"uid": uid, "record": bson.M{"$exists": false}}).Decode(&user)
+	if err != nil {
+		return currency.Default
+	}
+	return currency.Normalize(user.HomeCurrency)
+}
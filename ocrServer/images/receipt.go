@@ -0,0 +1,107 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"ocrserver/models"
+	"ocrserver/storage"
+	"strings"
+	"time"
+)
+
+// 썸네일의 긴 변 픽셀 수
+const thumbnailSize = 320
+
+// Decode reads an image sent as base64, with or without a data URL prefix
+func Decode(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		if i := strings.Index(data, ","); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+}
+
+// Save stores the receipt photo of record rid with a JPEG thumbnail. Formats that
+// cannot be decoded here, such as PDF, are stored without a thumbnail.
+func Save(ctx context.Context, rid string, data []byte) (*models.ReceiptImage, error) {
+	receipt := &models.ReceiptImage{
+		Key:         rid,
+		ContentType: http.DetectContentType(data),
+		Size:        int64(len(data)),
+		UploadedAt:  time.Now(),
+	}
+	if err := storage.Images.Put(ctx, receipt.Key, data); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return receipt, nil
+	}
+	receipt.Width, receipt.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(img), &jpeg.Options{Quality: 80}); err != nil {
+		return receipt, nil
+	}
+	if err := storage.Images.Put(ctx, rid+"-thumb", thumb.Bytes()); err != nil {
+		// 원본은 저장됐으므로 썸네일 없이 진행
+		return receipt, nil
+	}
+	receipt.ThumbnailKey = rid + "-thumb"
+	receipt.ThumbnailSize = int64(thumb.Len())
+	return receipt, nil
+}
+
+// thumbnail scales img down so its longer side is thumbnailSize, averaging a few
+// source pixels per output pixel
+func thumbnail(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= thumbnailSize && h <= thumbnailSize {
+		return img
+	}
+
+	tw, th := thumbnailSize, h*thumbnailSize/w
+	if h > w {
+		tw, th = w*thumbnailSize/h, thumbnailSize
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	// 큰 사진도 빠르게 줄이도록 출력 픽셀당 최대 4x4개만 표본으로 씀
+	const samples = 4
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint32
+			for sy := 0; sy < samples; sy++ {
+				py := y0 + (y1-y0)*sy/samples
+				for sx := 0; sx < samples; sx++ {
+					px := x0 + (x1-x0)*sx/samples
+					pr, pg, pb, pa := img.At(px, py).RGBA()
+					r, g, bl, a, n = r+pr, g+pg, bl+pb, a+pa, n+1
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
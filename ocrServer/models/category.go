@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	CategoryRuleKeyword = "keyword"
+	CategoryRuleRegex   = "regex"
+	CategoryRuleExact   = "exact"
+
+	// 카테고리가 정해진 경로
+	CategorySourceDictionary = "dictionary"
+	CategorySourceRule       = "rule"
+	CategorySourceLearned    = "learned"
+	CategorySourceManual     = "manual"
+)
+
+// CategoryRule assigns a category to products whose name matches Pattern.
+// Exact rules are learned from the user's own corrections and win over every other rule.
+type CategoryRule struct {
+	RuleId    string    `json:"ruleId" bson:"ruleId"`
+	Uid       string    `json:"uid" bson:"uid"`
+	Type      string    `json:"type" bson:"type"`
+	Pattern   string    `json:"pattern" bson:"pattern"`
+	Category  string    `json:"category" bson:"category"`
+	Priority  int       `json:"priority" bson:"priority"`
+	Learned   bool      `json:"learned" bson:"learned"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
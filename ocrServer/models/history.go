@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const HistoryActionCreate = "create"
+
+// RecordHistory is one stored version of a record together with the change that produced it
+type RecordHistory struct {
+	Rid       string        `json:"rid" bson:"rid"`
+	Uid       string        `json:"uid" bson:"uid"`
+	Version   int           `json:"version" bson:"version"`
+	Action    string        `json:"action" bson:"action"`
+	Actor     string        `json:"actor" bson:"actor"`
+	Changes   []FieldChange `json:"changes" bson:"changes"`
+	Snapshot  RecordInput   `json:"snapshot" bson:"snapshot"`
+	CreatedAt time.Time     `json:"createdAt" bson:"createdAt"`
+}
+
+// FieldChange describes a single field that differs between two versions
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
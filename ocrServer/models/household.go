@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	HouseholdRoleOwner  = "owner"
+	HouseholdRoleEditor = "editor"
+	HouseholdRoleViewer = "viewer"
+)
+
+// Household is a group of users sharing a ledger
+type Household struct {
+	GroupId   string            `json:"groupId" bson:"groupId"`
+	Name      string            `json:"name" bson:"name"`
+	Members   []HouseholdMember `json:"members" bson:"members"`
+	CreatedAt time.Time         `json:"createdAt" bson:"createdAt"`
+}
+
+type HouseholdMember struct {
+	Uid      string    `json:"uid" bson:"uid"`
+	Role     string    `json:"role" bson:"role"`
+	JoinedAt time.Time `json:"joinedAt" bson:"joinedAt"`
+}
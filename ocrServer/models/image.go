@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ReceiptImage is the photo a record was read from. The original and a JPEG
+// thumbnail are kept in the image store under Key and ThumbnailKey.
+type ReceiptImage struct {
+	Key           string    `json:"-" bson:"key"`
+	ContentType   string    `json:"contentType" bson:"contentType"`
+	Size          int64     `json:"size" bson:"size"`
+	Width         int       `json:"width,omitempty" bson:"width,omitempty"`
+	Height        int       `json:"height,omitempty" bson:"height,omitempty"`
+	ThumbnailKey  string    `json:"-" bson:"thumbnailKey,omitempty"`
+	ThumbnailSize int64     `json:"thumbnailSize,omitempty" bson:"thumbnailSize,omitempty"`
+	UploadedAt    time.Time `json:"uploadedAt" bson:"uploadedAt"`
+}
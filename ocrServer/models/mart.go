@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Mart is an entry of a user's store directory. Records point to it with
+// mart.martId and keep a copy of its name, address and phone number.
+type Mart struct {
+	MartId  string `json:"martId" bson:"martId"`
+	Uid     string `json:"uid" bson:"uid"`
+	Name    string `json:"name" bson:"name"`
+	Address string `json:"address,omitempty" bson:"address,omitempty"`
+	Tel     string `json:"tel,omitempty" bson:"tel,omitempty"`
+	BizNum  string `json:"bizNum,omitempty" bson:"bizNum,omitempty"`
+	// Aliases are the other spellings of the name found on receipts
+	Aliases []string `json:"aliases" bson:"aliases"`
+	// 중복 판별에 쓰는 정규화 키
+	NormNames   []string  `json:"-" bson:"normNames"`
+	NormAddress string    `json:"-" bson:"normAddress,omitempty"`
+	NormTel     string    `json:"-" bson:"normTel,omitempty"`
+	NormBizNum  string    `json:"-" bson:"normBizNum,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+}
@@ -4,7 +4,7 @@ type OCRRequest struct {
 	Version   string     `json:"version"`
 	RequestId string     `json:"requestId"`
 	Timestamp string     `json:"timestamp"`
-	Lang      string     `json:"lang, omitempty"`
+	Lang      string     `json:"lang,omitempty"`
 	Images    []OCRImage `json:"images"`
 }
 
@@ -13,5 +13,5 @@ type OCRImage struct {
 	Data       string   `json:"data,omitempty"`
 	Url        string   `json:"url,omitempty"`
 	Name       string   `json:"name"`
-	TemplateId []string `json:"templateId, omitempty"`
+	TemplateId []string `json:"templateId,omitempty"`
 }
@@ -2,28 +2,72 @@ package models
 
 type RecordInput struct {
 	Uid        string      `bson:"uid"`
+	GroupId    string      `bson:"groupId,omitempty"`
 	Record     DBRecord    `bson:"record"`
 	Mart       DBMart      `bson:"mart"`
 	Product    []DBProduct `bson:"product"`
 	TotalPrice int         `bson:"totalPrice"`
+	// Discount is taken off the whole receipt, on top of the line item discounts
+	Discount int          `bson:"discount,omitempty"`
+	Tax      *TaxInfo     `bson:"tax,omitempty"`
+	Payment  *PaymentInfo `bson:"payment,omitempty"`
+	// Image is the receipt photo of records read by OCR
+	Image *ReceiptImage `bson:"image,omitempty"`
+	// Issues are the reconciliation problems found on the receipt
+	Issues []RecordIssue `bson:"issues,omitempty"`
+}
+
+// TaxInfo is the VAT breakdown printed on a receipt
+type TaxInfo struct {
+	Supply  int `json:"supply" bson:"supply"`
+	Vat     int `json:"vat" bson:"vat"`
+	TaxFree int `json:"taxFree,omitempty" bson:"taxFree,omitempty"`
+}
+
+const (
+	PaymentMethodCard = "card"
+	PaymentMethodCash = "cash"
+)
+
+// PaymentInfo is how a receipt was paid. CardNumber is always stored masked.
+type PaymentInfo struct {
+	Method         string `json:"method" bson:"method"`
+	CardCompany    string `json:"cardCompany,omitempty" bson:"cardCompany,omitempty"`
+	CardNumber     string `json:"cardNumber,omitempty" bson:"cardNumber,omitempty"`
+	ApprovalNumber string `json:"approvalNumber,omitempty" bson:"approvalNumber,omitempty"`
 }
 
 type DBRecord struct {
-	Rid       string `bson:"rid"`
-	Rname     string `bson:"rname"`
-	TimeStamp string `bson:"timeStamp"`
+	Rid         string       `bson:"rid"`
+	Rname       string       `bson:"rname"`
+	TimeStamp   PurchaseTime `bson:"timeStamp"`
+	Fingerprint string       `bson:"fingerprint,omitempty"`
+	DuplicateOf string       `bson:"duplicateOf,omitempty"`
 }
 
 type DBProduct struct {
-	Pname  string `bson:"pname"`
-	Price  int    `bson:"price"`
-	Amount int    `bson:"amount"`
+	Pname          string `bson:"pname"`
+	NormName       string `bson:"normName,omitempty"`
+	Price          int    `bson:"price"`
+	Amount         int    `bson:"amount"`
+	Category       string `bson:"category,omitempty"`
+	CategorySource string `bson:"categorySource,omitempty"`
+	// Discount is taken off this line, e.g. a "1+1" or member discount
+	Discount int `bson:"discount,omitempty"`
+}
+
+// LineTotal is what was paid for the line: price times amount less its discount
+func (p DBProduct) LineTotal() int {
+	return p.Price*p.Amount - p.Discount
 }
 
 type DBMart struct {
+	// MartId points to the mart directory entry of the record's owner
+	MartId      string `bson:"martId,omitempty"`
 	MartAddress string `bson:"martAddress"`
 	MartName    string `bson:"martName"`
 	Tel         string `bson:"tel"`
+	BizNum      string `bson:"bizNum,omitempty"`
 }
 
 type RecordList struct {
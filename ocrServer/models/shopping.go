@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	ShoppingSourceManual    = "manual"
+	ShoppingSourceRecurring = "recurring"
+)
+
+// ShoppingItem is an entry of a user's shopping list
+type ShoppingItem struct {
+	ItemId    string     `json:"itemId" bson:"itemId"`
+	Uid       string     `json:"uid" bson:"uid"`
+	Name      string     `json:"name" bson:"name"`
+	NormName  string     `json:"normName" bson:"normName"`
+	Quantity  int        `json:"quantity" bson:"quantity"`
+	Note      string     `json:"note,omitempty" bson:"note,omitempty"`
+	Source    string     `json:"source" bson:"source"`
+	Checked   bool       `json:"checked" bson:"checked"`
+	CheckedAt *time.Time `json:"checkedAt,omitempty" bson:"checkedAt,omitempty"`
+	// BoughtRid is the receipt that ticked the item off, empty when ticked by hand
+	BoughtRid string    `json:"boughtRid,omitempty" bson:"boughtRid,omitempty"`
+	AddedAt   time.Time `json:"addedAt" bson:"addedAt"`
+}
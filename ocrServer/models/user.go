@@ -1,7 +1,7 @@
 package models
 
 type DBRequest struct {
-	Uid      string      `bson:"uid", omitempty`
+	Uid      string      `bson:"uid,omitempty"`
 	Nickname string      `bson:"nickname"`
 	Email    string      `bson:"email"`
 	Pw       string      `bson:"pw"`
@@ -21,6 +21,9 @@ type User struct {
 	Nickname string `bson:"nickname"`
 	Email    string `bson:"email"`
 	Pw       string `bson:"pw"`
+	TimeZone string `bson:"timeZone,omitempty"`
+	// HomeCurrency is the currency budgets are kept in
+	HomeCurrency string `bson:"homeCurrency,omitempty"`
 }
 
 type SignupRequest struct {
@@ -0,0 +1,73 @@
+// Package notify queues notifications for dbServer to deliver
+package notify
+
+import (
+	"context"
+	"ocrserver/currency"
+	"ocrserver/db"
+	"ocrserver/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Send queues a notification of kind for uid. Data fills the template of the kind.
+func Send(ctx context.Context, uid string, kind string, data map[string]string) error {
+	now := time.Now()
+	_, err := db.NotificationCollection.InsertOne(ctx, models.Notification{
+		Nid:          uuid.NewString(),
+		Uid:          uid,
+		Kind:         kind,
+		Data:         data,
+		Status:       models.NotificationPending,
+		DeliverAfter: now,
+		CreatedAt:    now,
+	})
+	return err
+}
+
+// OCRFailed tells uid the receipt uploaded at could not be read
+func OCRFailed(ctx context.Context, uid string, at time.Time) error {
+	return Send(ctx, uid, models.NotificationOCRFailed, map[string]string{
+		"at": at.Format(time.RFC3339),
+	})
+}
+
+// BudgetNotifier sends budget alerts as notifications, see budget.SetNotifier
+type BudgetNotifier struct{}
+
+func (BudgetNotifier) Notify(ctx context.Context, alert models.BudgetAlert) error {
+	code := currency.Normalize(alert.Currency)
+	return Send(ctx, alert.Uid, models.NotificationBudgetAlert, map[string]string{
+		"aid":       alert.Aid,
+		"bid":       alert.Bid,
+		"budget":    alert.BudgetName,
+		"threshold": strconv.Itoa(alert.Threshold),
+		"exceeded":  strconv.FormatBool(alert.Threshold >= 100),
+		"spent":     amount(alert.Spent, code),
+		"amount":    amount(alert.Amount, code),
+		"currency":  code,
+	})
+}
+
+// amount formats an amount in minor units of code with digit grouping
+func amount(amount int, code string) string {
+	digits := currency.Format(amount, code)
+	sign, fraction := "", ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+		digits, fraction = digits[:dot], digits[dot:]
+	}
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + fraction
+}
@@ -0,0 +1,107 @@
+package reconcile
+
+import (
+	"fmt"
+	"hash/fnv"
+	"ocrserver/models"
+	"time"
+)
+
+// MaxAmount is the largest quantity on one line that is believed without a check
+const MaxAmount = 999
+
+// Check validates the lines of a record against its total. previous are the issues
+// stored on the record, whose resolution is kept for issues that are found again
+// with the same values.
+func Check(record models.RecordInput, previous []models.RecordIssue, now time.Time) []models.RecordIssue {
+	var found []models.RecordIssue
+	add := func(code string, pname string, expected int, actual int) {
+		id := code
+		if pname != "" {
+			h := fnv.New32a()
+			h.Write([]byte(pname))
+			id = fmt.Sprintf("%s-%08x", code, h.Sum32())
+		}
+		for _, issue := range found {
+			if issue.IssueId == id {
+				return
+			}
+		}
+		found = append(found, models.RecordIssue{
+			IssueId:    id,
+			Code:       code,
+			Pname:      pname,
+			Expected:   expected,
+			Actual:     actual,
+			DetectedAt: now,
+		})
+	}
+
+	if len(record.Product) == 0 {
+		return carryOver(found, previous)
+	}
+
+	sum := 0
+	for _, p := range record.Product {
+		sum += p.LineTotal()
+
+		if p.Price == 0 || (p.Price < 0 && record.TotalPrice > 0) {
+			add(models.IssueBadPrice, p.Pname, 0, p.Price)
+		}
+		if p.Amount < 1 || p.Amount > MaxAmount {
+			add(models.IssueBadAmount, p.Pname, 1, p.Amount)
+		}
+		if p.Discount > 0 && p.Discount > p.Price*p.Amount {
+			add(models.IssueDiscountTooLarge, p.Pname, p.Price*p.Amount, p.Discount)
+		}
+	}
+
+	if record.TotalPrice == 0 {
+		add(models.IssueZeroTotal, "", sum-record.Discount, 0)
+	} else if expected := sum - record.Discount; expected != record.TotalPrice {
+		add(models.IssueSumMismatch, "", expected, record.TotalPrice)
+	}
+
+	return carryOver(found, previous)
+}
+
+// carryOver keeps the detection time and resolution of issues that did not change
+func carryOver(found []models.RecordIssue, previous []models.RecordIssue) []models.RecordIssue {
+	for i := range found {
+		for _, old := range previous {
+			if old.IssueId != found[i].IssueId || old.Expected != found[i].Expected || old.Actual != found[i].Actual {
+				continue
+			}
+			found[i].DetectedAt = old.DetectedAt
+			found[i].Resolved = old.Resolved
+			found[i].ResolvedBy = old.ResolvedBy
+			found[i].ResolvedAt = old.ResolvedAt
+		}
+	}
+	return found
+}
+
+// Equal reports whether two issue lists are the same, so unchanged records are not written
+func Equal(a, b []models.RecordIssue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].IssueId != b[i].IssueId || a[i].Expected != b[i].Expected || a[i].Actual != b[i].Actual ||
+			a[i].Resolved != b[i].Resolved || !a[i].DetectedAt.Equal(b[i].DetectedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// Open counts the issues not resolved yet
+func Open(issues []models.RecordIssue) int {
+	open := 0
+	for _, issue := range issues {
+		if !issue.Resolved {
+			open++
+		}
+	}
+	return open
+}
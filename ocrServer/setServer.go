@@ -1,11 +1,15 @@
 package main
 
 import (
+	"log"
 	"net/http"
+	"ocrserver/budget"
 	"ocrserver/config"
 	"ocrserver/db"
 	login "ocrserver/handlers"
 	"ocrserver/handlers/middleware"
+	"ocrserver/notify"
+	"ocrserver/storage"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -22,6 +26,10 @@ type User struct {
 func Setup() *gin.Engine {
 	config.Init()
 	db.DBInit()
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Failed to open receipt image storage: %v", err)
+	}
+	budget.SetNotifier(notify.BudgetNotifier{})
 
 	r := gin.Default()
 
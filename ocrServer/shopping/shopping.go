@@ -0,0 +1,60 @@
+package shopping
+
+import (
+	"context"
+	"ocrserver/db"
+	"ocrserver/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Matches reports whether a product on a receipt is the shopping list item, either
+// by the same normalized name or by containing it, so "우유" is ticked off by
+// "서울우유 1L"
+func Matches(item string, product string) bool {
+	if item == "" || product == "" {
+		return false
+	}
+	return product == item || strings.Contains(product, item)
+}
+
+// MarkBought ticks off the open shopping list items of uid that appear on record
+// and returns them
+func MarkBought(ctx context.Context, uid string, record models.RecordInput) ([]models.ShoppingItem, error) {
+	cursor, err := db.ShoppingCollection.Find(ctx, bson.M{"uid": uid, "checked": false})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var open []models.ShoppingItem
+	if err := cursor.All(ctx, &open); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	bought := []models.ShoppingItem{}
+	for _, item := range open {
+		for _, product := range record.Product {
+			if !Matches(item.NormName, product.NormName) {
+				continue
+			}
+
+			_, err := db.ShoppingCollection.UpdateOne(ctx,
+				bson.M{"itemId": item.ItemId, "checked": false},
+				bson.M{"$set": bson.M{"checked": true, "checkedAt": now, "boughtRid": record.Record.Rid}},
+			)
+			if err != nil {
+				return bought, err
+			}
+			item.Checked = true
+			item.CheckedAt = &now
+			item.BoughtRid = record.Record.Rid
+			bought = append(bought, item)
+			break
+		}
+	}
+	return bought, nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"ocrserver/config"
+	"ocrserver/db"
+	"ocrserver/models"
+)
+
+// ErrNotFound is returned by Open when no image is stored under the key
+var ErrNotFound = errors.New("image not found")
+
+// Store keeps receipt images by key
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the image of key. Deleting a missing image is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Images is the store selected by the configuration, set by Init
+var Images Store
+
+// Init opens the receipt image store. It must run after db.DBInit.
+func Init() error {
+	cfg := config.AppConfig.Storage
+	switch cfg.Backend {
+	case config.StorageLocal:
+		store, err := NewLocal(cfg.Dir)
+		if err != nil {
+			return err
+		}
+		Images = store
+	case config.StorageGridFS:
+		Images = NewGridFS(db.Client.Database(config.AppConfig.MongoDB.Database))
+	default:
+		return fmt.Errorf("unknown receipt image storage %q", cfg.Backend)
+	}
+	return nil
+}
+
+// Remove deletes the original and the thumbnail of a record's image
+func Remove(ctx context.Context, image *models.ReceiptImage) error {
+	if image == nil {
+		return nil
+	}
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := Images.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}